@@ -4,6 +4,7 @@ import (
 	"auth/internal/config"
 	"auth/internal/server"
 	firebaseHelper "auth/internal/utils/helpers/firebaseHelpers"
+	"auth/internal/utils/helpers/jwtHelpers"
 	"context"
 	"fmt"
 	"log"
@@ -39,11 +40,15 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 
 func main() {
 	firebaseHelper.App()
+	jwtHelpers.GetSigner()
 
 	server := server.New()
 
 	server.RegisterFiberRoutes()
 
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	sweeper := server.StartDeletionSweeper(sweeperCtx)
+
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
@@ -61,5 +66,7 @@ func main() {
 
 	// Wait for the graceful shutdown to complete
 	<-done
+	stopSweeper()
+	sweeper.Wait()
 	log.Println("Graceful shutdown complete.")
 }
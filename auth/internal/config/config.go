@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -13,8 +16,84 @@ var (
 	internalAccessToken = os.Getenv("INTERNAL_ACCESS_TOKEN")
 	firebaseConfigPath  = os.Getenv("FIREBASE_CONFIG_PATH")
 	env                 = os.Getenv("APP_ENV")
+	googleProjectID     = os.Getenv("GOOGLE_PROJECT_ID")
+	accessTokenTTL      = os.Getenv("ACCESS_TOKEN_TTL")
+	refreshTokenTTL     = os.Getenv("REFRESH_TOKEN_TTL")
+	otpTTL              = os.Getenv("OTP_TTL")
+	otpResendCooldown   = os.Getenv("OTP_RESEND_COOLDOWN")
+	otpMaxAttempts      = os.Getenv("OTP_MAX_ATTEMPTS")
+	otpMaxPerPhone      = os.Getenv("OTP_MAX_PER_PHONE_PER_HOUR")
+	otpMaxPerIP         = os.Getenv("OTP_MAX_PER_IP_PER_HOUR")
+	sms                 = SmsConfig{
+		Provider: os.Getenv("SMS_PROVIDER"),
+		APIURL:   os.Getenv("SMS_API_URL"),
+		APIKey:   os.Getenv("SMS_API_KEY"),
+		SenderID: os.Getenv("SMS_SENDER_ID"),
+	}
+	otp = OtpConfig{
+		Secret: os.Getenv("OTP_SECRET"),
+	}
+	jwt = JwtConfig{
+		PrivateKeyPath: os.Getenv("JWT_PRIVATE_KEY_PATH"),
+		Issuer:         os.Getenv("JWT_ISSUER"),
+	}
+	rateLimit = RateLimitConfig{
+		Store:  os.Getenv("RATE_LIMIT_STORE"),
+		Limits: os.Getenv("RATE_LIMITS"),
+	}
+	proxy = ProxyConfig{
+		Header: os.Getenv("PROXY_HEADER"),
+	}
+	trustedProxies = os.Getenv("TRUSTED_PROXIES")
 )
 
+// JwtConfig configures the session tokens issued by the service. Without a
+// PrivateKeyPath a key is generated at startup outside of prod, so tokens
+// don't survive a restart.
+type JwtConfig struct {
+	PrivateKeyPath  string
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// SmsConfig picks how text messages are sent: "console" (the default) logs
+// them and is refused in prod, "api" posts them to the gateway at APIURL.
+type SmsConfig struct {
+	Provider string
+	APIURL   string
+	APIKey   string
+	SenderID string
+}
+
+// OtpConfig bounds the one time codes sent for phone sign in. Codes are
+// stored as an HMAC keyed with Secret, which falls back to the internal access
+// token outside of prod only.
+type OtpConfig struct {
+	Secret         string
+	TTL            time.Duration
+	ResendCooldown time.Duration
+	MaxAttempts    int
+	MaxPerPhone    int
+	MaxPerIP       int
+}
+
+// RateLimitConfig picks where rate limit buckets are kept: "memory" (the
+// default) per instance, "mongo" shared by all instances. Limits overrides
+// the limits of routes by rule name, like "token=10/1m".
+type RateLimitConfig struct {
+	Store  string
+	Limits string
+}
+
+// ProxyConfig names the load balancers in front of the service. The client
+// address is read from Header only on requests coming from TrustedProxies
+// (addresses or CIDR ranges), other requests are keyed on their own address.
+type ProxyConfig struct {
+	Header         string
+	TrustedProxies []string
+}
+
 type configType struct {
 	Port                string
 	MongoConnUrl        string
@@ -22,6 +101,12 @@ type configType struct {
 	InternalAccessToken string
 	FirebaseConfigPath  string
 	Env                 string
+	GoogleProjectID     string
+	Jwt                 JwtConfig
+	Sms                 SmsConfig
+	Otp                 OtpConfig
+	RateLimit           RateLimitConfig
+	Proxy               ProxyConfig
 }
 
 func GetConfig() configType {
@@ -32,9 +117,63 @@ func GetConfig() configType {
 		InternalAccessToken: internalAccessToken,
 		FirebaseConfigPath:  firebaseConfigPath,
 		Env:                 env,
+		GoogleProjectID:     googleProjectID,
+		Jwt:                 jwt,
+		Sms:                 sms,
+		Otp:                 otp,
+		RateLimit:           rateLimit,
+		Proxy:               proxy,
 	}
 	if port != "" {
 		obj.Port = port
 	}
+	if jwt.Issuer == "" {
+		obj.Jwt.Issuer = "purely-auth"
+	}
+	obj.Jwt.AccessTokenTTL, _ = time.ParseDuration(accessTokenTTL)
+	if obj.Jwt.AccessTokenTTL <= 0 {
+		obj.Jwt.AccessTokenTTL = 10 * time.Minute
+	}
+	obj.Jwt.RefreshTokenTTL, _ = time.ParseDuration(refreshTokenTTL)
+	if obj.Jwt.RefreshTokenTTL <= 0 {
+		obj.Jwt.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if rateLimit.Store == "" {
+		obj.RateLimit.Store = "memory"
+	}
+	if proxy.Header == "" {
+		obj.Proxy.Header = "X-Forwarded-For"
+	}
+	for _, trustedProxy := range strings.Split(trustedProxies, ",") {
+		if trustedProxy = strings.TrimSpace(trustedProxy); trustedProxy != "" {
+			obj.Proxy.TrustedProxies = append(obj.Proxy.TrustedProxies, trustedProxy)
+		}
+	}
+	if sms.Provider == "" {
+		obj.Sms.Provider = "console"
+	}
+	if otp.Secret == "" && env != "prod" {
+		obj.Otp.Secret = internalAccessToken
+	}
+	obj.Otp.TTL, _ = time.ParseDuration(otpTTL)
+	if obj.Otp.TTL <= 0 {
+		obj.Otp.TTL = 5 * time.Minute
+	}
+	obj.Otp.ResendCooldown, _ = time.ParseDuration(otpResendCooldown)
+	if obj.Otp.ResendCooldown <= 0 {
+		obj.Otp.ResendCooldown = 30 * time.Second
+	}
+	obj.Otp.MaxAttempts, _ = strconv.Atoi(otpMaxAttempts)
+	if obj.Otp.MaxAttempts <= 0 {
+		obj.Otp.MaxAttempts = 5
+	}
+	obj.Otp.MaxPerPhone, _ = strconv.Atoi(otpMaxPerPhone)
+	if obj.Otp.MaxPerPhone <= 0 {
+		obj.Otp.MaxPerPhone = 5
+	}
+	obj.Otp.MaxPerIP, _ = strconv.Atoi(otpMaxPerIP)
+	if obj.Otp.MaxPerIP <= 0 {
+		obj.Otp.MaxPerIP = 20
+	}
 	return obj
 }
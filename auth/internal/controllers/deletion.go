@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	PubSub "auth/internal/providers/pubSub"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	httpHelper "auth/internal/utils/helpers/httpHelper"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PubSubMessagePayload is the body of a Pub/Sub push request.
+type PubSubMessagePayload struct {
+	Message struct {
+		Data string `json:"data"`
+	} `json:"message"`
+}
+
+func (authController *AuthController) RequestAccountDeletion(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.RequestAccountDeletion(ctx, data.(string))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return c.Locals("authId").(string)
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) GetDeletionSaga(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.GetDeletionSaga(ctx, data.(string))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return c.Params("authId")
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) HandlePubSubMessage(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			parsedData, ok := data.(PubSub.PubSubMessageType)
+			if !ok {
+				log.Default().Println("HandlePubSubMessage Type parsing error")
+				return false, nil
+			}
+			// A non-2xx response has Pub/Sub redeliver the message
+			if !authController.AuthService.HandlePubSubMessage(ctx, parsedData) {
+				return nil, httpErrors.HydrateHttpError("purely/requests/errors/message-not-handled", 503, "Message not handled, redeliver it")
+			}
+			return true, nil
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var msg PubSubMessagePayload
+			if err := c.BodyParser(&msg); err != nil {
+				return nil
+			}
+			decoded, err := base64.StdEncoding.DecodeString(msg.Message.Data)
+			if err != nil {
+				return nil
+			}
+			var data PubSub.PubSubMessageType
+			if err := json.Unmarshal(decoded, &data); err != nil {
+				log.Default().Printf("HandlePubSubMessage Failed to parse JSON from decoded message: %v", err)
+				return nil
+			}
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	httpHelper "auth/internal/utils/helpers/httpHelper"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (authController *AuthController) RequestDataExport(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.RequestDataExport(ctx, data.(string))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return c.Locals("authId").(string)
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) GetDataExport(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.GetDataExport(ctx, data.(string))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return c.Locals("authId").(string)
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
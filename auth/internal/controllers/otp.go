@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"auth/internal/types/authServiceTypes"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	httpHelper "auth/internal/utils/helpers/httpHelper"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (authController *AuthController) StartOtp(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			otpData, ok := data.(authServiceTypes.StartOtpType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return authController.AuthService.StartOtp(ctx, otpData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data authServiceTypes.StartOtpType
+			if err := c.BodyParser(&data); err != nil {
+				return nil
+			}
+			data.IP = c.IP()
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) VerifyOtp(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			otpData, ok := data.(authServiceTypes.VerifyOtpType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return authController.AuthService.VerifyOtp(ctx, otpData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data authServiceTypes.VerifyOtpType
+			if err := c.BodyParser(&data); err != nil {
+				return nil
+			}
+			data.UserAgent = c.Get("User-Agent")
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"auth/internal/database/models"
+	"auth/internal/types/authServiceTypes"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	httpHelper "auth/internal/utils/helpers/httpHelper"
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (authController *AuthController) CreateSession(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.CreateSession(ctx, data.(authServiceTypes.CreateSessionType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return authServiceTypes.CreateSessionType{
+				Uid:       c.Locals("uid").(string),
+				UserAgent: c.Get("User-Agent"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) RefreshSession(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			refreshData, ok := data.(authServiceTypes.RefreshSessionType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return authController.AuthService.RefreshSession(ctx, refreshData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data authServiceTypes.RefreshSessionType
+			if err := c.BodyParser(&data); err != nil {
+				return nil
+			}
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) ListSessions(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.ListSessions(ctx, data.(authServiceTypes.RevokeSessionType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return authServiceTypes.RevokeSessionType{
+				AuthId:    c.Locals("authId").(string),
+				SessionID: c.Locals("sessionID").(string),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) RevokeSession(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.RevokeSession(ctx, data.(authServiceTypes.RevokeSessionType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return authServiceTypes.RevokeSessionType{
+				AuthId:    c.Locals("authId").(string),
+				SessionID: c.Params("sessionID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (authController *AuthController) RevokeSessions(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx *context.Context, data interface{}) (interface{}, error) {
+			return authController.AuthService.RevokeSessions(ctx, data.(string), models.SessionRevokedAdministrator)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return c.Params("authId")
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+// JWKS is fetched by the other services to verify access tokens, it is
+// served bare as they expect and may be cached for a few minutes.
+func (authController *AuthController) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(authController.AuthService.JWKS())
+}
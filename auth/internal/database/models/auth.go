@@ -6,6 +6,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const AuthStatusDeleted = "deleted"
+
+// Auth is a user of the service. A deleted user is kept as a tombstone: its
+// status is deleted and its identifier and phone no longer point at anyone.
 type Auth struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty"`
 	Identifier string             `bson:"identifier,omitempty" unique:"true" required:"true"`
@@ -13,4 +17,5 @@ type Auth struct {
 	Status     string             `bson:"status,omitempty"`
 	CreatedAt  time.Time          `bson:"createdAt,omitempty"`
 	UpdatedAt  time.Time          `bson:"updatedAt,omitempty"`
+	DeletedAt  *time.Time         `bson:"deletedAt,omitempty"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DataExportStatusRequested = "requested"
+	DataExportStatusReady     = "ready"
+	DataExportStatusFailed    = "failed"
+)
+
+// DataExport is a request of a user for a copy of their data. Profiles and
+// media add their part and media packages the archive, DownloadURL is a
+// signed link that stops working at ExpiresAt.
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	AuthId      string             `bson:"authId,omitempty" index:"true"`
+	Status      string             `bson:"status,omitempty" index:"true"`
+	DownloadURL string             `bson:"downloadURL,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expiresAt,omitempty"`
+	ReadyAt     *time.Time         `bson:"readyAt,omitempty"`
+	NotifiedAt  *time.Time         `bson:"notifiedAt,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt,omitempty"`
+	UpdatedAt   time.Time          `bson:"updatedAt,omitempty"`
+}
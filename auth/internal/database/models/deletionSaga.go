@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DeletionSagaStatusInProgress = "inProgress"
+	DeletionSagaStatusCompleted  = "completed"
+)
+
+const (
+	DeletionStepStatusPending      = "pending"
+	DeletionStepStatusAcknowledged = "acknowledged"
+)
+
+// Services taking part in an account deletion, auth runs its own step.
+const (
+	DeletionStepAuth     = "auth"
+	DeletionStepProfiles = "profiles"
+	DeletionStepMedia    = "media"
+)
+
+var DeletionSteps = []string{DeletionStepAuth, DeletionStepProfiles, DeletionStepMedia}
+
+// DeletionStep is the part of an account deletion one service carries out.
+// Until the service acknowledges it, it is requested again at NextAttemptAt.
+type DeletionStep struct {
+	Status          string     `bson:"status" json:"status"`
+	Attempts        int        `bson:"attempts" json:"attempts"`
+	LastRequestedAt *time.Time `bson:"lastRequestedAt,omitempty" json:"lastRequestedAt,omitempty"`
+	NextAttemptAt   time.Time  `bson:"nextAttemptAt" json:"-"`
+	AcknowledgedAt  *time.Time `bson:"acknowledgedAt,omitempty" json:"acknowledgedAt,omitempty"`
+	LastError       string     `bson:"lastError,omitempty" json:"lastError,omitempty"`
+}
+
+// DeletionSaga tracks the deletion of an account across services, keyed by
+// service in Steps. It is completed once every step is acknowledged.
+type DeletionSaga struct {
+	ID          primitive.ObjectID      `bson:"_id,omitempty"`
+	AuthId      string                  `bson:"authId,omitempty" unique:"true"`
+	Status      string                  `bson:"status,omitempty" index:"true"`
+	Steps       map[string]DeletionStep `bson:"steps,omitempty"`
+	CompletedAt *time.Time              `bson:"completedAt,omitempty"`
+	CreatedAt   time.Time               `bson:"createdAt,omitempty"`
+	UpdatedAt   time.Time               `bson:"updatedAt,omitempty"`
+}
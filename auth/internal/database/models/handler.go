@@ -26,6 +26,26 @@ var (
 			CollectionName: "auth",
 			Timestamps:     true,
 		},
+		reflect.TypeOf(Session{}): {
+			Model:          Session{},
+			CollectionName: "sessions",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(DeletionSaga{}): {
+			Model:          DeletionSaga{},
+			CollectionName: "deletionSagas",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(OtpChallenge{}): {
+			Model:          OtpChallenge{},
+			CollectionName: "otpChallenges",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(DataExport{}): {
+			Model:          DataExport{},
+			CollectionName: "dataExports",
+			Timestamps:     true,
+		},
 	}
 )
 
@@ -157,6 +177,23 @@ func UpdateMany(ctx *context.Context, db *mongo.Database, model interface{}, fil
 	return result, nil
 }
 
+// FindOneAndIncrement increments the fields of the first document matching
+// the filter and returns it as updated.
+func FindOneAndIncrement(ctx *context.Context, db *mongo.Database, model interface{}, filter bson.M, incData map[string]interface{}) *mongo.SingleResult {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	update := bson.M{"$inc": incData}
+	if setData := beforeUpdate(model); len(setData) > 0 {
+		update["$set"] = setData
+	}
+	return collection.FindOneAndUpdate(*ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+}
+
+// Count counts the documents matching the filter.
+func Count(ctx *context.Context, db *mongo.Database, model interface{}, filter bson.M) (int64, error) {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	return collection.CountDocuments(*ctx, filter)
+}
+
 func FindOne(ctx *context.Context, db *mongo.Database, model interface{}) *mongo.SingleResult {
 	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
 	var filter map[string]interface{}
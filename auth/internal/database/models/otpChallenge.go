@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OtpChallenge is a one time code sent to a phone number. Only its HMAC is
+// stored. A challenge is done with once it is consumed, expired or out of
+// attempts, the number and IP are kept to throttle senders.
+type OtpChallenge struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Phone      string             `bson:"phone,omitempty" index:"true"`
+	IP         string             `bson:"ip,omitempty" index:"true"`
+	CodeHash   string             `bson:"codeHash,omitempty"`
+	Attempts   int                `bson:"attempts,omitempty"`
+	SentAt     time.Time          `bson:"sentAt,omitempty"`
+	ExpiresAt  time.Time          `bson:"expiresAt,omitempty"`
+	ConsumedAt *time.Time         `bson:"consumedAt,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt,omitempty"`
+	UpdatedAt  time.Time          `bson:"updatedAt,omitempty"`
+}
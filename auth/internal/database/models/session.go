@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SessionRevokedLogout        = "logout"
+	SessionRevokedReused        = "refreshTokenReused"
+	SessionRevokedAdministrator = "administrator"
+	SessionRevokedDeleted       = "accountDeleted"
+)
+
+// Session is a signed in device. Only hashes of its refresh tokens are
+// stored: the current one, and the one it replaced so a replayed token can be
+// told apart from an unknown one.
+type Session struct {
+	ID                       primitive.ObjectID `bson:"_id,omitempty"`
+	AuthId                   string             `bson:"authId,omitempty" index:"true"`
+	RefreshTokenHash         string             `bson:"refreshTokenHash,omitempty" unique:"true"`
+	PreviousRefreshTokenHash string             `bson:"previousRefreshTokenHash,omitempty" index:"true"`
+	UserAgent                string             `bson:"userAgent,omitempty"`
+	ExpiresAt                time.Time          `bson:"expiresAt,omitempty"`
+	LastUsedAt               time.Time          `bson:"lastUsedAt,omitempty"`
+	RevokedAt                *time.Time         `bson:"revokedAt,omitempty"`
+	RevokedReason            string             `bson:"revokedReason,omitempty"`
+	CreatedAt                time.Time          `bson:"createdAt,omitempty"`
+	UpdatedAt                time.Time          `bson:"updatedAt,omitempty"`
+}
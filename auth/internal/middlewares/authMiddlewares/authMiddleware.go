@@ -5,9 +5,11 @@ import (
 	firebaseHelper "auth/internal/utils/helpers/firebaseHelpers"
 	httpErrors "auth/internal/utils/helpers/httpError"
 	"auth/internal/utils/helpers/httpHelper"
+	"auth/internal/utils/helpers/jwtHelpers"
 	"context"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,6 +17,10 @@ import (
 func VerifyInternalAccess(c *fiber.Ctx) error {
 	// Retrieve the 'Access-Token' header
 	accessToken := c.Get("Access-Token")
+	// Pub/Sub push subscriptions can only pass it in the URL
+	if len(accessToken) == 0 {
+		accessToken = c.Query("accessToken")
+	}
 
 	// Check if the token matches the expected internal access token
 	if config.GetConfig().InternalAccessToken != accessToken {
@@ -65,3 +71,18 @@ func ValidateFirebaseToken(c *fiber.Ctx) error {
 	// Proceed to next middleware or handler
 	return c.Next()
 }
+
+// VerifySessionToken accepts the access tokens this service issues.
+func VerifySessionToken(c *fiber.Ctx) error {
+	bearerToken := strings.Split(c.Get("Authorization"), "Bearer ")
+	if len(bearerToken) != 2 {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+	}
+	claims, err := jwtHelpers.GetSigner().Verify(bearerToken[1], config.GetConfig().Jwt.Issuer, time.Now())
+	if err != nil {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+	}
+	c.Locals("authId", claims.ID)
+	c.Locals("sessionID", claims.SessionID)
+	return c.Next()
+}
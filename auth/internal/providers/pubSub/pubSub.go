@@ -0,0 +1,55 @@
+package PubSub
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// PubSub publishes through the REST API of Pub/Sub with the application
+// default credentials.
+type PubSub struct {
+	service   *pubsub.Service
+	projectID string
+}
+
+var pubSub *PubSub
+var once sync.Once
+
+func Init(ctx context.Context, projectID string) {
+	once.Do(func() {
+		service, err := pubsub.NewService(ctx)
+		if err != nil {
+			panic(err)
+		}
+		pubSub = &PubSub{service: service, projectID: projectID}
+	})
+}
+
+func GetClient() *PubSub {
+	if pubSub == nil {
+		panic("PubSub client not initialized, call Init(...) first")
+	}
+	return pubSub
+}
+
+func (ps *PubSub) PublishToService(ctx context.Context, serviceName string, message PubSubMessageType) error {
+	messageData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+	topic := fmt.Sprintf("projects/%s/topics/%s", ps.projectID, serviceName)
+	res, err := ps.service.Projects.Topics.Publish(topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{Data: base64.StdEncoding.EncodeToString(messageData)}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %v", err)
+	}
+	log.Printf("Message published with IDs: %v", res.MessageIds)
+	return nil
+}
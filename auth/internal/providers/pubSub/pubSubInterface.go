@@ -0,0 +1,7 @@
+package PubSub
+
+import "context"
+
+type PubSubInterface interface {
+	PublishToService(ctx context.Context, serviceName string, message PubSubMessageType) error
+}
@@ -0,0 +1,62 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// APISender hands messages to an SMS gateway. It is posted
+// {"to": "+91...", "from": "...", "message": "..."} and answers with any 2xx.
+type APISender struct {
+	URL      string
+	APIKey   string
+	SenderID string
+	Client   *http.Client
+}
+
+func NewAPISender(url string, apiKey string, senderID string) *APISender {
+	return &APISender{
+		URL:      url,
+		APIKey:   apiKey,
+		SenderID: senderID,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type apiSendRequest struct {
+	To      string `json:"to"`
+	From    string `json:"from,omitempty"`
+	Message string `json:"message"`
+}
+
+func (sender *APISender) Send(ctx context.Context, phone string, message string) error {
+	body, err := json.Marshal(apiSendRequest{
+		To:      phone,
+		From:    sender.SenderID,
+		Message: message,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sender.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+sender.APIKey)
+	}
+	res, err := sender.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("SMS API responded with status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package sms
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ConsoleSender logs messages instead of sending them and keeps them in
+// memory, for development and tests.
+type ConsoleSender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewConsoleSender() *ConsoleSender {
+	return &ConsoleSender{}
+}
+
+func (sender *ConsoleSender) Send(ctx context.Context, phone string, message string) error {
+	log.Printf("[SMS] to %s: %s", phone, message)
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	sender.messages = append(sender.messages, Message{Phone: phone, Body: message, SentAt: time.Now()})
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (sender *ConsoleSender) Messages() []Message {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	return append([]Message{}, sender.messages...)
+}
+
+// Last returns the last message sent to phone.
+func (sender *ConsoleSender) Last(phone string) (Message, bool) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	for i := len(sender.messages) - 1; i >= 0; i-- {
+		if sender.messages[i].Phone == phone {
+			return sender.messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,8 @@
+package sms
+
+import "context"
+
+// SmsSender delivers a text message to a phone number in E.164 format.
+type SmsSender interface {
+	Send(ctx context.Context, phone string, message string) error
+}
@@ -0,0 +1,50 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConsoleSenderRecordsMessages(t *testing.T) {
+	sender := NewConsoleSender()
+	sender.Send(context.Background(), "+919800000001", "first")
+	sender.Send(context.Background(), "+919800000002", "other")
+	sender.Send(context.Background(), "+919800000001", "second")
+
+	if len(sender.Messages()) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(sender.Messages()))
+	}
+	last, ok := sender.Last("+919800000001")
+	if !ok || last.Body != "second" {
+		t.Errorf("expected the last message to be second, got %+v", last)
+	}
+	if _, ok := sender.Last("+919800000003"); ok {
+		t.Errorf("expected no message for an unknown number")
+	}
+}
+
+func TestAPISenderPostsMessages(t *testing.T) {
+	var received apiSendRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if err := NewAPISender(server.URL, "key", "PURELY").Send(context.Background(), "+919800000001", "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if received.To != "+919800000001" || received.From != "PURELY" || received.Message != "123456" {
+		t.Errorf("unexpected request %+v", received)
+	}
+	if err := NewAPISender(server.URL, "wrong", "").Send(context.Background(), "+919800000001", "123456"); err == nil {
+		t.Errorf("expected an error for a rejected request")
+	}
+}
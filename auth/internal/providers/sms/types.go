@@ -0,0 +1,10 @@
+package sms
+
+import "time"
+
+// Message is a text message as recorded by the ConsoleSender.
+type Message struct {
+	Phone  string    `json:"phone"`
+	Body   string    `json:"body"`
+	SentAt time.Time `json:"sentAt"`
+}
@@ -3,6 +3,9 @@ package routes
 import (
 	"auth/internal/controllers"
 	"auth/internal/middlewares/authMiddlewares"
+	"auth/internal/middlewares/rateLimitMiddlewares"
+	"auth/internal/utils/helpers/rateLimitHelpers"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,5 +19,24 @@ func (r *Router) InitRoutes(router fiber.Router) {
 		return c.SendString("Hello, World!")
 	})
 	router.Post("/internal", authMiddlewares.VerifyInternalAccess, r.AuthController.InsertAuth)
-	router.Get("/token", authMiddlewares.ValidateFirebaseToken, r.AuthController.GetAuthToken)
+	// Limited by IP before the Firebase token is verified, then per user
+	router.Get("/token",
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "tokenIP", KeyBy: rateLimitMiddlewares.KeyByIP, Limit: rateLimitHelpers.Limit{Burst: 30, Per: time.Minute}}),
+		authMiddlewares.ValidateFirebaseToken,
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "token", KeyBy: rateLimitMiddlewares.KeyByAuthId, Limit: rateLimitHelpers.Limit{Burst: 10, Per: time.Minute}}),
+		r.AuthController.GetAuthToken,
+	)
+	router.Get("/.well-known/jwks.json", r.AuthController.JWKS)
+	router.Post("/sessions", authMiddlewares.ValidateFirebaseToken, r.AuthController.CreateSession)
+	router.Post("/sessions/refresh", r.AuthController.RefreshSession)
+	router.Post("/otp/start", r.AuthController.StartOtp)
+	router.Post("/otp/verify", r.AuthController.VerifyOtp)
+	router.Get("/sessions", authMiddlewares.VerifySessionToken, r.AuthController.ListSessions)
+	router.Delete("/sessions/:sessionID", authMiddlewares.VerifySessionToken, r.AuthController.RevokeSession)
+	router.Delete("/internal/sessions/:authId", authMiddlewares.VerifyInternalAccess, r.AuthController.RevokeSessions)
+	router.Post("/account/deletion", authMiddlewares.VerifySessionToken, r.AuthController.RequestAccountDeletion)
+	router.Post("/account/export", authMiddlewares.VerifySessionToken, r.AuthController.RequestDataExport)
+	router.Get("/account/export", authMiddlewares.VerifySessionToken, r.AuthController.GetDataExport)
+	router.Get("/internal/deletions/:authId", authMiddlewares.VerifyInternalAccess, r.AuthController.GetDeletionSaga)
+	router.Post("/internal/pubsub/messages", authMiddlewares.VerifyInternalAccess, r.AuthController.HandlePubSubMessage)
 }
@@ -1,17 +1,75 @@
 package server
 
 import (
+	"auth/internal/config"
 	"auth/internal/controllers"
+	"auth/internal/database"
+	"auth/internal/middlewares/rateLimitMiddlewares"
+	"auth/internal/providers/sms"
 	"auth/internal/routes"
+	"auth/internal/services"
+	"auth/internal/utils/helpers/rateLimitHelpers"
+	"context"
+	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+func newSmsSender() (sms.SmsSender, error) {
+	smsConfig := config.GetConfig().Sms
+	switch smsConfig.Provider {
+	case "console":
+		if config.GetConfig().Env == "prod" {
+			return nil, fmt.Errorf("the console SMS sender can't be used in prod")
+		}
+		return sms.NewConsoleSender(), nil
+	case "api":
+		if smsConfig.APIURL == "" {
+			return nil, fmt.Errorf("SMS_API_URL is required for the api SMS sender")
+		}
+		return sms.NewAPISender(smsConfig.APIURL, smsConfig.APIKey, smsConfig.SenderID), nil
+	}
+	return nil, fmt.Errorf("unknown SMS provider %q", smsConfig.Provider)
+}
+
+func newRateLimitStore() (rateLimitHelpers.Store, error) {
+	switch config.GetConfig().RateLimit.Store {
+	case "memory":
+		return rateLimitHelpers.NewMemoryStore(), nil
+	case "mongo":
+		return rateLimitHelpers.NewMongoStore(context.Background(), database.Mongo().Db().Collection("rateLimits"))
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q", config.GetConfig().RateLimit.Store)
+}
+
 func (s *FiberServer) RegisterFiberRoutes() {
+	if config.GetConfig().Env == "prod" && config.GetConfig().Otp.Secret == "" {
+		panic("OTP_SECRET is required in prod")
+	}
+	smsSender, err := newSmsSender()
+	if err != nil {
+		panic(err)
+	}
+	rateLimitStore, err := newRateLimitStore()
+	if err != nil {
+		panic(err)
+	}
+	rateLimits, err := rateLimitHelpers.ParseLimits(config.GetConfig().RateLimit.Limits)
+	if err != nil {
+		panic(err)
+	}
+	rateLimitMiddlewares.Configure(rateLimitStore, rateLimits)
 	rootGroup := s.App.Group("/")
 
+	authService := services.AuthService{
+		SmsSender: smsSender,
+	}
+	s.authService = &authService
+
 	authRouter := routes.Router{
-		AuthController: controllers.AuthController{},
+		AuthController: controllers.AuthController{
+			AuthService: authService,
+		},
 	}
 	authRouter.InitRoutes(rootGroup)
 
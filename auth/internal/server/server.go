@@ -1,17 +1,23 @@
 package server
 
 import (
+	"context"
+	"sync"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 
 	"auth/internal/config"
 	"auth/internal/database"
+	PubSub "auth/internal/providers/pubSub"
+	"auth/internal/services"
 )
 
 type FiberServer struct {
 	*fiber.App
 
-	db database.Service
+	db          database.Service
+	authService *services.AuthService
 }
 
 func New() *FiberServer {
@@ -19,10 +25,17 @@ func New() *FiberServer {
 		App: fiber.New(fiber.Config{
 			ServerHeader: "auth",
 			AppName:      "auth",
+			// Rate limits key on c.IP(), which only trusts the proxy header
+			// on requests from the configured load balancers
+			ProxyHeader:             config.GetConfig().Proxy.Header,
+			EnableTrustedProxyCheck: true,
+			TrustedProxies:          config.GetConfig().Proxy.TrustedProxies,
+			EnableIPValidation:      true,
 		}),
 
 		db: database.Mongo(),
 	}
+	PubSub.Init(context.Background(), config.GetConfig().GoogleProjectID)
 
 	if config.GetConfig().Env != "prod" {
 		server.App.Use(cors.New(cors.Config{
@@ -45,3 +58,9 @@ func New() *FiberServer {
 
 	return server
 }
+
+// StartDeletionSweeper retries unfinished account deletions, routes have to
+// be registered first.
+func (s *FiberServer) StartDeletionSweeper(ctx context.Context) *sync.WaitGroup {
+	return s.authService.StartDeletionSweeper(ctx)
+}
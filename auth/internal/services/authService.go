@@ -3,16 +3,18 @@ package services
 import (
 	"auth/internal/database"
 	"auth/internal/database/models"
+	"auth/internal/providers/sms"
 	firebaseHelper "auth/internal/utils/helpers/firebaseHelpers"
 	httpErrors "auth/internal/utils/helpers/httpError"
 	"context"
-	"fmt"
 	"log"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type AuthService struct{}
+type AuthService struct {
+	SmsSender sms.SmsSender
+}
 
 func (authService *AuthService) InsertAuth(ctx *context.Context, auth models.Auth) (string, error) {
 	existingAuth := models.FindOne(ctx, database.Mongo().Db(), models.Auth{Phone: auth.Phone})
@@ -29,7 +31,7 @@ func (authService *AuthService) InsertAuth(ctx *context.Context, auth models.Aut
 }
 
 func (authService *AuthService) GetAuthToken(ctx *context.Context, uid *string) (string, error) {
-	fmt.Println("UID %s", *uid)
+	log.Default().Printf("UID %s", *uid)
 	auth := models.FindOne(ctx, database.Mongo().Db(), models.Auth{Identifier: *uid})
 	if auth.Err() != nil {
 		log.Default().Println(auth.Err().Error())
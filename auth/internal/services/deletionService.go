@@ -0,0 +1,314 @@
+package services
+
+import (
+	"auth/internal/database"
+	"auth/internal/database/models"
+	PubSub "auth/internal/providers/pubSub"
+	"auth/internal/types/authServiceTypes"
+	firebaseHelper "auth/internal/utils/helpers/firebaseHelpers"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	firebaseAuth "firebase.google.com/go/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// Unacknowledged steps are looked at this often.
+	deletionSweepInterval = time.Minute
+	// Steps are requested again after a delay doubling from deletionRetryBase
+	// up to deletionRetryMax, for as long as it takes.
+	deletionRetryBase = time.Minute
+	deletionRetryMax  = time.Hour
+)
+
+func deletionRetryDelay(attempts int) time.Duration {
+	delay := deletionRetryBase
+	for i := 1; i < attempts && delay < deletionRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, deletionRetryMax)
+}
+
+func deletionSagaRes(saga models.DeletionSaga) *authServiceTypes.DeletionSagaResType {
+	return &authServiceTypes.DeletionSagaResType{
+		ID:          saga.ID.Hex(),
+		AuthId:      saga.AuthId,
+		Status:      saga.Status,
+		Steps:       saga.Steps,
+		RequestedAt: saga.CreatedAt,
+		CompletedAt: saga.CompletedAt,
+	}
+}
+
+func findDeletionSaga(ctx *context.Context, filter models.DeletionSaga) (*models.DeletionSaga, error) {
+	var saga models.DeletionSaga
+	if err := models.FindOne(ctx, database.Mongo().Db(), filter).Decode(&saga); err != nil {
+		return nil, err
+	}
+	return &saga, nil
+}
+
+// RequestAccountDeletion starts deleting the account of authId. The user is
+// signed out right away, profiles and media are asked to delete their data
+// and the saga completes once both acknowledged. Asking again returns the
+// deletion already under way.
+func (authService *AuthService) RequestAccountDeletion(ctx *context.Context, authId string) (*authServiceTypes.DeletionSagaResType, error) {
+	saga, err := findDeletionSaga(ctx, models.DeletionSaga{AuthId: authId})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		now := time.Now()
+		steps := map[string]models.DeletionStep{}
+		for _, step := range models.DeletionSteps {
+			steps[step] = models.DeletionStep{Status: models.DeletionStepStatusPending, NextAttemptAt: now}
+		}
+		saga = &models.DeletionSaga{
+			ID:     primitive.NewObjectID(),
+			AuthId: authId,
+			Status: models.DeletionSagaStatusInProgress,
+			Steps:  steps,
+		}
+		if _, err := models.Create(ctx, database.Mongo().Db(), *saga); err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				return nil, err
+			}
+		}
+		saga, err = findDeletionSaga(ctx, models.DeletionSaga{AuthId: authId})
+	}
+	if err != nil {
+		return nil, err
+	}
+	authService.advanceDeletionSaga(ctx, *saga)
+	return authService.GetDeletionSaga(ctx, authId)
+}
+
+// GetDeletionSaga reports how far the deletion of an account got.
+func (authService *AuthService) GetDeletionSaga(ctx *context.Context, authId string) (*authServiceTypes.DeletionSagaResType, error) {
+	saga, err := findDeletionSaga(ctx, models.DeletionSaga{AuthId: authId})
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/requests/deletions/errors/not-found", 404, "No deletion requested")
+	}
+	return deletionSagaRes(*saga), nil
+}
+
+// advanceDeletionSaga runs or requests every step that is due. The auth step
+// runs here, the others are requested from their service over Pub/Sub.
+func (authService *AuthService) advanceDeletionSaga(ctx *context.Context, saga models.DeletionSaga) {
+	now := time.Now()
+	for _, name := range models.DeletionSteps {
+		step, ok := saga.Steps[name]
+		if !ok || step.Status != models.DeletionStepStatusPending || step.NextAttemptAt.After(now) {
+			continue
+		}
+		attempts := step.Attempts + 1
+		prefix := "steps." + name + "."
+		update := map[string]interface{}{
+			prefix + "attempts":        attempts,
+			prefix + "lastRequestedAt": now,
+			prefix + "nextAttemptAt":   now.Add(deletionRetryDelay(attempts)),
+			prefix + "lastError":       "",
+		}
+		var err error
+		if name == models.DeletionStepAuth {
+			if err = authService.tombstoneAuth(ctx, saga.AuthId); err == nil {
+				update[prefix+"status"] = models.DeletionStepStatusAcknowledged
+				update[prefix+"acknowledgedAt"] = now
+			}
+		} else {
+			err = PubSub.GetClient().PublishToService(*ctx, name, PubSub.PubSubMessageType{
+				Type: "deleteAccount",
+				Data: map[string]interface{}{
+					"authId": saga.AuthId,
+					"sagaID": saga.ID.Hex(),
+				},
+			})
+		}
+		if err != nil {
+			log.Default().Printf("Error running %s step of deletion %s: %v", name, saga.ID.Hex(), err)
+			update[prefix+"lastError"] = err.Error()
+		}
+		if _, err := models.UpdateOne(ctx, database.Mongo().Db(), models.DeletionSaga{}, bson.M{
+			"_id":             saga.ID,
+			prefix + "status": models.DeletionStepStatusPending,
+		}, update); err != nil {
+			log.Default().Printf("Error updating %s step of deletion %s: %v", name, saga.ID.Hex(), err)
+		}
+	}
+	completeDeletionSaga(ctx, saga.ID)
+}
+
+// tombstoneAuth signs the user out everywhere and turns their Auth record into
+// a tombstone, freeing the phone number. Their Firebase user is deleted.
+func (authService *AuthService) tombstoneAuth(ctx *context.Context, authId string) error {
+	authObjectID, err := primitive.ObjectIDFromHex(authId)
+	if err != nil {
+		return err
+	}
+	var auth models.Auth
+	err = models.FindOne(ctx, database.Mongo().Db(), models.Auth{ID: authObjectID}).Decode(&auth)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if err == nil && auth.Status != models.AuthStatusDeleted {
+		// Records created by phone sign in have no Firebase user
+		if !strings.HasPrefix(auth.Identifier, "phone:") {
+			client, err := firebaseHelper.App().Auth(*ctx)
+			if err != nil {
+				return err
+			}
+			if err := client.DeleteUser(*ctx, auth.Identifier); err != nil && !firebaseAuth.IsUserNotFound(err) {
+				return err
+			}
+		}
+		if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Auth{}, auth.ID, map[string]interface{}{
+			"status":     models.AuthStatusDeleted,
+			"identifier": "deleted:" + authId,
+			"phone":      "deleted:" + authId,
+			"deletedAt":  time.Now(),
+		}); err != nil {
+			return err
+		}
+	}
+	_, err = authService.RevokeSessions(ctx, authId, models.SessionRevokedDeleted)
+	return err
+}
+
+// AcknowledgeDeletionStep records that a service deleted its data of an
+// account.
+func (authService *AuthService) AcknowledgeDeletionStep(ctx *context.Context, sagaID string, authId string, service string) error {
+	sagaObjectID, err := primitive.ObjectIDFromHex(sagaID)
+	if err != nil {
+		return httpErrors.HydrateHttpError("purely/requests/deletions/errors/invalid-saga-id", 400, "Invalid deletion ID")
+	}
+	if service == models.DeletionStepAuth || !slices.Contains(models.DeletionSteps, service) {
+		return httpErrors.HydrateHttpError("purely/requests/deletions/errors/invalid-step", 400, "Unknown deletion step")
+	}
+	prefix := "steps." + service + "."
+	if _, err := models.UpdateOne(ctx, database.Mongo().Db(), models.DeletionSaga{}, bson.M{
+		"_id":             sagaObjectID,
+		"authId":          authId,
+		prefix + "status": models.DeletionStepStatusPending,
+	}, map[string]interface{}{
+		prefix + "status":         models.DeletionStepStatusAcknowledged,
+		prefix + "acknowledgedAt": time.Now(),
+		prefix + "lastError":      "",
+	}); err != nil {
+		return err
+	}
+	completeDeletionSaga(ctx, sagaObjectID)
+	return nil
+}
+
+// completeDeletionSaga marks a saga completed once all its steps are
+// acknowledged.
+func completeDeletionSaga(ctx *context.Context, sagaID primitive.ObjectID) {
+	saga, err := findDeletionSaga(ctx, models.DeletionSaga{ID: sagaID})
+	if err != nil {
+		log.Default().Printf("Error fetching deletion %s: %v", sagaID.Hex(), err)
+		return
+	}
+	if saga.Status == models.DeletionSagaStatusCompleted {
+		return
+	}
+	for _, step := range saga.Steps {
+		if step.Status != models.DeletionStepStatusAcknowledged {
+			return
+		}
+	}
+	if _, err := models.UpdateOne(ctx, database.Mongo().Db(), models.DeletionSaga{}, bson.M{
+		"_id":    sagaID,
+		"status": models.DeletionSagaStatusInProgress,
+	}, map[string]interface{}{
+		"status":      models.DeletionSagaStatusCompleted,
+		"completedAt": time.Now(),
+	}); err != nil {
+		log.Default().Printf("Error completing deletion %s: %v", sagaID.Hex(), err)
+		return
+	}
+	log.Default().Printf("Deletion of %s completed", saga.AuthId)
+}
+
+// RetryDeletionSagas requests again the steps of unfinished deletions that
+// are due.
+func (authService *AuthService) RetryDeletionSagas(ctx *context.Context) error {
+	cursor, err := models.Find(ctx, database.Mongo().Db(), models.DeletionSaga{Status: models.DeletionSagaStatusInProgress}, nil)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(*ctx)
+	var sagas []models.DeletionSaga
+	if err := cursor.All(*ctx, &sagas); err != nil {
+		return err
+	}
+	for _, saga := range sagas {
+		authService.advanceDeletionSaga(ctx, saga)
+	}
+	return nil
+}
+
+// StartDeletionSweeper retries unfinished deletions in the background until
+// ctx is done.
+func (authService *AuthService) StartDeletionSweeper(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			sweepCtx, cancel := context.WithTimeout(ctx, deletionSweepInterval)
+			if err := authService.RetryDeletionSagas(&sweepCtx); err != nil {
+				log.Default().Printf("Error retrying deletions: %v", err)
+			}
+			cancel()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(deletionSweepInterval):
+			}
+		}
+	}()
+	return &wg
+}
+
+// HandlePubSubMessage handles the messages other services publish to auth.
+// Returning false has the message delivered again.
+func (authService *AuthService) HandlePubSubMessage(ctx *context.Context, data PubSub.PubSubMessageType) bool {
+	switch data.Type {
+	case "accountDeletionStepCompleted":
+		{
+			sagaID, _ := data.Data["sagaID"].(string)
+			authId, _ := data.Data["authId"].(string)
+			service, _ := data.Data["service"].(string)
+			if err := authService.AcknowledgeDeletionStep(ctx, sagaID, authId, service); err != nil {
+				log.Default().Printf("Error acknowledging %s step of deletion %s: %v", service, sagaID, err)
+				var httpErr *httpErrors.HttpError
+				// Bad requests won't get better on redelivery
+				return errors.As(err, &httpErr) && httpErr.StatusCode < 500
+			}
+		}
+	case "accountExportReady":
+		{
+			exportID, _ := data.Data["exportID"].(string)
+			authId, _ := data.Data["authId"].(string)
+			downloadURL, _ := data.Data["downloadURL"].(string)
+			rawExpiresAt, _ := data.Data["expiresAt"].(string)
+			expiresAt, err := time.Parse(time.RFC3339, rawExpiresAt)
+			if err != nil || downloadURL == "" {
+				log.Default().Printf("Invalid ready export %s", exportID)
+				return true
+			}
+			if err := authService.CompleteDataExport(ctx, exportID, authId, downloadURL, expiresAt); err != nil {
+				log.Default().Printf("Error completing export %s: %v", exportID, err)
+				var httpErr *httpErrors.HttpError
+				return errors.As(err, &httpErr) && httpErr.StatusCode < 500
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"auth/internal/database"
+	"auth/internal/database/models"
+	PubSub "auth/internal/providers/pubSub"
+	"auth/internal/types/authServiceTypes"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// An export still being prepared is returned when asked again, after this
+// long it is considered lost and a new one can be requested.
+const dataExportStaleAfter = 24 * time.Hour
+
+func dataExportRes(export models.DataExport) *authServiceTypes.DataExportResType {
+	res := &authServiceTypes.DataExportResType{
+		ID:          export.ID.Hex(),
+		Status:      export.Status,
+		RequestedAt: export.CreatedAt,
+		ReadyAt:     export.ReadyAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+	if export.ExpiresAt != nil && time.Now().Before(*export.ExpiresAt) {
+		res.DownloadURL = export.DownloadURL
+	}
+	return res
+}
+
+// lastDataExport returns the most recent export of a user, nil if they never
+// asked for one.
+func lastDataExport(ctx *context.Context, authId string) (*models.DataExport, error) {
+	cursor, err := models.Find(ctx, database.Mongo().Db(), models.DataExport{AuthId: authId}, options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(*ctx)
+	var exports []models.DataExport
+	if err := cursor.All(*ctx, &exports); err != nil {
+		return nil, err
+	}
+	if len(exports) == 0 {
+		return nil, nil
+	}
+	return &exports[0], nil
+}
+
+// RequestDataExport starts preparing a copy of everything held about a user.
+// The Auth record is sent to profiles, which adds the profiles and passes it
+// on to media to be packaged with the photos. The user is notified once the
+// archive is ready.
+func (authService *AuthService) RequestDataExport(ctx *context.Context, authId string) (*authServiceTypes.DataExportResType, error) {
+	authObjectID, err := primitive.ObjectIDFromHex(authId)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/requests/exports/errors/invalid-user", 400, "Invalid user")
+	}
+	var auth models.Auth
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Auth{ID: authObjectID}).Decode(&auth); err != nil || auth.Status == models.AuthStatusDeleted {
+		return nil, httpErrors.HydrateHttpError("purely/requests/exports/errors/invalid-user", 400, "Could not find user")
+	}
+
+	last, err := lastDataExport(ctx, authId)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil && last.Status == models.DataExportStatusRequested && time.Since(last.CreatedAt) < dataExportStaleAfter {
+		return dataExportRes(*last), nil
+	}
+
+	export := models.DataExport{
+		ID:     primitive.NewObjectID(),
+		AuthId: authId,
+		Status: models.DataExportStatusRequested,
+	}
+	if _, err := models.Create(ctx, database.Mongo().Db(), export); err != nil {
+		return nil, err
+	}
+	if err := PubSub.GetClient().PublishToService(*ctx, "profiles", PubSub.PubSubMessageType{
+		Type: "exportAccount",
+		Data: map[string]interface{}{
+			"exportID": export.ID.Hex(),
+			"authId":   authId,
+			"auth": map[string]interface{}{
+				"id":         authId,
+				"identifier": auth.Identifier,
+				"phone":      auth.Phone,
+				"status":     auth.Status,
+				"createdAt":  auth.CreatedAt,
+				"updatedAt":  auth.UpdatedAt,
+			},
+		},
+	}); err != nil {
+		log.Default().Printf("Error requesting export %s: %v", export.ID.Hex(), err)
+		// Lets the user ask again right away
+		if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.DataExport{}, export.ID, map[string]interface{}{
+			"status": models.DataExportStatusFailed,
+		}); err != nil {
+			log.Default().Printf("Error updating export %s: %v", export.ID.Hex(), err)
+		}
+		return nil, httpErrors.HydrateHttpError("purely/requests/exports/errors/could-not-request", 500, "Failed to request export")
+	}
+	created, err := lastDataExport(ctx, authId)
+	if err != nil || created == nil {
+		return nil, err
+	}
+	return dataExportRes(*created), nil
+}
+
+// GetDataExport reports the last export of a user, with its download link
+// while it is valid.
+func (authService *AuthService) GetDataExport(ctx *context.Context, authId string) (*authServiceTypes.DataExportResType, error) {
+	export, err := lastDataExport(ctx, authId)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, httpErrors.HydrateHttpError("purely/requests/exports/errors/not-found", 404, "No export requested")
+	}
+	return dataExportRes(*export), nil
+}
+
+// CompleteDataExport records the link media packaged an export under and
+// tells the user about it. Users with a phone number get a text, the others
+// see the link when they look at their export.
+func (authService *AuthService) CompleteDataExport(ctx *context.Context, exportID string, authId string, downloadURL string, expiresAt time.Time) error {
+	exportObjectID, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return httpErrors.HydrateHttpError("purely/requests/exports/errors/invalid-export-id", 400, "Invalid export ID")
+	}
+	now := time.Now()
+	result, err := models.UpdateOne(ctx, database.Mongo().Db(), models.DataExport{}, bson.M{
+		"_id":    exportObjectID,
+		"authId": authId,
+		"status": models.DataExportStatusRequested,
+	}, map[string]interface{}{
+		"status":      models.DataExportStatusReady,
+		"downloadURL": downloadURL,
+		"expiresAt":   expiresAt,
+		"readyAt":     now,
+	})
+	if err != nil {
+		return err
+	}
+	// Redelivered, the user was already told
+	if result.MatchedCount == 0 {
+		return nil
+	}
+
+	authObjectID, err := primitive.ObjectIDFromHex(authId)
+	if err != nil {
+		return nil
+	}
+	var auth models.Auth
+	err = models.FindOne(ctx, database.Mongo().Db(), models.Auth{ID: authObjectID}).Decode(&auth)
+	if errors.Is(err, mongo.ErrNoDocuments) || auth.Phone == "" || auth.Status == models.AuthStatusDeleted {
+		return nil
+	}
+	if err != nil {
+		log.Default().Printf("Error fetching user of export %s: %v", exportID, err)
+		return nil
+	}
+	message := fmt.Sprintf("Your Purely data export is ready. Download it before %s: %s", expiresAt.UTC().Format("2 Jan 2006 15:04 MST"), downloadURL)
+	if err := authService.SmsSender.Send(*ctx, auth.Phone, message); err != nil {
+		log.Default().Printf("Error notifying user of export %s: %v", exportID, err)
+		return nil
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.DataExport{}, exportObjectID, map[string]interface{}{
+		"notifiedAt": now,
+	}); err != nil {
+		log.Default().Printf("Error updating export %s: %v", exportID, err)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"auth/internal/config"
+	"auth/internal/database"
+	"auth/internal/database/models"
+	"auth/internal/types/authServiceTypes"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const otpCodeLength = 6
+
+// Senders are throttled over this window.
+const otpThrottleWindow = time.Hour
+
+var (
+	e164Pattern    = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	otpCodePattern = regexp.MustCompile(fmt.Sprintf(`^[0-9]{%d}$`, otpCodeLength))
+	phoneSeparator = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+)
+
+var errOtpExpired = httpErrors.HydrateHttpError("purely/requests/otp/errors/expired", 400, "Code expired, ask for a new one")
+
+// normalizePhone strips the separators people type and checks the number is
+// in E.164 format.
+func normalizePhone(phone string) (string, bool) {
+	phone = phoneSeparator.Replace(strings.TrimSpace(phone))
+	return phone, e164Pattern.MatchString(phone)
+}
+
+func newOtpCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpCodeLength, n.Int64()), nil
+}
+
+// hashOtpCode keys the code with the challenge so a leaked hash can't be
+// matched against other challenges.
+func hashOtpCode(challengeID primitive.ObjectID, code string) string {
+	mac := hmac.New(sha256.New, []byte(config.GetConfig().Otp.Secret))
+	mac.Write([]byte(challengeID.Hex() + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newOtpChallenge(phone string, ip string, code string, now time.Time) models.OtpChallenge {
+	challenge := models.OtpChallenge{
+		ID:        primitive.NewObjectID(),
+		Phone:     phone,
+		IP:        ip,
+		SentAt:    now,
+		ExpiresAt: now.Add(config.GetConfig().Otp.TTL),
+	}
+	challenge.CodeHash = hashOtpCode(challenge.ID, code)
+	return challenge
+}
+
+// otpAttemptFilter matches a challenge that can still be tried. Challenges
+// are stored without an attempts field until the first try, which $lt alone
+// never matches.
+func otpAttemptFilter(challengeID primitive.ObjectID, maxAttempts int) bson.M {
+	return bson.M{
+		"_id": challengeID,
+		"$or": bson.A{
+			bson.M{"attempts": bson.M{"$exists": false}},
+			bson.M{"attempts": bson.M{"$lt": maxAttempts}},
+		},
+		"consumedAt": bson.M{"$exists": false},
+	}
+}
+
+func latestOtpChallenge(ctx *context.Context, phone string) (*models.OtpChallenge, error) {
+	cursor, err := models.Find(ctx, database.Mongo().Db(), models.OtpChallenge{Phone: phone}, options.Find().SetSort(bson.M{"sentAt": -1}).SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(*ctx)
+	var challenges []models.OtpChallenge
+	if err := cursor.All(*ctx, &challenges); err != nil {
+		return nil, err
+	}
+	if len(challenges) == 0 {
+		return nil, nil
+	}
+	return &challenges[0], nil
+}
+
+// StartOtp texts a sign in code to a phone number. Asking again is allowed
+// once the resend cooldown is over and replaces the previous code. Numbers
+// and IPs asking too often within an hour are turned away.
+func (authService *AuthService) StartOtp(ctx *context.Context, data authServiceTypes.StartOtpType) (*authServiceTypes.StartOtpResType, error) {
+	phone, ok := normalizePhone(data.Phone)
+	if !ok {
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/invalid-phone", 400, "Phone number must be in international format, like +919876543210")
+	}
+	otpConfig := config.GetConfig().Otp
+	now := time.Now()
+
+	latest, err := latestOtpChallenge(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && now.Sub(latest.SentAt) < otpConfig.ResendCooldown {
+		wait := int((otpConfig.ResendCooldown - now.Sub(latest.SentAt)).Seconds()) + 1
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/resend-cooldown", 429, fmt.Sprintf("Wait %d seconds before asking for another code", wait))
+	}
+	since := now.Add(-otpThrottleWindow)
+	sentToPhone, err := models.Count(ctx, database.Mongo().Db(), models.OtpChallenge{}, bson.M{"phone": phone, "sentAt": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+	if sentToPhone >= int64(otpConfig.MaxPerPhone) {
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/phone-throttled", 429, "Too many codes sent to this number, try again later")
+	}
+	if data.IP != "" {
+		sentFromIP, err := models.Count(ctx, database.Mongo().Db(), models.OtpChallenge{}, bson.M{"ip": data.IP, "sentAt": bson.M{"$gte": since}})
+		if err != nil {
+			return nil, err
+		}
+		if sentFromIP >= int64(otpConfig.MaxPerIP) {
+			return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/ip-throttled", 429, "Too many codes requested, try again later")
+		}
+	}
+
+	code, err := newOtpCode()
+	if err != nil {
+		return nil, err
+	}
+	challenge := newOtpChallenge(phone, data.IP, code, now)
+	// Stored before sending so failed sends count towards the throttles too
+	if _, err := models.Create(ctx, database.Mongo().Db(), challenge); err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("%s is your Purely code. It expires in %d minutes, don't share it with anyone.", code, int(otpConfig.TTL.Minutes()))
+	if err := authService.SmsSender.Send(*ctx, phone, message); err != nil {
+		log.Default().Printf("Error sending OTP to %s: %v", phone, err)
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/sms-failed", 502, "Could not send the code, try again")
+	}
+	// Only the last code sent works
+	if _, err := models.UpdateMany(ctx, database.Mongo().Db(), models.OtpChallenge{}, bson.M{
+		"phone":      phone,
+		"_id":        bson.M{"$ne": challenge.ID},
+		"consumedAt": bson.M{"$exists": false},
+	}, map[string]interface{}{
+		"consumedAt": now,
+	}); err != nil {
+		log.Default().Printf("Error replacing previous OTPs of %s: %v", phone, err)
+	}
+
+	return &authServiceTypes.StartOtpResType{
+		Phone:     phone,
+		ExpiresIn: int(otpConfig.TTL.Seconds()),
+		ResendIn:  int(otpConfig.ResendCooldown.Seconds()),
+	}, nil
+}
+
+// VerifyOtp checks the last code texted to a number. On success the code is
+// used up and a session is opened for the Auth record of the number, created
+// on first sign in.
+func (authService *AuthService) VerifyOtp(ctx *context.Context, data authServiceTypes.VerifyOtpType) (*authServiceTypes.SessionTokensType, error) {
+	phone, ok := normalizePhone(data.Phone)
+	if !ok {
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/invalid-phone", 400, "Phone number must be in international format, like +919876543210")
+	}
+	if !otpCodePattern.MatchString(data.Code) {
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/invalid-code", 400, "Wrong code")
+	}
+	latest, err := latestOtpChallenge(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil || latest.ConsumedAt != nil || time.Now().After(latest.ExpiresAt) {
+		return nil, errOtpExpired
+	}
+
+	// The attempt is counted before the code is checked, so parallel guesses
+	// can't go past the limit
+	var challenge models.OtpChallenge
+	err = models.FindOneAndIncrement(ctx, database.Mongo().Db(), models.OtpChallenge{}, otpAttemptFilter(latest.ID, config.GetConfig().Otp.MaxAttempts), map[string]interface{}{"attempts": 1}).Decode(&challenge)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/attempts-exceeded", 429, "Too many wrong codes, ask for a new one")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(hashOtpCode(challenge.ID, data.Code)), []byte(challenge.CodeHash)) {
+		return nil, httpErrors.HydrateHttpError("purely/requests/otp/errors/invalid-code", 400, "Wrong code")
+	}
+	result, err := models.UpdateOne(ctx, database.Mongo().Db(), models.OtpChallenge{}, bson.M{
+		"_id":        challenge.ID,
+		"consumedAt": bson.M{"$exists": false},
+	}, map[string]interface{}{
+		"consumedAt": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errOtpExpired
+	}
+
+	auth, created, err := findOrCreatePhoneAuth(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := openSession(ctx, *auth, data.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	tokens.NewUser = created
+	return tokens, nil
+}
+
+// findOrCreatePhoneAuth returns the Auth record of a phone number. Records
+// created here have no Firebase user, their identifier is derived from the
+// number.
+func findOrCreatePhoneAuth(ctx *context.Context, phone string) (*models.Auth, bool, error) {
+	var auth models.Auth
+	err := models.FindOne(ctx, database.Mongo().Db(), models.Auth{Phone: phone}).Decode(&auth)
+	if err == nil {
+		return &auth, false, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, err
+	}
+	auth = models.Auth{
+		ID:         primitive.NewObjectID(),
+		Identifier: "phone:" + phone,
+		Phone:      phone,
+	}
+	if _, err := models.Create(ctx, database.Mongo().Db(), auth); err != nil {
+		// Signed up by a concurrent verification
+		if mongo.IsDuplicateKeyError(err) {
+			if err := models.FindOne(ctx, database.Mongo().Db(), models.Auth{Phone: phone}).Decode(&auth); err == nil {
+				return &auth, false, nil
+			}
+		}
+		return nil, false, err
+	}
+	return &auth, true, nil
+}
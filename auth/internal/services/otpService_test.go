@@ -0,0 +1,88 @@
+package services
+
+import (
+	"auth/internal/database/models"
+	"crypto/hmac"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// matches evaluates the operators otpAttemptFilter uses against a document
+// the way MongoDB does: comparisons never match a missing field.
+func matches(doc bson.M, filter bson.M) bool {
+	for key, cond := range filter {
+		if key == "$or" {
+			matched := false
+			for _, branch := range cond.(bson.A) {
+				matched = matched || matches(doc, branch.(bson.M))
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+		value, present := doc[key]
+		ops, isOps := cond.(bson.M)
+		if !isOps {
+			if !present || value != cond {
+				return false
+			}
+			continue
+		}
+		for op, operand := range ops {
+			switch op {
+			case "$exists":
+				if present != operand.(bool) {
+					return false
+				}
+			case "$lt":
+				n, ok := value.(int32)
+				if !present || !ok || int(n) >= operand.(int) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// storedOtpChallenge returns a freshly started challenge as it is inserted.
+func storedOtpChallenge(t *testing.T, attempts int) (models.OtpChallenge, bson.M) {
+	challenge := newOtpChallenge("+919876543210", "127.0.0.1", "123456", time.Now())
+	challenge.Attempts = attempts
+	raw, err := bson.Marshal(challenge)
+	if err != nil {
+		t.Fatalf("marshal challenge: %v", err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshal challenge: %v", err)
+	}
+	return challenge, doc
+}
+
+func TestOtpAttemptFilterMatchesFreshChallenge(t *testing.T) {
+	challenge, doc := storedOtpChallenge(t, 0)
+	if _, ok := doc["attempts"]; ok {
+		t.Fatalf("expected a fresh challenge to be stored without attempts, got %v", doc["attempts"])
+	}
+	if !matches(doc, otpAttemptFilter(challenge.ID, 5)) {
+		t.Fatal("expected a fresh challenge to accept an attempt")
+	}
+	if !hmac.Equal([]byte(hashOtpCode(challenge.ID, "123456")), []byte(challenge.CodeHash)) {
+		t.Fatal("expected the code of a fresh challenge to verify")
+	}
+}
+
+func TestOtpAttemptFilterStopsAtMaxAttempts(t *testing.T) {
+	challenge, doc := storedOtpChallenge(t, 4)
+	if !matches(doc, otpAttemptFilter(challenge.ID, 5)) {
+		t.Fatal("expected a challenge under the limit to accept an attempt")
+	}
+	challenge, doc = storedOtpChallenge(t, 5)
+	if matches(doc, otpAttemptFilter(challenge.ID, 5)) {
+		t.Fatal("expected a challenge at the limit to refuse an attempt")
+	}
+}
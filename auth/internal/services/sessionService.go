@@ -0,0 +1,220 @@
+package services
+
+import (
+	"auth/internal/config"
+	"auth/internal/database"
+	"auth/internal/database/models"
+	"auth/internal/types/authServiceTypes"
+	httpErrors "auth/internal/utils/helpers/httpError"
+	"auth/internal/utils/helpers/jwtHelpers"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errInvalidRefreshToken = httpErrors.HydrateHttpError("purely/requests/sessions/errors/invalid-refresh-token", 401, "Invalid refresh token")
+
+func newRefreshToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionTokens mints an access token for a session alongside its refresh token.
+func sessionTokens(session models.Session, refreshToken string) (*authServiceTypes.SessionTokensType, error) {
+	jwtConfig := config.GetConfig().Jwt
+	now := time.Now()
+	accessToken, err := jwtHelpers.GetSigner().Sign(jwtHelpers.Claims{
+		Issuer:    jwtConfig.Issuer,
+		Subject:   session.AuthId,
+		ID:        session.AuthId,
+		SessionID: session.ID.Hex(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(jwtConfig.AccessTokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &authServiceTypes.SessionTokensType{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(jwtConfig.AccessTokenTTL.Seconds()),
+		SessionID:    session.ID.Hex(),
+	}, nil
+}
+
+// CreateSession signs in the Firebase user uid: it opens a session and
+// returns its first access and refresh tokens.
+func (authService *AuthService) CreateSession(ctx *context.Context, data authServiceTypes.CreateSessionType) (*authServiceTypes.SessionTokensType, error) {
+	var auth models.Auth
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Auth{Identifier: data.Uid}).Decode(&auth); err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/requests/sessions/errors/invalid-user", 400, "Could not find user")
+	}
+	return openSession(ctx, auth, data.UserAgent)
+}
+
+// openSession opens a session for auth and returns its first tokens.
+func openSession(ctx *context.Context, auth models.Auth, userAgent string) (*authServiceTypes.SessionTokensType, error) {
+	refreshToken, refreshTokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := models.Session{
+		ID:               primitive.NewObjectID(),
+		AuthId:           auth.ID.Hex(),
+		RefreshTokenHash: refreshTokenHash,
+		UserAgent:        userAgent,
+		ExpiresAt:        now.Add(config.GetConfig().Jwt.RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if _, err := models.Create(ctx, database.Mongo().Db(), session); err != nil {
+		return nil, err
+	}
+	return sessionTokens(session, refreshToken)
+}
+
+// RefreshSession trades a refresh token for a new access token and a new
+// refresh token, the presented one stops working. A refresh token presented
+// twice has leaked or raced, the whole session is revoked.
+func (authService *AuthService) RefreshSession(ctx *context.Context, data authServiceTypes.RefreshSessionType) (*authServiceTypes.SessionTokensType, error) {
+	if data.RefreshToken == "" {
+		return nil, errInvalidRefreshToken
+	}
+	refreshTokenHash := hashRefreshToken(data.RefreshToken)
+	var session models.Session
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Session{RefreshTokenHash: refreshTokenHash}).Decode(&session); err != nil {
+		var reusedSession models.Session
+		if models.FindOne(ctx, database.Mongo().Db(), models.Session{PreviousRefreshTokenHash: refreshTokenHash}).Decode(&reusedSession) == nil {
+			authService.revokeSession(ctx, reusedSession.ID, models.SessionRevokedReused)
+		}
+		return nil, errInvalidRefreshToken
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return nil, errInvalidRefreshToken
+	}
+
+	refreshToken, nextRefreshTokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	// Matching on the presented hash lets only one of two concurrent refreshes win
+	result, err := models.UpdateOne(ctx, database.Mongo().Db(), models.Session{}, bson.M{
+		"_id":              session.ID,
+		"refreshTokenHash": refreshTokenHash,
+	}, map[string]interface{}{
+		"refreshTokenHash":         nextRefreshTokenHash,
+		"previousRefreshTokenHash": refreshTokenHash,
+		"lastUsedAt":               now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		authService.revokeSession(ctx, session.ID, models.SessionRevokedReused)
+		return nil, errInvalidRefreshToken
+	}
+	return sessionTokens(session, refreshToken)
+}
+
+func (authService *AuthService) revokeSession(ctx *context.Context, sessionID primitive.ObjectID, reason string) {
+	if _, err := models.UpdateOne(ctx, database.Mongo().Db(), models.Session{}, bson.M{
+		"_id":       sessionID,
+		"revokedAt": bson.M{"$exists": false},
+	}, map[string]interface{}{
+		"revokedAt":     time.Now(),
+		"revokedReason": reason,
+	}); err != nil {
+		log.Default().Printf("Error revoking session %s: %v", sessionID.Hex(), err)
+	}
+}
+
+// RevokeSession signs out one session of a user, its refresh token stops
+// working right away and its access tokens once they expire.
+func (authService *AuthService) RevokeSession(ctx *context.Context, data authServiceTypes.RevokeSessionType) (interface{}, error) {
+	sessionID, err := primitive.ObjectIDFromHex(data.SessionID)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/requests/sessions/errors/invalid-session-id", 400, "Invalid session ID")
+	}
+	result, err := models.UpdateOne(ctx, database.Mongo().Db(), models.Session{}, bson.M{
+		"_id":       sessionID,
+		"authId":    data.AuthId,
+		"revokedAt": bson.M{"$exists": false},
+	}, map[string]interface{}{
+		"revokedAt":     time.Now(),
+		"revokedReason": models.SessionRevokedLogout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, httpErrors.HydrateHttpError("purely/requests/sessions/errors/not-found", 404, "Session not found")
+	}
+	return nil, nil
+}
+
+// RevokeSessions signs a user out everywhere.
+func (authService *AuthService) RevokeSessions(ctx *context.Context, authId string, reason string) (interface{}, error) {
+	result, err := models.UpdateMany(ctx, database.Mongo().Db(), models.Session{}, bson.M{
+		"authId":    authId,
+		"revokedAt": bson.M{"$exists": false},
+	}, map[string]interface{}{
+		"revokedAt":     time.Now(),
+		"revokedReason": reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"revoked": result.ModifiedCount}, nil
+}
+
+// ListSessions returns the sessions a user is still signed in with.
+func (authService *AuthService) ListSessions(ctx *context.Context, data authServiceTypes.RevokeSessionType) ([]authServiceTypes.SessionResType, error) {
+	cursor, err := models.Find(ctx, database.Mongo().Db(), models.Session{AuthId: data.AuthId}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(*ctx)
+	var sessions []models.Session
+	if err := cursor.All(*ctx, &sessions); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	res := []authServiceTypes.SessionResType{}
+	for _, session := range sessions {
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			continue
+		}
+		res = append(res, authServiceTypes.SessionResType{
+			ID:         session.ID.Hex(),
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID.Hex() == data.SessionID,
+		})
+	}
+	return res, nil
+}
+
+// JWKS publishes the keys access tokens are signed with.
+func (authService *AuthService) JWKS() jwtHelpers.JWKS {
+	return jwtHelpers.GetSigner().JWKS()
+}
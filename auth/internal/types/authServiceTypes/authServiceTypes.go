@@ -0,0 +1,73 @@
+package authServiceTypes
+
+import (
+	"auth/internal/database/models"
+	"time"
+)
+
+type CreateSessionType struct {
+	Uid       string
+	UserAgent string
+}
+
+type RefreshSessionType struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type SessionTokensType struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+	SessionID    string `json:"sessionID"`
+	NewUser      bool   `json:"newUser,omitempty"`
+}
+
+type RevokeSessionType struct {
+	AuthId    string
+	SessionID string
+}
+
+type SessionResType struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+type StartOtpType struct {
+	Phone string `json:"phone"`
+	IP    string `json:"-"`
+}
+
+type StartOtpResType struct {
+	Phone     string `json:"phone"`
+	ExpiresIn int    `json:"expiresIn"`
+	ResendIn  int    `json:"resendIn"`
+}
+
+type VerifyOtpType struct {
+	Phone     string `json:"phone"`
+	Code      string `json:"code"`
+	UserAgent string `json:"-"`
+}
+
+type DeletionSagaResType struct {
+	ID          string                         `json:"id"`
+	AuthId      string                         `json:"authId"`
+	Status      string                         `json:"status"`
+	Steps       map[string]models.DeletionStep `json:"steps"`
+	RequestedAt time.Time                      `json:"requestedAt"`
+	CompletedAt *time.Time                     `json:"completedAt,omitempty"`
+}
+
+type DataExportResType struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	ReadyAt     *time.Time `json:"readyAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadURL,omitempty"`
+}
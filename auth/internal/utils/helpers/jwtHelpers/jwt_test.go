@@ -0,0 +1,64 @@
+package jwtHelpers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewSigner(key)
+	now := time.Now()
+	token, err := signer.Sign(Claims{
+		Issuer:    "purely-auth",
+		Subject:   "auth-1",
+		ID:        "auth-1",
+		SessionID: "session-1",
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(10 * time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := signer.Verify(token, "purely-auth", now)
+	if err != nil {
+		t.Fatalf("expected a valid token, got %v", err)
+	}
+	if claims.ID != "auth-1" || claims.SessionID != "session-1" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err := signer.Verify(token, "someone-else", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected a foreign issuer to be rejected, got %v", err)
+	}
+	if _, err := signer.Verify(token, "purely-auth", now.Add(time.Hour)); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected an expired token, got %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := signer.Verify(tampered, "purely-auth", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected a tampered token to be rejected, got %v", err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSigner(otherKey).Verify(token, "purely-auth", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected a token of another key to be rejected, got %v", err)
+	}
+
+	jwks := signer.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != KeyID(&key.PublicKey) || jwks.Keys[0].E != "AQAB" {
+		t.Errorf("unexpected JWKS %+v", jwks)
+	}
+}
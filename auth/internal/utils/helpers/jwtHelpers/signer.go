@@ -0,0 +1,41 @@
+package jwtHelpers
+
+import (
+	"auth/internal/config"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"log"
+	"sync"
+)
+
+var (
+	signer     *Signer
+	signerOnce sync.Once
+)
+
+// GetSigner loads the signing key once. Outside of prod a missing key is
+// replaced by one generated for the lifetime of the process.
+func GetSigner() *Signer {
+	signerOnce.Do(func() {
+		path := config.GetConfig().Jwt.PrivateKeyPath
+		if path == "" {
+			if config.GetConfig().Env == "prod" {
+				panic("JWT_PRIVATE_KEY_PATH is required in prod")
+			}
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				panic(fmt.Errorf("error generating signing key: %v", err))
+			}
+			log.Default().Printf("JWT_PRIVATE_KEY_PATH not set, signing with a generated key")
+			signer = NewSigner(key)
+			return
+		}
+		loaded, err := LoadSigner(path)
+		if err != nil {
+			panic(fmt.Errorf("error loading signing key: %v", err))
+		}
+		signer = loaded
+	})
+	return signer
+}
@@ -0,0 +1,59 @@
+package rateLimitHelpers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Full buckets are dropped this often so idle callers don't pile up.
+const memorySweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryStore keeps buckets in the process. Every instance limits on its own,
+// deployments running several use the MongoStore.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if now.Sub(store.lastSweep) > memorySweepInterval {
+		store.sweep(now)
+	}
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), last: now}
+		store.buckets[key] = bucket
+	}
+	bucket.tokens = refill(bucket.tokens, bucket.last, limit, now)
+	bucket.last = now
+	bucket.limit = limit
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return resultOf(allowed, bucket.tokens, limit), nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	for key, bucket := range store.buckets {
+		if refill(bucket.tokens, bucket.last, bucket.limit, now) >= float64(bucket.limit.Burst) {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
@@ -0,0 +1,47 @@
+package rateLimitHelpers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRefills(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 2, Per: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Take(context.Background(), "token:ip:1", limit, now); !res.Allowed {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+	res, _ := store.Take(context.Background(), "token:ip:1", limit, now)
+	if res.Allowed {
+		t.Fatalf("expected the bucket to be empty")
+	}
+	if res.RetryAfter != 30*time.Second {
+		t.Errorf("expected to retry after 30s, got %v", res.RetryAfter)
+	}
+	if res, _ := store.Take(context.Background(), "token:ip:2", limit, now); !res.Allowed {
+		t.Errorf("expected another caller to have its own bucket")
+	}
+	if res, _ := store.Take(context.Background(), "token:ip:1", limit, now.Add(30*time.Second)); !res.Allowed {
+		t.Errorf("expected a token to be back after 30s")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("token=10/1m, locations=30/1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits["token"] != (Limit{Burst: 10, Per: time.Minute}) || limits["locations"] != (Limit{Burst: 30, Per: time.Hour}) {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	for _, value := range []string{"token", "token=10", "token=0/1m", "token=10/soon"} {
+		if _, err := ParseLimits(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
@@ -45,6 +45,9 @@ func main() {
 
 	server.RegisterFiberRoutes()
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := server.StartMediaJobWorkers(workersCtx)
+
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
@@ -62,5 +65,7 @@ func main() {
 
 	// Wait for the graceful shutdown to complete
 	<-done
+	stopWorkers()
+	workers.Wait()
 	log.Println("Graceful shutdown complete.")
 }
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -15,14 +18,56 @@ var (
 	env                       = os.Getenv("APP_ENV")
 	googleMapsAPIKey          = os.Getenv("GOOGLE_MAPS_API_KEY")
 	googleServiceJsonFilePath = os.Getenv("GOOGLE_SERVICE_JSON_FILE_PATH")
+	faceCascadePath           = os.Getenv("FACE_CASCADE_PATH")
+	mediaJobWorkers           = os.Getenv("MEDIA_JOB_WORKERS")
+	directUploadMaxSize       = os.Getenv("DIRECT_UPLOAD_MAX_SIZE")
+	moderationReviewThreshold = os.Getenv("MODERATION_REVIEW_THRESHOLD")
+	moderationRejectThreshold = os.Getenv("MODERATION_REJECT_THRESHOLD")
+	quotaMaxBytes             = os.Getenv("QUOTA_MAX_BYTES")
+	quotaMaxObjects           = os.Getenv("QUOTA_MAX_OBJECTS")
+	quotaMaxActiveSessions    = os.Getenv("QUOTA_MAX_ACTIVE_SESSIONS")
+	quotaUploadsPerHour       = os.Getenv("QUOTA_UPLOADS_PER_HOUR")
+	firebaseAuthFallback      = os.Getenv("FIREBASE_AUTH_FALLBACK")
+	exportLinkTTL             = os.Getenv("EXPORT_LINK_TTL")
 	aws                       = AwsConfig{
 		Region:             os.Getenv("AWS_REGION"),
 		AWSAccessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
 		AWSSecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
 	}
+	moderation = ModerationConfig{
+		Provider:    os.Getenv("MODERATION_PROVIDER"),
+		FixturePath: os.Getenv("MODERATION_FIXTURE_PATH"),
+		APIURL:      os.Getenv("MODERATION_API_URL"),
+		APIKey:      os.Getenv("MODERATION_API_KEY"),
+	}
+	auth = AuthConfig{
+		JwksURL:    os.Getenv("AUTH_JWKS_URL"),
+		Issuer:     os.Getenv("AUTH_JWT_ISSUER"),
+		ServiceURL: os.Getenv("AUTH_SERVICE_URL"),
+	}
+	export = ExportConfig{
+		LinkBaseURL: os.Getenv("EXPORT_LINK_BASE_URL"),
+	}
 	google = GoogleConfig{
 		ProjectID: os.Getenv("GOOGLE_PROJECT_ID"),
 	}
+	storage = StorageConfig{
+		Provider:       os.Getenv("STORAGE_PROVIDER"),
+		Bucket:         os.Getenv("STORAGE_BUCKET"),
+		PrivateBucket:  os.Getenv("STORAGE_PRIVATE_BUCKET"),
+		ProfilesBucket: os.Getenv("STORAGE_PROFILES_BUCKET"),
+		PublicBaseURL:  os.Getenv("STORAGE_PUBLIC_BASE_URL"),
+		LocalDir:       os.Getenv("STORAGE_LOCAL_DIR"),
+		SigningSecret:  os.Getenv("STORAGE_SIGNING_SECRET"),
+	}
+	rateLimit = RateLimitConfig{
+		Store:  os.Getenv("RATE_LIMIT_STORE"),
+		Limits: os.Getenv("RATE_LIMITS"),
+	}
+	proxy = ProxyConfig{
+		Header: os.Getenv("PROXY_HEADER"),
+	}
+	trustedProxies = os.Getenv("TRUSTED_PROXIES")
 )
 
 type AwsConfig struct {
@@ -31,10 +76,81 @@ type AwsConfig struct {
 	AWSSecretAccessKey string `json:"awsSecretAccessKey"`
 }
 
+// AuthConfig configures how user requests are authenticated. Access tokens
+// of the auth service are verified against the keys published at JwksURL,
+// Firebase ID tokens are only accepted when FirebaseFallback is turned on.
+// ServiceURL is where the auth service is reached for internal requests.
+type AuthConfig struct {
+	JwksURL          string
+	Issuer           string
+	ServiceURL       string
+	FirebaseFallback bool
+}
+
 type GoogleConfig struct {
 	ProjectID string
 }
 
+// StorageConfig selects the storage provider and where objects live.
+// Provider is one of "aws" (default), "gcp" or "local". For "local" objects
+// are written to LocalDir and served by this service under /storage, so
+// PublicBaseURL should point at that route. Bucket is public, originals go
+// to PrivateBucket and are only reachable through short lived signed URLs.
+type StorageConfig struct {
+	Provider       string
+	Bucket         string
+	PrivateBucket  string
+	ProfilesBucket string
+	PublicBaseURL  string
+	LocalDir       string
+	SigningSecret  string
+}
+
+// ModerationConfig selects the moderator every processed photo goes through.
+// Provider is one of "heuristic" (default), "fixture" or "api". Thresholds are
+// label scores from 0 to 1 above which a photo goes to review or is rejected.
+type ModerationConfig struct {
+	Provider        string
+	FixturePath     string
+	APIURL          string
+	APIKey          string
+	ReviewThreshold float64
+	RejectThreshold float64
+}
+
+// QuotaConfig holds the default storage quota of every user, admins can
+// override it per user.
+type QuotaConfig struct {
+	MaxBytes          int64
+	MaxObjects        int
+	MaxActiveSessions int
+	UploadsPerHour    int
+}
+
+// ExportConfig configures the links data exports are downloaded through.
+// LinkBaseURL is where this service is reachable by users, links stop working
+// LinkTTL after the export is ready and the archive is deleted then.
+type ExportConfig struct {
+	LinkBaseURL string
+	LinkTTL     time.Duration
+}
+
+// RateLimitConfig picks where rate limit buckets are kept: "memory" (the
+// default) per instance, "mongo" shared by all instances. Limits overrides
+// the limits of routes by rule name, like "uploadUrls=30/1m".
+type RateLimitConfig struct {
+	Store  string
+	Limits string
+}
+
+// ProxyConfig names the load balancers in front of the service. The client
+// address is read from Header only on requests coming from TrustedProxies
+// (addresses or CIDR ranges), other requests are keyed on their own address.
+type ProxyConfig struct {
+	Header         string
+	TrustedProxies []string
+}
+
 type configType struct {
 	Port                      string
 	MongoConnUrl              string
@@ -44,8 +160,18 @@ type configType struct {
 	Env                       string
 	GoogleMapsAPIKey          string
 	GoogleServiceJsonFilePath string
+	FaceCascadePath           string
+	MediaJobWorkers           int
+	DirectUploadMaxSize       int
 	AWS                       AwsConfig
 	Google                    GoogleConfig
+	Auth                      AuthConfig
+	Storage                   StorageConfig
+	Moderation                ModerationConfig
+	Quota                     QuotaConfig
+	Export                    ExportConfig
+	RateLimit                 RateLimitConfig
+	Proxy                     ProxyConfig
 }
 
 func GetConfig() configType {
@@ -58,8 +184,15 @@ func GetConfig() configType {
 		Env:                       env,
 		GoogleMapsAPIKey:          googleMapsAPIKey,
 		GoogleServiceJsonFilePath: googleServiceJsonFilePath,
+		FaceCascadePath:           faceCascadePath,
 		AWS:                       aws,
 		Google:                    google,
+		Auth:                      auth,
+		Storage:                   storage,
+		Moderation:                moderation,
+		Export:                    export,
+		RateLimit:                 rateLimit,
+		Proxy:                     proxy,
 	}
 	if port == "" {
 		obj.Port = "8080"
@@ -67,8 +200,92 @@ func GetConfig() configType {
 	if googleServiceJsonFilePath == "" {
 		obj.GoogleServiceJsonFilePath = "media/googleService.json"
 	}
+	obj.MediaJobWorkers, _ = strconv.Atoi(mediaJobWorkers)
+	if obj.MediaJobWorkers <= 0 {
+		obj.MediaJobWorkers = 4
+	}
+	obj.DirectUploadMaxSize, _ = strconv.Atoi(directUploadMaxSize)
+	if obj.DirectUploadMaxSize <= 0 {
+		obj.DirectUploadMaxSize = 5 * 1024 * 1024
+	}
+	obj.Quota.MaxBytes, _ = strconv.ParseInt(quotaMaxBytes, 10, 64)
+	if obj.Quota.MaxBytes <= 0 {
+		obj.Quota.MaxBytes = 500 * 1024 * 1024
+	}
+	obj.Quota.MaxObjects, _ = strconv.Atoi(quotaMaxObjects)
+	if obj.Quota.MaxObjects <= 0 {
+		obj.Quota.MaxObjects = 200
+	}
+	obj.Quota.MaxActiveSessions, _ = strconv.Atoi(quotaMaxActiveSessions)
+	if obj.Quota.MaxActiveSessions <= 0 {
+		obj.Quota.MaxActiveSessions = 5
+	}
+	obj.Quota.UploadsPerHour, _ = strconv.Atoi(quotaUploadsPerHour)
+	if obj.Quota.UploadsPerHour <= 0 {
+		obj.Quota.UploadsPerHour = 60
+	}
 	if aws.Region == "" {
 		obj.AWS.Region = "ap-south-1"
 	}
+	if storage.Provider == "" {
+		obj.Storage.Provider = "aws"
+	}
+	if storage.Bucket == "" {
+		obj.Storage.Bucket = "purely-public-assets"
+	}
+	if storage.PrivateBucket == "" {
+		obj.Storage.PrivateBucket = "purely-private-assets"
+	}
+	if storage.ProfilesBucket == "" {
+		obj.Storage.ProfilesBucket = "purely-profiles"
+	}
+	if storage.PublicBaseURL == "" {
+		obj.Storage.PublicBaseURL = "https://dl1b79m70nfwv.cloudfront.net"
+		if obj.Storage.Provider == "local" {
+			obj.Storage.PublicBaseURL = "http://localhost:" + obj.Port + "/storage"
+		}
+		if obj.Storage.Provider == "gcp" {
+			obj.Storage.PublicBaseURL = "https://storage.googleapis.com"
+		}
+	}
+	if moderation.Provider == "" {
+		obj.Moderation.Provider = "heuristic"
+	}
+	obj.Moderation.ReviewThreshold, _ = strconv.ParseFloat(moderationReviewThreshold, 64)
+	if obj.Moderation.ReviewThreshold <= 0 {
+		obj.Moderation.ReviewThreshold = 0.6
+	}
+	obj.Moderation.RejectThreshold, _ = strconv.ParseFloat(moderationRejectThreshold, 64)
+	if obj.Moderation.RejectThreshold <= 0 {
+		obj.Moderation.RejectThreshold = 0.9
+	}
+	if storage.LocalDir == "" {
+		obj.Storage.LocalDir = "tmp/storage"
+	}
+	if storage.SigningSecret == "" {
+		obj.Storage.SigningSecret = internalAccessToken
+	}
+	if auth.Issuer == "" {
+		obj.Auth.Issuer = "purely-auth"
+	}
+	obj.Auth.FirebaseFallback = firebaseAuthFallback == "true"
+	if rateLimit.Store == "" {
+		obj.RateLimit.Store = "memory"
+	}
+	if proxy.Header == "" {
+		obj.Proxy.Header = "X-Forwarded-For"
+	}
+	for _, trustedProxy := range strings.Split(trustedProxies, ",") {
+		if trustedProxy = strings.TrimSpace(trustedProxy); trustedProxy != "" {
+			obj.Proxy.TrustedProxies = append(obj.Proxy.TrustedProxies, trustedProxy)
+		}
+	}
+	if export.LinkBaseURL == "" {
+		obj.Export.LinkBaseURL = "http://localhost:" + obj.Port
+	}
+	obj.Export.LinkTTL, _ = time.ParseDuration(exportLinkTTL)
+	if obj.Export.LinkTTL <= 0 {
+		obj.Export.LinkTTL = 7 * 24 * time.Hour
+	}
 	return obj
 }
@@ -5,10 +5,14 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"media/internal/services"
+	"media/internal/types/mediaServiceTypes"
+	httpErrors "media/internal/utils/helpers/httpError"
 	"media/internal/utils/helpers/httpHelper"
 	PubSub "media/providers/pubSub"
 	"net/http"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -32,6 +36,10 @@ func (ic *InternalController) BlurImage(c *fiber.Ctx) error {
 			if err != nil {
 				return nil, err
 			}
+			// Media of purposes that are not blurred have no blurred image
+			if res == nil {
+				return nil, nil
+			}
 			return *res, nil
 		},
 		DataExtractor: func(c *fiber.Ctx) interface{} {
@@ -46,6 +54,28 @@ func (ic *InternalController) BlurImage(c *fiber.Ctx) error {
 	})
 }
 
+func (ic *InternalController) GenerateSignedDownloadUrls(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			signedUrlsData, ok := data.(mediaServiceTypes.GenerateSignedDownloadUrlsType)
+			if !ok || signedUrlsData.ViewerAuthId == "" {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return ic.MediaService.GenerateSignedDownloadUrls(ctx, signedUrlsData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data mediaServiceTypes.GenerateSignedDownloadUrlsType
+			if err := c.BodyParser(&data); err != nil {
+				return nil
+			}
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
 func (ic *InternalController) HandlePubSubMessage(c *fiber.Ctx) error {
 	return httpHelper.Controller(httpHelper.ControllerHelperType{
 		C: c,
@@ -56,8 +86,11 @@ func (ic *InternalController) HandlePubSubMessage(c *fiber.Ctx) error {
 				fmt.Println("HandlePubSubMessage Type parsing error")
 				return false, nil
 			}
-			res := ic.MediaService.HandlePubSubMessage(ctx, parsedData)
-			return res, nil
+			// A non-2xx response has Pub/Sub redeliver the message
+			if !ic.MediaService.HandlePubSubMessage(ctx, parsedData) {
+				return nil, httpErrors.HydrateHttpError("purely/requests/errors/message-not-handled", 503, "Message not handled, redeliver it")
+			}
+			return true, nil
 		},
 		DataExtractor: func(c *fiber.Ctx) interface{} {
 			var msg PubSubMessagePayload
@@ -80,3 +113,134 @@ func (ic *InternalController) HandlePubSubMessage(c *fiber.Ctx) error {
 		Code:    nil,
 	})
 }
+
+func (ic *InternalController) GetMediaStatus(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return ic.MediaService.GetMediaStatus(ctx, data.(mediaServiceTypes.GetMediaStatusType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return mediaServiceTypes.GetMediaStatusType{
+				MediaID: c.Params("mediaID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (ic *InternalController) GetModerationQueue(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return ic.MediaService.GetModerationQueue(ctx, data.(mediaServiceTypes.GetModerationQueueType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			page, err := strconv.ParseInt(c.Query("page", "0"), 10, 64)
+			if err != nil || page < 0 {
+				page = 0
+			}
+			return mediaServiceTypes.GetModerationQueueType{
+				Status: c.Query("status"),
+				Page:   page,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (ic *InternalController) OverrideModerationVerdict(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			verdictData, ok := data.(mediaServiceTypes.OverrideModerationVerdictType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return ic.MediaService.OverrideModerationVerdict(ctx, verdictData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data mediaServiceTypes.OverrideModerationVerdictType
+			if err := c.BodyParser(&data); err != nil {
+				return nil
+			}
+			data.MediaID = c.Params("mediaID")
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (ic *InternalController) GetStorageQuota(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return ic.MediaService.GetStorageQuota(ctx, data.(string))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			return c.Params("authId")
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (ic *InternalController) UpdateStorageQuota(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			quotaData, ok := data.(mediaServiceTypes.UpdateStorageQuotaType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return ic.MediaService.UpdateStorageQuota(ctx, quotaData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data mediaServiceTypes.UpdateStorageQuotaType
+			if err := c.BodyParser(&data.QuotaLimitsType); err != nil {
+				return nil
+			}
+			data.AuthId = c.Params("authId")
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (ic *InternalController) DecodeWatermark(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			decodeData, ok := data.(mediaServiceTypes.DecodeWatermarkType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "An image file is required")
+			}
+			return ic.MediaService.DecodeWatermark(ctx, decodeData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			fileHeader, err := c.FormFile("image")
+			if err != nil {
+				return nil
+			}
+			file, err := fileHeader.Open()
+			if err != nil {
+				return nil
+			}
+			defer file.Close()
+			image, err := io.ReadAll(file)
+			if err != nil {
+				return nil
+			}
+			return mediaServiceTypes.DecodeWatermarkType{
+				Image:   image,
+				MediaID: c.FormValue("mediaID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
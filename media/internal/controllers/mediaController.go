@@ -60,7 +60,7 @@ func (mediaController *MediaController) GenerateMultipartUploadUrls(c *fiber.Ctx
 			if !ok {
 				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
 			}
-			return mediaController.MediaService.GenerateMultipartUploadUrls(getSignedUrlData)
+			return mediaController.MediaService.GenerateMultipartUploadUrls(ctx, getSignedUrlData)
 		},
 		DataExtractor: func(c *fiber.Ctx) interface{} {
 			var mediaUploadData mediaControllerTypes.GenerateMultipartMediaUploadSignedUrls
@@ -98,10 +98,11 @@ func (mediaController *MediaController) CompleteMultipartUpload(c *fiber.Ctx) er
 			if err := c.BodyParser(&mediaUploadData); err != nil {
 				return nil
 			}
+			auth := c.Locals("auth").(appTypes.Auth)
 			mediaUploadParams := mediaServiceTypes.CompleteMultipartUploadType{
-				UploadID: mediaUploadData.UploadID,
-				URL:      mediaUploadData.URL,
-				Parts:    mediaUploadData.Parts,
+				AuthId:    auth.Id,
+				SessionID: mediaUploadData.SessionID,
+				Parts:     mediaUploadData.Parts,
 			}
 			return mediaUploadParams
 		},
@@ -109,3 +110,107 @@ func (mediaController *MediaController) CompleteMultipartUpload(c *fiber.Ctx) er
 		Code:    nil,
 	})
 }
+
+func (mediaController *MediaController) DirectUpload(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			uploadData, ok := data.(mediaServiceTypes.DirectUploadType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "A file is required")
+			}
+			return mediaController.MediaService.DirectUpload(ctx, uploadData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			fileHeader, err := c.FormFile("file")
+			if err != nil {
+				return nil
+			}
+			auth := c.Locals("auth").(appTypes.Auth)
+			return mediaServiceTypes.DirectUploadType{
+				AuthId:  auth.Id,
+				Purpose: c.FormValue("purpose"),
+				File:    fileHeader,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (mediaController *MediaController) DeleteMedia(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			deleteMediaData, ok := data.(mediaServiceTypes.DeleteMediaType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return mediaController.MediaService.DeleteMedia(ctx, deleteMediaData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			auth := c.Locals("auth").(appTypes.Auth)
+			return mediaServiceTypes.DeleteMediaType{
+				AuthId:  auth.Id,
+				MediaID: c.Params("mediaID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (mediaController *MediaController) ReplaceMedia(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			replaceMediaData, ok := data.(mediaServiceTypes.ReplaceMediaType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return mediaController.MediaService.ReplaceMedia(ctx, replaceMediaData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var replaceMediaData mediaControllerTypes.ReplaceMediaType
+			if err := c.BodyParser(&replaceMediaData); err != nil || replaceMediaData.MediaID == nil {
+				return nil
+			}
+			auth := c.Locals("auth").(appTypes.Auth)
+			return mediaServiceTypes.ReplaceMediaType{
+				AuthId:     auth.Id,
+				MediaID:    c.Params("mediaID"),
+				NewMediaID: *replaceMediaData.MediaID,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (mediaController *MediaController) GetMediaStatus(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return mediaController.MediaService.GetMediaStatus(ctx, data.(mediaServiceTypes.GetMediaStatusType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			auth := c.Locals("auth").(appTypes.Auth)
+			return mediaServiceTypes.GetMediaStatusType{
+				AuthId:  auth.Id,
+				MediaID: c.Params("mediaID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+// DownloadAccountExport redirects a signed export link to the archive. The
+// link itself is the credential, users follow it from a text or the app.
+func (mediaController *MediaController) DownloadAccountExport(c *fiber.Ctx) error {
+	downloadURL, err := mediaController.MediaService.AccountExportDownloadURL(c.Context(), c.Params("exportID"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		return httpHelper.SendErrorResponse(c, err)
+	}
+	return c.Redirect(downloadURL, fiber.StatusFound)
+}
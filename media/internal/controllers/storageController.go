@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"media/internal/utils/helpers/httpHelper"
+	"media/providers/storage"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StorageController serves the signed URLs issued by the local storage
+// provider so uploads and downloads work without a cloud bucket.
+type StorageController struct {
+	StorageProvider *storage.LocalStorageProvider
+}
+
+func storageKey(c *fiber.Ctx) (string, error) {
+	return url.PathUnescape(c.Params("*"))
+}
+
+func (sc *StorageController) UploadPart(c *fiber.Ctx) error {
+	bucket := c.Params("bucket")
+	key, err := storageKey(c)
+	if err != nil {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/invalid-key", 400, "Invalid object key"))
+	}
+	uploadID := c.Query("uploadId")
+	partNumberStr := c.Query("partNumber")
+	partNumber, err := strconv.Atoi(partNumberStr)
+	if err != nil || partNumber < 1 {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/invalid-part", 400, "Invalid part number"))
+	}
+	if err := sc.StorageProvider.VerifySignature("PUT", bucket, key, uploadID, partNumberStr, c.Query("expires"), c.Query("signature")); err != nil {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/invalid-signature", 403, "Invalid or expired signature"))
+	}
+	etag, err := sc.StorageProvider.PutPart(bucket, key, uploadID, partNumber, bytes.NewReader(c.Body()), c.Get("Content-MD5"))
+	if err != nil {
+		if errors.Is(err, storage.ErrUploadNotFound) {
+			return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/upload-not-found", 404, "Upload not found"))
+		}
+		if errors.Is(err, storage.ErrBadDigest) {
+			return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/bad-digest", 400, "Part does not match its Content-MD5"))
+		}
+		return httpHelper.SendErrorResponse(c, err)
+	}
+	c.Set("ETag", fmt.Sprintf("\"%s\"", etag))
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (sc *StorageController) GetObject(c *fiber.Ctx) error {
+	bucket := c.Params("bucket")
+	key, err := storageKey(c)
+	if err != nil {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/invalid-key", 400, "Invalid object key"))
+	}
+	if c.Query("signature") != "" || !sc.StorageProvider.IsPublicBucket(bucket) {
+		if err := sc.StorageProvider.VerifySignature("GET", bucket, key, "", "", c.Query("expires"), c.Query("signature")); err != nil {
+			return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/invalid-signature", 403, "Invalid or expired signature"))
+		}
+	}
+	file, err := sc.StorageProvider.Open(bucket, key)
+	if err != nil {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/media/storage/errors/not-found", 404, "Not found"))
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return httpHelper.SendErrorResponse(c, err)
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(key)); contentType != "" {
+		c.Set("Content-Type", contentType)
+	}
+	// fasthttp closes the file once the body has been streamed
+	return c.SendStream(file, int(stat.Size()))
+}
@@ -50,6 +50,7 @@ func createIndexes(client *mongo.Client, model interface{}, collectionName strin
 		field := t.Field(i)
 		bsonTag := field.Tag.Get("bson")
 		uniqueTag := field.Tag.Get("unique")
+		indexTag := field.Tag.Get("index")
 
 		// If the field has a BSON tag and is unique
 		if bsonTag != "" && uniqueTag == "true" {
@@ -61,6 +62,19 @@ func createIndexes(client *mongo.Client, model interface{}, collectionName strin
 			}
 			indexModels = append(indexModels, indexModel)
 		}
+
+		// Plain (non unique) ascending index for fields used in lookups
+		if bsonTag != "" && indexTag == "true" && uniqueTag != "true" {
+			parts := strings.Split(bsonTag, ",")
+			tagName := parts[0]
+			indexModels = append(indexModels, mongo.IndexModel{
+				Keys: bson.D{{Key: tagName, Value: 1}},
+			})
+		}
+	}
+
+	if err := dropStaleUniqueIndexes(ctx, collection, indexModels); err != nil {
+		return err
 	}
 
 	// Create the indexes in MongoDB
@@ -73,6 +87,38 @@ func createIndexes(client *mongo.Client, model interface{}, collectionName strin
 	return nil
 }
 
+// dropStaleUniqueIndexes drops unique indexes on fields the model indexes
+// without uniqueness now, creating them again would conflict with the old ones.
+func dropStaleUniqueIndexes(ctx context.Context, collection *mongo.Collection, indexModels []mongo.IndexModel) error {
+	plainIndexes := map[string]bool{}
+	for _, indexModel := range indexModels {
+		if indexModel.Options == nil || indexModel.Options.Unique == nil {
+			plainIndexes[indexModel.Keys.(bson.D)[0].Key] = true
+		}
+	}
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %v", err)
+	}
+	defer cursor.Close(ctx)
+	var existing []struct {
+		Name   string `bson:"name"`
+		Key    bson.D `bson:"key"`
+		Unique bool   `bson:"unique"`
+	}
+	if err := cursor.All(ctx, &existing); err != nil {
+		return fmt.Errorf("failed to list indexes: %v", err)
+	}
+	for _, index := range existing {
+		if index.Unique && len(index.Key) == 1 && plainIndexes[index.Key[0].Key] {
+			if _, err := collection.Indexes().DropOne(ctx, index.Name); err != nil {
+				return fmt.Errorf("failed to drop unique index %s: %v", index.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *service) init() {
 	models := models.GetModels()
 	for _, modelProvider := range models {
@@ -98,6 +144,16 @@ func Mongo() Service {
 	return instance
 }
 
+// Use makes Mongo return db instead of connecting, for tests running against
+// a mock deployment.
+func Use(db *mongo.Database) {
+	once.Do(func() {})
+	instance = &service{
+		client: db.Client(),
+		db:     db,
+	}
+}
+
 func (s *service) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AccountExportStatusPending = "pending"
+	AccountExportStatusReady   = "ready"
+)
+
+// AccountExport is a data export being packaged, its ID is the one auth gave
+// the export. Payload is the JSON of the sections other services added, the
+// archive is stored privately under Bucket, Path and FileName once ready and
+// deleted at ExpiresAt.
+type AccountExport struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	AuthId    string     `bson:"authId,omitempty" json:"authId" index:"true"`
+	Status    string     `bson:"status,omitempty" json:"status"`
+	Payload   string     `bson:"payload,omitempty" json:"-"`
+	Bucket    string     `bson:"bucket,omitempty" json:"-"`
+	Path      string     `bson:"path,omitempty" json:"-"`
+	FileName  string     `bson:"fileName,omitempty" json:"-"`
+	Size      int        `bson:"size,omitempty" json:"size,omitempty"`
+	ReadyAt   *time.Time `bson:"readyAt,omitempty" json:"readyAt,omitempty"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty" index:"true"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
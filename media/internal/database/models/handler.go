@@ -26,6 +26,41 @@ var (
 			CollectionName: "media",
 			Timestamps:     true,
 		},
+		reflect.TypeOf(MediaJob{}): {
+			Model:          MediaJob{},
+			CollectionName: "mediaJobs",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(MediaFlag{}): {
+			Model:          MediaFlag{},
+			CollectionName: "mediaFlags",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(StoredObject{}): {
+			Model:          StoredObject{},
+			CollectionName: "storedObjects",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(UploadSession{}): {
+			Model:          UploadSession{},
+			CollectionName: "uploadSessions",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(StorageQuota{}): {
+			Model:          StorageQuota{},
+			CollectionName: "storageQuotas",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(QuotaReservation{}): {
+			Model:          QuotaReservation{},
+			CollectionName: "quotaReservations",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(AccountExport{}): {
+			Model:          AccountExport{},
+			CollectionName: "accountExports",
+			Timestamps:     true,
+		},
 	}
 )
 
@@ -157,6 +192,78 @@ func UpdateMany(ctx context.Context, db *mongo.Database, model interface{}, filt
 	return result, nil
 }
 
+// DeleteById deletes a document by its ID.
+func DeleteById(ctx context.Context, db *mongo.Database, model interface{}, id primitive.ObjectID) (*mongo.DeleteResult, error) {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteOne deletes the first document that matches the filter.
+func DeleteOne(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M) (*mongo.DeleteResult, error) {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteMany deletes every document that matches the filter.
+func DeleteMany(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M) (*mongo.DeleteResult, error) {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindOneAndUpdate atomically updates the first document that matches the
+// filter, setting `updatedAt` if timestamps are enabled, and returns it as
+// it is after the update.
+func FindOneAndUpdate(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M, updateData map[string]interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	for k, v := range beforeUpdate(model) {
+		updateData[k] = v
+	}
+	opts = append([]*options.FindOneAndUpdateOptions{options.FindOneAndUpdate().SetReturnDocument(options.After)}, opts...)
+	return collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": updateData}, opts...)
+}
+
+// FindOneAndIncrement atomically adds the given amounts to fields of the
+// first document that matches the filter and returns it after the update.
+func FindOneAndIncrement(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M, incData map[string]interface{}) *mongo.SingleResult {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	update := bson.M{"$inc": incData}
+	if setData := beforeUpdate(model); len(setData) > 0 {
+		update["$set"] = setData
+	}
+	return collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+}
+
+// FindOneAndApply atomically applies update, which may use any operator, to
+// the first document that matches the filter, setting `updatedAt` if
+// timestamps are enabled, and returns it as it is after the update.
+func FindOneAndApply(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M, update bson.M, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	if setData := beforeUpdate(model); len(setData) > 0 {
+		set, _ := update["$set"].(bson.M)
+		if set == nil {
+			set = bson.M{}
+		}
+		for k, v := range setData {
+			set[k] = v
+		}
+		update["$set"] = set
+	}
+	opts = append([]*options.FindOneAndUpdateOptions{options.FindOneAndUpdate().SetReturnDocument(options.After)}, opts...)
+	return collection.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
 func FindOne(ctx context.Context, db *mongo.Database, model interface{}) *mongo.SingleResult {
 	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
 	var filter map[string]interface{}
@@ -1,18 +1,131 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	MediaVisibilityPublic  = "public"
+	MediaVisibilityPrivate = "private"
+)
+
+const (
+	MediaVariantBlurred     = "blurred"
+	MediaVariantThumbnail   = "thumbnail"
+	MediaVariantWatermarked = "watermarked"
+)
+
+// Moderation states, media in review or rejected are quarantined.
+const (
+	MediaModerationApproved = "approved"
+	MediaModerationReview   = "review"
+	MediaModerationRejected = "rejected"
+)
+
+// Where the focal point of an original came from, crops are centered on it.
+const (
+	MediaFocalSourceFaces    = "faces"
+	MediaFocalSourceSaliency = "saliency"
+	MediaFocalSourceUser     = "user"
+)
+
+// FocalPoint is relative to the image, 0,0 is the top left corner and 1,1
+// the bottom right one.
+type FocalPoint struct {
+	X float64 `bson:"x" json:"x"`
+	Y float64 `bson:"y" json:"y"`
+}
+
+type FaceBox struct {
+	X      int     `bson:"x" json:"x"`
+	Y      int     `bson:"y" json:"y"`
+	Width  int     `bson:"width" json:"width"`
+	Height int     `bson:"height" json:"height"`
+	Score  float64 `bson:"score" json:"score"`
+}
+
+// MediaQuality holds the quality metrics of an original and the warnings the
+// thresholds of its purpose raised.
+type MediaQuality struct {
+	Sharpness   float64  `bson:"sharpness" json:"sharpness"`
+	Brightness  float64  `bson:"brightness" json:"brightness"`
+	Clipped     float64  `bson:"clipped" json:"clipped"`
+	Width       int      `bson:"width" json:"width"`
+	Height      int      `bson:"height" json:"height"`
+	AspectRatio float64  `bson:"aspectRatio" json:"aspectRatio"`
+	Warnings    []string `bson:"warnings,omitempty" json:"warnings,omitempty"`
+}
+
 type Media struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 
-	URL         string             `bson:"url,omitempty" json:"url" unique:"true"`
+	URL         string             `bson:"url,omitempty" json:"url" index:"true"`
 	EXT         string             `bson:"ext,omitempty" json:"ext"`
 	RefID       primitive.ObjectID `bson:"refID,omitempty" json:"refID" unique:"true"`
+	AuthId      string             `bson:"authId,omitempty" json:"authId" index:"true"`
+	Bucket      string             `bson:"bucket,omitempty" json:"bucket"`
+	Visibility  string             `bson:"visibility,omitempty" json:"visibility"`
 	Domain      string             `bson:"domain,omitempty" json:"domain"`
-	Path        string             `bson:"path,omitempty" json:"path"`
+	Path        string             `bson:"path,omitempty" json:"path" index:"true"`
 	ContentType string             `bson:"contentType,omitempty" json:"contentType"`
 	FileName    string             `bson:"fileName,omitempty" json:"fileName"`
 	Size        int                `bson:"size,omitempty" json:"size"`
+	Purpose     string             `bson:"purpose,omitempty" json:"purpose,omitempty"`
+	// SHA-256 of the stored bytes, hex encoded. Media with the same bytes
+	// share one StoredObject.
+	SHA256 string `bson:"sha256,omitempty" json:"sha256,omitempty" index:"true"`
+
+	// Set from the retention of the purpose, the media is purged afterwards.
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty" index:"true"`
+
+	// Variants (blurred, resized) point at the original they were derived
+	// from so they can be removed along with it.
+	SourceID primitive.ObjectID `bson:"sourceID,omitempty" json:"sourceID,omitempty" index:"true"`
+	Variant  string             `bson:"variant,omitempty" json:"variant,omitempty"`
+
+	// Watermarked renditions are made for one viewer, whose profile ID they
+	// carry, at the recorded size.
+	ViewerProfileID primitive.ObjectID `bson:"viewerProfileID,omitempty" json:"viewerProfileID,omitempty" index:"true"`
+	Width           int                `bson:"width,omitempty" json:"width,omitempty"`
+	Height          int                `bson:"height,omitempty" json:"height,omitempty"`
+
+	// Perceptual hash (dHash) of the original image, hex encoded.
+	// PHashBands splits the hash into 8 bit bands so near duplicates can be
+	// found through an index before comparing hamming distances.
+	PHash      string   `bson:"pHash,omitempty" json:"pHash,omitempty"`
+	PHashBands []string `bson:"pHashBands,omitempty" json:"-" index:"true"`
+
+	// Placeholders clients render while the image loads: a BlurHash
+	// (https://blurha.sh) and the dominant color as "#rrggbb".
+	BlurHash      string `bson:"blurHash,omitempty" json:"blurHash,omitempty"`
+	DominantColor string `bson:"dominantColor,omitempty" json:"dominantColor,omitempty"`
+
+	// Faces found in the original, FaceCount is nil until detection ran.
+	// RejectedReason is set when the photo breaks the rules of its purpose.
+	FaceBoxes      []FaceBox `bson:"faceBoxes,omitempty" json:"faceBoxes,omitempty"`
+	FaceCount      *int      `bson:"faceCount,omitempty" json:"faceCount,omitempty"`
+	RejectedReason string    `bson:"rejectedReason,omitempty" json:"rejectedReason,omitempty"`
+
+	// Length of audio in milliseconds and its waveform preview, bars from 0
+	// to 100.
+	DurationMs int   `bson:"durationMs,omitempty" json:"durationMs,omitempty"`
+	Waveform   []int `bson:"waveform,omitempty" json:"waveform,omitempty"`
+
+	// Point cropped variants are centered on. Originals record where it came
+	// from, cropped variants the point they were cut around.
+	FocalPoint  *FocalPoint `bson:"focalPoint,omitempty" json:"focalPoint,omitempty"`
+	FocalSource string      `bson:"focalSource,omitempty" json:"focalSource,omitempty"`
+
+	// Quality metrics of the original, nil until they were measured.
+	Quality *MediaQuality `bson:"quality,omitempty" json:"quality,omitempty"`
+
+	// Verdict of the moderator, empty until the original was screened.
+	// ModeratedBy and ModeratedAt are set when a moderator overrode it.
+	ModerationStatus   string             `bson:"moderationStatus,omitempty" json:"moderationStatus,omitempty" index:"true"`
+	ModerationLabels   map[string]float64 `bson:"moderationLabels,omitempty" json:"moderationLabels,omitempty"`
+	ModerationProvider string             `bson:"moderationProvider,omitempty" json:"moderationProvider,omitempty"`
+	ModeratedBy        string             `bson:"moderatedBy,omitempty" json:"moderatedBy,omitempty"`
+	ModeratedAt        *time.Time         `bson:"moderatedAt,omitempty" json:"moderatedAt,omitempty"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MediaFlagReasonDuplicatePhoto = "duplicatePhoto"
+
+	MediaFlagStatusOpen = "open"
+)
+
+// MediaFlag is a trust and safety flag raised against an uploaded media.
+type MediaFlag struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	MediaID        primitive.ObjectID `bson:"mediaID,omitempty" json:"mediaID" index:"true"`
+	MatchedMediaID primitive.ObjectID `bson:"matchedMediaID,omitempty" json:"matchedMediaID"`
+	AuthId         string             `bson:"authId,omitempty" json:"authId" index:"true"`
+	MatchedAuthId  string             `bson:"matchedAuthId,omitempty" json:"matchedAuthId" index:"true"`
+	Reason         string             `bson:"reason,omitempty" json:"reason"`
+	Distance       int                `bson:"distance" json:"distance"`
+	Status         string             `bson:"status,omitempty" json:"status" index:"true"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
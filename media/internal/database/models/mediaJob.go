@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MediaJobTypeBlurImage    = "blurImage"
+	MediaJobTypeCropVariants = "cropVariants"
+	// Packages a data export, MediaID holds the ID of the AccountExport
+	MediaJobTypeExportAccount = "exportAccount"
+)
+
+const (
+	MediaJobStatePending    = "pending"
+	MediaJobStateProcessing = "processing"
+	MediaJobStateReady      = "ready"
+	MediaJobStateFailed     = "failed"
+)
+
+// MediaJob is a unit of background media processing. Jobs are claimed by the
+// worker pool, LockedUntil lets another worker pick a job up again when the
+// one processing it died.
+type MediaJob struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	MediaID     primitive.ObjectID `bson:"mediaID,omitempty" json:"mediaID" index:"true"`
+	ProfileID   string             `bson:"profileID,omitempty" json:"profileID,omitempty"`
+	Type        string             `bson:"type,omitempty" json:"type"`
+	State       string             `bson:"state,omitempty" json:"state" index:"true"`
+	Attempts    int                `bson:"attempts,omitempty" json:"attempts"`
+	LastError   string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	RunAfter    time.Time          `bson:"runAfter,omitempty" json:"runAfter"`
+	LockedUntil time.Time          `bson:"lockedUntil,omitempty" json:"-"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuotaReservation holds the uploads of one user that passed the quota check
+// but aren't recorded as media or upload sessions yet. Every change bumps
+// Version, reservations are only added if it didn't move since usage was read.
+type QuotaReservation struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	AuthId       string                  `bson:"authId,omitempty" json:"authId" unique:"true"`
+	Version      int                     `bson:"version,omitempty" json:"version"`
+	Reservations []QuotaReservationEntry `bson:"reservations,omitempty" json:"reservations"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// QuotaReservationEntry is one upload being started. Entries left behind by a
+// crash stop counting at ExpiresAt.
+type QuotaReservationEntry struct {
+	ID        primitive.ObjectID `bson:"id" json:"id"`
+	Bytes     int64              `bson:"bytes" json:"bytes"`
+	Session   bool               `bson:"session" json:"session"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StorageQuota overrides the default quota of one user. Limits left at 0 use
+// the default from the config.
+type StorageQuota struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	AuthId            string `bson:"authId,omitempty" json:"authId" unique:"true"`
+	MaxBytes          int64  `bson:"maxBytes" json:"maxBytes"`
+	MaxObjects        int    `bson:"maxObjects" json:"maxObjects"`
+	MaxActiveSessions int    `bson:"maxActiveSessions" json:"maxActiveSessions"`
+	UploadsPerHour    int    `bson:"uploadsPerHour" json:"uploadsPerHour"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StoredObject is an object in storage shared by every media with the same
+// bytes. RefCount is the number of media pointing at it, the object is
+// deleted from storage when the last one lets go. Address is the bucket and
+// key, so an object is never recorded twice.
+type StoredObject struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	Address     string `bson:"address,omitempty" json:"address" unique:"true"`
+	Bucket      string `bson:"bucket,omitempty" json:"bucket"`
+	Path        string `bson:"path,omitempty" json:"path"`
+	FileName    string `bson:"fileName,omitempty" json:"fileName"`
+	ContentType string `bson:"contentType,omitempty" json:"contentType"`
+	SHA256      string `bson:"sha256,omitempty" json:"sha256" index:"true"`
+	Size        int    `bson:"size,omitempty" json:"size"`
+	URL         string `bson:"url,omitempty" json:"url"`
+	Domain      string `bson:"domain,omitempty" json:"domain"`
+	RefCount    int    `bson:"refCount" json:"refCount"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
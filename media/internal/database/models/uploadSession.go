@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UploadSession records a multipart upload handed out to a client. Completing
+// the upload only trusts what was recorded here, never the URL the client
+// sends back. Direct uploads are recorded as completed sessions, so every
+// upload counts towards the upload rate of its user. CompletingAt is set while
+// a request completes the upload, so only one of them does.
+type UploadSession struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	AuthId       string             `bson:"authId,omitempty" json:"authId" index:"true"`
+	UploadID     string             `bson:"uploadID,omitempty" json:"uploadID"`
+	Bucket       string             `bson:"bucket,omitempty" json:"bucket"`
+	FilePath     string             `bson:"filePath,omitempty" json:"filePath"`
+	FileName     string             `bson:"fileName,omitempty" json:"fileName"`
+	Key          string             `bson:"key,omitempty" json:"key"`
+	ContentType  string             `bson:"contentType,omitempty" json:"contentType"`
+	FileSize     int                `bson:"fileSize,omitempty" json:"fileSize"`
+	Purpose      string             `bson:"purpose,omitempty" json:"purpose"`
+	ExpiresAt    time.Time          `bson:"expiresAt,omitempty" json:"expiresAt"`
+	CompletingAt *time.Time         `bson:"completingAt,omitempty" json:"-"`
+	CompletedAt  *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	MediaID      primitive.ObjectID `bson:"mediaID,omitempty" json:"mediaID,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
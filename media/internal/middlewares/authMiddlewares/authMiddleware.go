@@ -8,6 +8,7 @@ import (
 	firebaseHelper "media/internal/utils/helpers/firebaseHelpers"
 	httpErrors "media/internal/utils/helpers/httpError"
 	"media/internal/utils/helpers/httpHelper"
+	"media/internal/utils/helpers/jwtHelpers"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,6 +17,14 @@ import (
 func VerifyInternalAccess(c *fiber.Ctx) error {
 	// Retrieve the 'Access-Token' header
 	accessToken := c.Get("Access-Token")
+	// Pub/Sub push subscriptions can only pass it in the URL
+	if len(accessToken) == 0 {
+		accessToken = c.Query("accessToken")
+	}
+
+	if len(accessToken) == 0 {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+	}
 
 	// Check if the token matches the expected internal access token
 	if config.GetConfig().InternalAccessToken != accessToken {
@@ -33,6 +42,21 @@ func VerifyUserAccess(c *fiber.Ctx) error {
 	if len(bearerToken) < 2 {
 		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
 	}
+	// Access tokens of the auth service are verified locally
+	authConfig := config.GetConfig().Auth
+	if verifier := jwtHelpers.GetVerifier(); verifier != nil && jwtHelpers.IssuerOf(bearerToken[1]) == authConfig.Issuer {
+		claims, err := verifier.Verify(bearerToken[1])
+		if err != nil {
+			return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+		}
+		c.Locals("auth", appTypes.Auth{
+			Id: claims.ID,
+		})
+		return c.Next()
+	}
+	if !authConfig.FirebaseFallback {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+	}
 	firebaseAuth, err := firebaseHelper.App().Auth(context.Background())
 	if err != nil {
 		log.Default().Println(err)
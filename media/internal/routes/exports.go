@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"media/internal/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportRoutes serve the signed links of data exports, they carry their own
+// signature instead of a user token.
+type ExportRoutes struct {
+	MediaController controllers.MediaController
+}
+
+func (er *ExportRoutes) InitRoutes(router fiber.Router) {
+	router.Get("/:exportID/download", er.MediaController.DownloadAccountExport)
+}
@@ -2,6 +2,10 @@ package routes
 
 import (
 	"media/internal/controllers"
+	"media/internal/middlewares/authMiddlewares"
+	"media/internal/middlewares/rateLimitMiddlewares"
+	"media/internal/utils/helpers/rateLimitHelpers"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,5 +16,16 @@ type InternalRoutes struct {
 
 func (ir *InternalRoutes) InitRoutes(router fiber.Router) {
 	router.Post("/images/blur", ir.InternalController.BlurImage)
-	router.Post("/pubsub/messages", ir.InternalController.HandlePubSubMessage)
+	router.Post("/pubsub/messages", authMiddlewares.VerifyInternalAccess, ir.InternalController.HandlePubSubMessage)
+	// Profiles asks on behalf of the user viewing profiles
+	router.Post("/media/signed-urls", authMiddlewares.VerifyInternalAccess,
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "downloadUrls", KeyBy: rateLimitMiddlewares.KeyByViewer, Limit: rateLimitHelpers.Limit{Burst: 120, Per: time.Minute}}),
+		ir.InternalController.GenerateSignedDownloadUrls,
+	)
+	router.Get("/media/:mediaID/status", authMiddlewares.VerifyInternalAccess, ir.InternalController.GetMediaStatus)
+	router.Post("/media/watermark/decode", authMiddlewares.VerifyInternalAccess, ir.InternalController.DecodeWatermark)
+	router.Get("/moderation/queue", authMiddlewares.VerifyInternalAccess, ir.InternalController.GetModerationQueue)
+	router.Put("/moderation/:mediaID/verdict", authMiddlewares.VerifyInternalAccess, ir.InternalController.OverrideModerationVerdict)
+	router.Get("/quotas/:authId", authMiddlewares.VerifyInternalAccess, ir.InternalController.GetStorageQuota)
+	router.Put("/quotas/:authId", authMiddlewares.VerifyInternalAccess, ir.InternalController.UpdateStorageQuota)
 }
@@ -3,6 +3,9 @@ package routes
 import (
 	"media/internal/controllers"
 	"media/internal/middlewares/authMiddlewares"
+	"media/internal/middlewares/rateLimitMiddlewares"
+	"media/internal/utils/helpers/rateLimitHelpers"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,5 +18,15 @@ func (r *Router) InitRoutes(router fiber.Router) {
 	mediaRouteGroup := router.Group("/")
 	mediaRouteGroup.Use(authMiddlewares.VerifyUserAccess)
 	mediaRouteGroup.Post("/media/multipart/complete", r.MediaController.CompleteMultipartUpload)
-	mediaRouteGroup.Post("/media/multipart/signed-urls", r.MediaController.GenerateMultipartUploadUrls)
+	mediaRouteGroup.Post("/media/multipart/signed-urls",
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "uploadUrls", KeyBy: rateLimitMiddlewares.KeyByAuthId, Limit: rateLimitHelpers.Limit{Burst: 30, Per: time.Minute}}),
+		r.MediaController.GenerateMultipartUploadUrls,
+	)
+	mediaRouteGroup.Post("/media/upload",
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "directUploads", KeyBy: rateLimitMiddlewares.KeyByAuthId, Limit: rateLimitHelpers.Limit{Burst: 20, Per: time.Minute}}),
+		r.MediaController.DirectUpload,
+	)
+	mediaRouteGroup.Put("/media/:mediaID/replace", r.MediaController.ReplaceMedia)
+	mediaRouteGroup.Delete("/media/:mediaID", r.MediaController.DeleteMedia)
+	mediaRouteGroup.Get("/media/:mediaID/status", r.MediaController.GetMediaStatus)
 }
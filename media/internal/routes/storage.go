@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"media/internal/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StorageRoutes struct {
+	StorageController controllers.StorageController
+}
+
+func (sr *StorageRoutes) InitRoutes(router fiber.Router) {
+	router.Put("/:bucket/*", sr.StorageController.UploadPart)
+	router.Get("/:bucket/*", sr.StorageController.GetObject)
+}
@@ -1,23 +1,127 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"media/internal/config"
 	"media/internal/controllers"
+	"media/internal/database"
+	"media/internal/middlewares/rateLimitMiddlewares"
 	"media/internal/routes"
 	"media/internal/services"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+	"media/internal/utils/helpers/rateLimitHelpers"
+	"media/providers/moderation"
 	"media/providers/storage"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+func newStorageProvider() (storage.StorageProvider, error) {
+	storageConfig := config.GetConfig().Storage
+	switch storageConfig.Provider {
+	case "aws":
+		awsConfig := config.GetConfig().AWS
+		return storage.NewAWSStorageProvider(awsConfig.Region, awsConfig.AWSAccessKeyId, awsConfig.AWSSecretAccessKey, storageConfig.PublicBaseURL)
+	case "gcp":
+		// Service account file locally, application default credentials otherwise
+		credentialsFile := ""
+		if config.GetConfig().Env == "development" {
+			credentialsFile = config.GetConfig().GoogleServiceJsonFilePath
+		}
+		return storage.NewGCPStorageProvider(context.Background(), credentialsFile, storageConfig.PublicBaseURL)
+	case "local":
+		return storage.NewLocalStorageProvider(storageConfig.LocalDir, storageConfig.PublicBaseURL, storageConfig.SigningSecret, []string{storageConfig.Bucket})
+	}
+	return nil, fmt.Errorf("unknown storage provider %q", storageConfig.Provider)
+}
+
+func newModerator() (moderation.Moderator, error) {
+	moderationConfig := config.GetConfig().Moderation
+	thresholds := moderation.Thresholds{
+		Review: moderationConfig.ReviewThreshold,
+		Reject: moderationConfig.RejectThreshold,
+	}
+	switch moderationConfig.Provider {
+	case "heuristic":
+		return moderation.NewHeuristicModerator(thresholds.Review), nil
+	case "fixture":
+		return moderation.LoadFixtureModerator(moderationConfig.FixturePath, moderation.VerdictApprove)
+	case "api":
+		if moderationConfig.APIURL == "" {
+			return nil, fmt.Errorf("MODERATION_API_URL is required for the api moderator")
+		}
+		return moderation.NewAPIModerator(moderationConfig.APIURL, moderationConfig.APIKey, thresholds), nil
+	}
+	return nil, fmt.Errorf("unknown moderation provider %q", moderationConfig.Provider)
+}
+
+// newFaceDetector runs the cascade at FACE_CASCADE_PATH, the facefinder
+// cascade shipped with the service otherwise.
+func newFaceDetector() (*mediahelpers.FaceDetector, error) {
+	if cascadePath := config.GetConfig().FaceCascadePath; cascadePath != "" {
+		return mediahelpers.LoadFaceDetector(cascadePath)
+	}
+	return mediahelpers.DefaultFaceDetector()
+}
+
+func newRateLimitStore() (rateLimitHelpers.Store, error) {
+	switch config.GetConfig().RateLimit.Store {
+	case "memory":
+		return rateLimitHelpers.NewMemoryStore(), nil
+	case "mongo":
+		return rateLimitHelpers.NewMongoStore(context.Background(), database.Mongo().Db().Collection("rateLimits"))
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q", config.GetConfig().RateLimit.Store)
+}
+
 func (s *FiberServer) RegisterFiberRoutes() {
-	awsStorageProvider, err := storage.NewAWSStorageProvider(config.GetConfig().AWS.Region, config.GetConfig().AWS.AWSAccessKeyId, config.GetConfig().AWS.AWSSecretAccessKey)
+	storageProvider, err := newStorageProvider()
 	if err != nil {
 		panic(err)
 	}
+	moderator, err := newModerator()
+	if err != nil {
+		panic(err)
+	}
+	faceDetector, err := newFaceDetector()
+	if err != nil {
+		panic(err)
+	}
+	rateLimitStore, err := newRateLimitStore()
+	if err != nil {
+		panic(err)
+	}
+	rateLimits, err := rateLimitHelpers.ParseLimits(config.GetConfig().RateLimit.Limits)
+	if err != nil {
+		panic(err)
+	}
+	rateLimitMiddlewares.Configure(rateLimitStore, rateLimits)
 	mediaService := services.MediaService{
-		StorageProvider: awsStorageProvider,
+		StorageProvider: storageProvider,
+		Moderator:       moderator,
+		FaceDetector:    faceDetector,
 	}
+	s.mediaService = &mediaService
+
+	// Local storage serves its own signed URLs, register before user auth
+	if localStorageProvider, ok := storageProvider.(*storage.LocalStorageProvider); ok {
+		storageRoutes := routes.StorageRoutes{
+			StorageController: controllers.StorageController{
+				StorageProvider: localStorageProvider,
+			},
+		}
+		storageRoutes.InitRoutes(s.App.Group("/storage"))
+	}
+
+	// Export links are signed, register before user auth
+	exportRoutes := routes.ExportRoutes{
+		MediaController: controllers.MediaController{
+			MediaService: mediaService,
+		},
+	}
+	exportRoutes.InitRoutes(s.App.Group("/exports"))
+
 	internalRoutesGroup := s.App.Group("/internal")
 	internalRoutes := routes.InternalRoutes{
 		InternalController: controllers.InternalController{
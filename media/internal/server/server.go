@@ -2,19 +2,22 @@ package server
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 
 	"media/internal/config"
 	"media/internal/database"
+	"media/internal/services"
 	PubSub "media/providers/pubSub"
 )
 
 type FiberServer struct {
 	*fiber.App
 
-	db database.Service
+	db           database.Service
+	mediaService *services.MediaService
 }
 
 func New() *FiberServer {
@@ -22,6 +25,15 @@ func New() *FiberServer {
 		App: fiber.New(fiber.Config{
 			ServerHeader: "auth",
 			AppName:      "auth",
+			// Multipart parts are 5MB, leave room for them on local storage
+			// and for direct uploads with their form encoding
+			BodyLimit: max(8*1024*1024, config.GetConfig().DirectUploadMaxSize+1024*1024),
+			// Rate limits key on c.IP(), which only trusts the proxy header
+			// on requests from the configured load balancers
+			ProxyHeader:             config.GetConfig().Proxy.Header,
+			EnableTrustedProxyCheck: true,
+			TrustedProxies:          config.GetConfig().Proxy.TrustedProxies,
+			EnableIPValidation:      true,
 		}),
 
 		db: database.Mongo(),
@@ -49,3 +61,10 @@ func New() *FiberServer {
 
 	return server
 }
+
+// StartMediaJobWorkers starts the media processing workers, routes have to be
+// registered first. The returned WaitGroup is done once ctx is cancelled and
+// the running jobs have finished.
+func (s *FiberServer) StartMediaJobWorkers(ctx context.Context) *sync.WaitGroup {
+	return s.mediaService.StartMediaJobWorkers(ctx, config.GetConfig().MediaJobWorkers)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"media/internal/utils/helpers/httpHelper"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+	"slices"
+)
+
+// Formats whose bytes may be uploaded under each audio content type, m4a
+// files are commonly sent as audio/aac.
+var audioFormatsByContentType = map[string][]string{
+	"audio/mpeg": {mediahelpers.AudioFormatMP3},
+	"audio/mp4":  {mediahelpers.AudioFormatMP4},
+	"audio/aac":  {mediahelpers.AudioFormatAAC, mediahelpers.AudioFormatMP4},
+	"audio/ogg":  {mediahelpers.AudioFormatOpus, mediahelpers.AudioFormatOgg},
+}
+
+// analyzeAudio probes an uploaded audio file and records its duration and
+// waveform. Files that are not audio of the declared type or run longer than
+// the policy allows are deleted along with their media.
+func (mediaService *MediaService) analyzeAudio(ctx context.Context, media *models.Media, policy *MediaPolicy) error {
+	downloadURL, err := mediaService.downloadURL(*media)
+	if err != nil {
+		return err
+	}
+	data, err := httpHelper.DownloadFromSignedURL(downloadURL)
+	if err != nil {
+		return err
+	}
+
+	info, err := mediahelpers.ProbeAudio(data)
+	if err != nil && !errors.Is(err, mediahelpers.ErrNotAudio) {
+		return err
+	}
+	var rejection error
+	switch {
+	case info == nil || !slices.Contains(audioFormatsByContentType[media.ContentType], info.Format):
+		rejection = httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-audio", 422, "File is not audio of the declared content type")
+	case info.Duration <= 0 || info.Duration > policy.MaxDuration:
+		rejection = httpErrors.HydrateHttpError("purely/media/requests/errors/audio-too-long", 422, fmt.Sprintf("Audio must be at most %d seconds long", int(policy.MaxDuration.Seconds())))
+	}
+	if rejection != nil {
+		if err := mediaService.releaseObject(ctx, *media); err != nil {
+			log.Printf("Error deleting rejected audio %s: %v", media.ID.Hex(), err)
+		}
+		if _, err := models.DeleteById(ctx, database.Mongo().Db(), models.Media{}, media.ID); err != nil {
+			log.Printf("Error deleting rejected audio entry %s: %v", media.ID.Hex(), err)
+		}
+		return rejection
+	}
+
+	media.DurationMs = int(info.Duration.Milliseconds())
+	media.Waveform = mediahelpers.Waveform(info.Levels, mediahelpers.WaveformBars)
+	_, err = models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, map[string]interface{}{
+		"durationMs": media.DurationMs,
+		"waveform":   media.Waveform,
+	})
+	return err
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	PubSub "media/providers/pubSub"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DeleteAccountMedia deletes everything authId uploaded for the account
+// deletion saga of auth: originals with their variants, variants left without
+// an original, unfinished uploads, the quota override and data exports.
+// Running it again is harmless, auth asks until it gets the acknowledgement.
+func (mediaService *MediaService) DeleteAccountMedia(ctx context.Context, authId string) error {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"authId": authId}}},
+		// Originals first so their variants go with them
+		{{Key: "$sort", Value: bson.M{"sourceID": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	var medias []models.Media
+	err = cursor.All(ctx, &medias)
+	cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+	for _, media := range medias {
+		if media.Variant == "" {
+			if err := mediaService.deleteMediaWithVariants(ctx, media); err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := models.DeleteById(ctx, database.Mongo().Db(), models.Media{}, media.ID)
+		if err != nil {
+			return err
+		}
+		// Already gone with its original
+		if result.DeletedCount == 0 {
+			continue
+		}
+		if err := mediaService.releaseObject(ctx, media); err != nil {
+			return err
+		}
+	}
+
+	sessionCursor, err := models.Find(ctx, database.Mongo().Db(), models.UploadSession{AuthId: authId}, nil)
+	if err != nil {
+		return err
+	}
+	var sessions []models.UploadSession
+	err = sessionCursor.All(ctx, &sessions)
+	sessionCursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.CompletedAt != nil || session.UploadID == "" {
+			continue
+		}
+		if err := mediaService.StorageProvider.AbortMultipartUpload(session.Bucket, session.UploadID, session.FilePath, session.FileName, session.ContentType); err != nil {
+			// Unfinished uploads also expire on their own in the bucket
+			log.Printf("Error aborting upload %s: %v", session.UploadID, err)
+		}
+	}
+	if _, err := models.DeleteMany(ctx, database.Mongo().Db(), models.UploadSession{}, bson.M{"authId": authId}); err != nil {
+		return err
+	}
+	if _, err := models.DeleteMany(ctx, database.Mongo().Db(), models.StorageQuota{}, bson.M{"authId": authId}); err != nil {
+		return err
+	}
+	if _, err := models.DeleteMany(ctx, database.Mongo().Db(), models.QuotaReservation{}, bson.M{"authId": authId}); err != nil {
+		return err
+	}
+	return mediaService.deleteAccountExports(ctx, bson.M{"authId": authId})
+}
+
+// acknowledgeAccountDeletion tells auth the media step of a deletion is done.
+func acknowledgeAccountDeletion(ctx context.Context, sagaID string, authId string) error {
+	return PubSub.GetClient().PublishToService(ctx, "auth", PubSub.PubSubMessageType{
+		Type: "accountDeletionStepCompleted",
+		Data: map[string]interface{}{
+			"sagaID":  sagaID,
+			"authId":  authId,
+			"service": "media",
+		},
+	})
+}
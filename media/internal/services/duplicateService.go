@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"image"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Hashes within this hamming distance are treated as the same photo. It has to
+// stay below mediahelpers.PHashBandsCount for the banded lookup to find them.
+const duplicatePhotoMaxDistance = 6
+
+type nearDuplicate struct {
+	Media    models.Media
+	Distance int
+}
+
+func (mediaService *MediaService) findNearDuplicates(ctx context.Context, media models.Media, hash uint64) ([]nearDuplicate, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id":        bson.M{"$ne": media.ID},
+			"pHashBands": bson.M{"$in": mediahelpers.PHashBands(hash)},
+		}}},
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []models.Media
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	duplicates := []nearDuplicate{}
+	for _, candidate := range candidates {
+		candidateHash, err := mediahelpers.ParsePHash(candidate.PHash)
+		if err != nil {
+			continue
+		}
+		distance := mediahelpers.HammingDistance(hash, candidateHash)
+		if distance <= duplicatePhotoMaxDistance {
+			duplicates = append(duplicates, nearDuplicate{Media: candidate, Distance: distance})
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Distance < duplicates[j].Distance
+	})
+	return duplicates, nil
+}
+
+func (mediaService *MediaService) flagDuplicatePhoto(ctx context.Context, media models.Media, duplicate nearDuplicate) error {
+	filter := bson.M{
+		"mediaID":        media.ID,
+		"matchedMediaID": duplicate.Media.ID,
+		"reason":         models.MediaFlagReasonDuplicatePhoto,
+	}
+	_, err := models.Upsert(ctx, database.Mongo().Db(), filter, models.MediaFlag{
+		MediaID:        media.ID,
+		MatchedMediaID: duplicate.Media.ID,
+		AuthId:         media.AuthId,
+		MatchedAuthId:  duplicate.Media.AuthId,
+		Reason:         models.MediaFlagReasonDuplicatePhoto,
+		Distance:       duplicate.Distance,
+		Status:         models.MediaFlagStatusOpen,
+	})
+	return err
+}
+
+// DetectDuplicates stores the perceptual hash of an original image and checks
+// it against every other hashed image. Matches owned by someone else raise a
+// trust and safety flag. A match owned by the same user means the photo was
+// uploaded twice, so the new copy is removed from storage and the existing
+// media is returned for the caller to continue with.
+func (mediaService *MediaService) DetectDuplicates(ctx context.Context, media models.Media, img image.Image) (*models.Media, error) {
+	hash := mediahelpers.DHash(img)
+	_, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, map[string]interface{}{
+		"pHash":      mediahelpers.FormatPHash(hash),
+		"pHashBands": mediahelpers.PHashBands(hash),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates, err := mediaService.findNearDuplicates(ctx, media, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var canonicalMedia *models.Media
+	for _, duplicate := range duplicates {
+		if media.AuthId == "" || duplicate.Media.AuthId == "" {
+			continue
+		}
+		if duplicate.Media.AuthId != media.AuthId {
+			if err := mediaService.flagDuplicatePhoto(ctx, media, duplicate); err != nil {
+				log.Printf("Error flagging duplicate photo %s: %v", media.ID.Hex(), err)
+			}
+			continue
+		}
+		if canonicalMedia == nil {
+			existingMedia := duplicate.Media
+			canonicalMedia = &existingMedia
+		}
+	}
+	if canonicalMedia == nil {
+		return nil, nil
+	}
+
+	if err := mediaService.releaseObject(ctx, media); err != nil {
+		return nil, err
+	}
+	if _, err := models.DeleteById(ctx, database.Mongo().Db(), models.Media{}, media.ID); err != nil {
+		return nil, err
+	}
+	return canonicalMedia, nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"media/internal/config"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/utils/constants"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"media/internal/utils/helpers/httpHelper"
+	PubSub "media/providers/pubSub"
+	"media/providers/storage"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const exportContentType = "application/zip"
+
+// Data the backend doesn't hold yet, listed in the manifest so the export
+// doesn't look like it lost them.
+var exportNotHeld = map[string]string{
+	"swipes":   "Swipes are not stored by the backend yet",
+	"matches":  "Matches are not stored by the backend yet",
+	"messages": "Messages are not stored by the backend yet",
+}
+
+// exportedMedia describes an original in the manifest of an export.
+type exportedMedia struct {
+	ID          string    `json:"id"`
+	Purpose     string    `json:"purpose,omitempty"`
+	ContentType string    `json:"contentType"`
+	Size        int       `json:"size"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+	File        string    `json:"file,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// QueueAccountExport stores the sections of an export auth and profiles put
+// together and queues the job packaging them with the photos. Redelivered
+// messages queue nothing new. An export whose job gives up is purged like an
+// expired one, after the link TTL.
+func (mediaService *MediaService) QueueAccountExport(ctx context.Context, exportID string, authId string, sections map[string]interface{}) error {
+	exportObjectID, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return httpErrors.HydrateHttpError("purely/media/exports/errors/invalid-export-id", 400, "Invalid export ID")
+	}
+	payload, err := json.Marshal(sections)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(config.GetConfig().Export.LinkTTL)
+	if _, err := models.Create(ctx, database.Mongo().Db(), models.AccountExport{
+		ID:        exportObjectID,
+		AuthId:    authId,
+		Status:    models.AccountExportStatusPending,
+		Payload:   string(payload),
+		ExpiresAt: &expiresAt,
+	}); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return mediaService.EnqueueMediaJob(ctx, models.MediaJobTypeExportAccount, exportID, "")
+}
+
+// BuildAccountExport packages an export into a zip: a manifest, the sections
+// of the other services and every original the user uploaded. Only media of
+// the user are read, renditions made for them from other users' photos are
+// variants and left out. The archive is stored privately and auth is given an
+// expiring link to it.
+func (mediaService *MediaService) BuildAccountExport(ctx context.Context, exportID primitive.ObjectID) error {
+	var export models.AccountExport
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.AccountExport{ID: exportID}).Decode(&export); err != nil {
+		return httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	if export.Status != models.AccountExportStatusReady {
+		// The archive holds every original of the user, it is written to disk
+		// and streamed from there instead of being kept in memory
+		archive, err := os.CreateTemp("", "export-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(archive.Name())
+		defer archive.Close()
+		if err := mediaService.packageAccountExport(ctx, export, archive); err != nil {
+			return err
+		}
+		size, err := archive.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if _, err := archive.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		filePath := "exports/" + export.AuthId
+		fileName := export.ID.Hex()
+		bucket := config.GetConfig().Storage.PrivateBucket
+		if _, err := storage.UploadStream(mediaService.StorageProvider, bucket, filePath, fileName, exportContentType, archive, int(size)); err != nil {
+			return err
+		}
+		now := time.Now()
+		expiresAt := now.Add(config.GetConfig().Export.LinkTTL)
+		if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.AccountExport{}, export.ID, map[string]interface{}{
+			"status":    models.AccountExportStatusReady,
+			"bucket":    bucket,
+			"path":      filePath,
+			"fileName":  fileName,
+			"size":      int(size),
+			"readyAt":   now,
+			"expiresAt": expiresAt,
+		}); err != nil {
+			return err
+		}
+		export.ExpiresAt = &expiresAt
+	}
+
+	// Publishing again after a failure is fine, auth ignores exports it already completed
+	return PubSub.GetClient().PublishToService(ctx, "auth", PubSub.PubSubMessageType{
+		Type: "accountExportReady",
+		Data: map[string]interface{}{
+			"exportID":    export.ID.Hex(),
+			"authId":      export.AuthId,
+			"downloadURL": exportDownloadURL(export.ID.Hex(), *export.ExpiresAt),
+			"expiresAt":   export.ExpiresAt.UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// packageAccountExport writes the zip of an export to w, the originals are
+// downloaded and added one at a time.
+func (mediaService *MediaService) packageAccountExport(ctx context.Context, export models.AccountExport, w io.Writer) error {
+	sections := map[string]interface{}{}
+	if export.Payload != "" {
+		if err := json.Unmarshal([]byte(export.Payload), &sections); err != nil {
+			return err
+		}
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"authId":  export.AuthId,
+			"variant": bson.M{"$exists": false},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	var originals []models.Media
+	err = cursor.All(ctx, &originals)
+	cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	writeJSON := func(name string, value interface{}) error {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	files := map[string]string{
+		"auth.json":     "Your account",
+		"profiles.json": "Your profiles, with the questions of your prompts",
+		"media.json":    "The photos and recordings you uploaded, stored under media/",
+	}
+	media := []exportedMedia{}
+	for _, original := range originals {
+		item := exportedMedia{
+			ID:          original.ID.Hex(),
+			Purpose:     original.Purpose,
+			ContentType: original.ContentType,
+			Size:        original.Size,
+			UploadedAt:  original.ID.Timestamp(),
+		}
+		data, err := mediaService.downloadOriginal(original)
+		if err != nil {
+			// A missing object shouldn't hold back the rest of the export
+			log.Printf("Error adding media %s to export %s: %v", original.ID.Hex(), export.ID.Hex(), err)
+			item.Error = "The file could not be retrieved"
+			media = append(media, item)
+			continue
+		}
+		item.File = "media/" + original.ID.Hex() + "." + constants.FileExtMap[original.ContentType]
+		file, err := archive.Create(item.File)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(data); err != nil {
+			return err
+		}
+		media = append(media, item)
+	}
+
+	if err := writeJSON("manifest.json", map[string]interface{}{
+		"exportID":    export.ID.Hex(),
+		"authId":      export.AuthId,
+		"generatedAt": time.Now().UTC(),
+		"files":       files,
+		"notIncluded": exportNotHeld,
+	}); err != nil {
+		return err
+	}
+	if err := writeJSON("auth.json", sections["auth"]); err != nil {
+		return err
+	}
+	profiles := sections["profiles"]
+	if profiles == nil {
+		profiles = []interface{}{}
+	}
+	if err := writeJSON("profiles.json", profiles); err != nil {
+		return err
+	}
+	if err := writeJSON("media.json", media); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func (mediaService *MediaService) downloadOriginal(media models.Media) ([]byte, error) {
+	downloadURL, err := mediaService.downloadURL(media)
+	if err != nil {
+		return nil, err
+	}
+	return httpHelper.DownloadFromSignedURL(downloadURL)
+}
+
+func exportSignature(exportID string, expires string) string {
+	mac := hmac.New(sha256.New, []byte(config.GetConfig().Storage.SigningSecret))
+	mac.Write([]byte("export\n" + exportID + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// exportDownloadURL is the link users download an export through. It is
+// signed by this service, so it can outlive the storage signed URLs it
+// redirects to.
+func exportDownloadURL(exportID string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", exportSignature(exportID, expires))
+	return fmt.Sprintf("%s/exports/%s/download?%s", config.GetConfig().Export.LinkBaseURL, exportID, query.Encode())
+}
+
+// AccountExportDownloadURL checks a link issued by exportDownloadURL and
+// returns a short lived storage URL of the archive.
+func (mediaService *MediaService) AccountExportDownloadURL(ctx context.Context, exportID string, expires string, signature string) (string, error) {
+	invalidLink := httpErrors.HydrateHttpError("purely/media/exports/errors/invalid-link", 403, "Invalid or expired link")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return "", invalidLink
+	}
+	if !hmac.Equal([]byte(exportSignature(exportID, expires)), []byte(signature)) {
+		return "", invalidLink
+	}
+	exportObjectID, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return "", invalidLink
+	}
+	var export models.AccountExport
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.AccountExport{ID: exportObjectID}).Decode(&export); err != nil || export.Status != models.AccountExportStatusReady {
+		return "", httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	signedUrl, err := mediaService.StorageProvider.GenerateSignedUrl(export.Bucket, export.Path, export.FileName, exportContentType, export.Size)
+	if err != nil {
+		log.Printf("Error signing download URL for export %s: %v", exportID, err)
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/could-not-generate-signed-url", 500, "Failed to generate signed URL")
+	}
+	return signedUrl.SignedUrl, nil
+}
+
+// deleteAccountExports deletes the exports matching filter and their archives.
+func (mediaService *MediaService) deleteAccountExports(ctx context.Context, filter bson.M) error {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.AccountExport{}, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+	})
+	if err != nil {
+		return err
+	}
+	var exports []models.AccountExport
+	err = cursor.All(ctx, &exports)
+	cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if export.FileName != "" {
+			if err := mediaService.StorageProvider.DeleteFile(export.Bucket, export.Path, export.FileName, exportContentType); err != nil {
+				return err
+			}
+		}
+		if _, err := models.DeleteById(ctx, database.Mongo().Db(), models.AccountExport{}, export.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeExpiredExports deletes exports whose link expired.
+func (mediaService *MediaService) PurgeExpiredExports(ctx context.Context) error {
+	return mediaService.deleteAccountExports(ctx, bson.M{"expiresAt": bson.M{"$lte": time.Now()}})
+}
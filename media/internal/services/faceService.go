@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/utils/constants"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+
+	"github.com/disintegration/imaging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const thumbnailSize = 320
+
+const (
+	MediaRejectedNoFace       = "noFace"
+	MediaRejectedTooManyFaces = "tooManyFaces"
+)
+
+// faceRule bounds the number of faces a photo of a purpose may show,
+// MaxFaces 0 means no upper bound.
+type faceRule struct {
+	MinFaces int
+	MaxFaces int
+}
+
+var purposeFaceRules = map[string]faceRule{
+	constants.MediaPurposePrimaryProfilePhoto: {MinFaces: 1, MaxFaces: 1},
+	constants.MediaPurposeProfilePhoto:        {MinFaces: 1},
+}
+
+// CheckFaces detects the faces in an original, stores them on the media and
+// returns the reason the photo breaks the rules of its purpose, if any.
+func (mediaService *MediaService) CheckFaces(ctx context.Context, media models.Media, img image.Image) ([]mediahelpers.FaceBox, string, error) {
+	if mediaService.FaceDetector == nil {
+		return nil, "", nil
+	}
+	faces := mediaService.FaceDetector.Detect(img)
+
+	faceBoxes := []models.FaceBox{}
+	for _, face := range faces {
+		faceBoxes = append(faceBoxes, models.FaceBox{
+			X:      face.X,
+			Y:      face.Y,
+			Width:  face.Width,
+			Height: face.Height,
+			Score:  face.Score,
+		})
+	}
+	rejectedReason := ""
+	if rule, ok := purposeFaceRules[media.Purpose]; ok {
+		if len(faces) < rule.MinFaces {
+			rejectedReason = MediaRejectedNoFace
+		} else if rule.MaxFaces > 0 && len(faces) > rule.MaxFaces {
+			rejectedReason = MediaRejectedTooManyFaces
+		}
+	}
+
+	update := map[string]interface{}{
+		"faceBoxes": faceBoxes,
+		"faceCount": len(faces),
+	}
+	if rejectedReason != "" {
+		update["rejectedReason"] = rejectedReason
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, update); err != nil {
+		return nil, "", err
+	}
+	return faces, rejectedReason, nil
+}
+
+// CreateThumbnail stores a square thumbnail of an original, cropped around its
+// focal point. A thumbnail cut around another point is replaced. Thumbnails
+// of originals are as private as the originals.
+func (mediaService *MediaService) CreateThumbnail(ctx context.Context, media models.Media, img image.Image, focus models.FocalPoint) error {
+	var existingThumbnail models.Media
+	err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+		SourceID: media.ID,
+		Variant:  models.MediaVariantThumbnail,
+	}).Decode(&existingThumbnail)
+	if err == nil && existingThumbnail.FocalPoint != nil && *existingThumbnail.FocalPoint == focus {
+		return nil
+	}
+	// Thumbnails made before focal points were recorded are kept
+	if err == nil && existingThumbnail.FocalPoint == nil && media.FocalSource != models.MediaFocalSourceUser {
+		return nil
+	}
+
+	crop := mediahelpers.FocalCrop(img.Bounds(), mediahelpers.FocalPoint(focus), 1)
+	thumbnail := imaging.Resize(imaging.Crop(img, crop), thumbnailSize, thumbnailSize, imaging.Lanczos)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+
+	placeholders := placeholdersOf(thumbnail)
+	contentType := "image/jpeg"
+	bucket := bucketOf(media)
+	object, err := mediaService.storeObject(ctx, bucket, contentType, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	thumbnailMedia := models.Media{
+		ID:          primitive.NewObjectID(),
+		URL:         object.URL,
+		EXT:         constants.FileExtMap[contentType],
+		Path:        object.Path,
+		Domain:      object.Domain,
+		ContentType: contentType,
+		FileName:    object.FileName,
+		Size:        object.Size,
+		SHA256:      object.SHA256,
+		AuthId:      media.AuthId,
+		Bucket:      bucket,
+		Visibility:  media.Visibility,
+		SourceID:    media.ID,
+		Variant:     models.MediaVariantThumbnail,
+		FocalPoint:  &focus,
+
+		ModerationStatus: media.ModerationStatus,
+
+		BlurHash:      placeholders.BlurHash,
+		DominantColor: placeholders.DominantColor,
+	}
+	if _, err = models.Create(ctx, database.Mongo().Db(), thumbnailMedia); err != nil {
+		if releaseErr := mediaService.releaseObject(ctx, thumbnailMedia); releaseErr != nil {
+			log.Printf("Error releasing thumbnail of %s: %v", media.ID.Hex(), releaseErr)
+		}
+		return err
+	}
+	if existingThumbnail.ID != primitive.NilObjectID {
+		if err := mediaService.releaseObject(ctx, existingThumbnail); err != nil {
+			log.Printf("Error releasing previous thumbnail of %s: %v", media.ID.Hex(), err)
+		}
+		if _, err := models.DeleteById(ctx, database.Mongo().Db(), models.Media{}, existingThumbnail.ID); err != nil {
+			log.Printf("Error deleting previous thumbnail of %s: %v", media.ID.Hex(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"image"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"media/internal/utils/helpers/httpHelper"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// focalPointOf returns the point the crops of an original are centered on:
+// the one its owner picked, else the center of its faces, else its most
+// salient region. A detected point is stored on the original.
+func (mediaService *MediaService) focalPointOf(ctx context.Context, media models.Media, img image.Image, faces []mediahelpers.FaceBox) models.FocalPoint {
+	if media.FocalSource == models.MediaFocalSourceUser && media.FocalPoint != nil {
+		return *media.FocalPoint
+	}
+	source := models.MediaFocalSourceFaces
+	focus, ok := mediahelpers.FacesFocus(img.Bounds(), faces)
+	if !ok {
+		source = models.MediaFocalSourceSaliency
+		focus = mediahelpers.SaliencyFocus(img)
+	}
+	focalPoint := models.FocalPoint(focus)
+	if media.FocalPoint == nil || *media.FocalPoint != focalPoint || media.FocalSource != source {
+		if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, map[string]interface{}{
+			"focalPoint":  focalPoint,
+			"focalSource": source,
+		}); err != nil {
+			log.Printf("Error storing focal point of %s: %v", media.ID.Hex(), err)
+		}
+	}
+	return focalPoint
+}
+
+// SetFocalPoint records the focal point the owner picked for an original and
+// has its variants cropped again around it. Without a focal point the
+// detected one is used again.
+func (mediaService *MediaService) SetFocalPoint(ctx context.Context, authId string, mediaID string, focalPoint *models.FocalPoint) error {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Invalid media ID")
+	}
+	if focalPoint != nil && (focalPoint.X < 0 || focalPoint.X > 1 || focalPoint.Y < 0 || focalPoint.Y > 1) {
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-focal-point", 400, "Focal point must be between 0 and 1")
+	}
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+		ID:     mediaObjectID,
+		AuthId: authId,
+	}).Err(); err != nil {
+		return httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	update := map[string]interface{}{
+		"focalPoint":  focalPoint,
+		"focalSource": models.MediaFocalSourceUser,
+	}
+	if focalPoint == nil {
+		update["focalSource"] = ""
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, mediaObjectID, update); err != nil {
+		return err
+	}
+	return mediaService.EnqueueMediaJob(ctx, models.MediaJobTypeCropVariants, mediaID, "")
+}
+
+// CropVariants cuts the cropped variants of an original again around its
+// focal point.
+func (mediaService *MediaService) CropVariants(ctx context.Context, mediaID primitive.ObjectID) error {
+	var media models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{ID: mediaID}).Decode(&media); err != nil {
+		return httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	policy := processingPolicyOf(media)
+	if !policy.processes(ProcessResize) || media.RejectedReason != "" {
+		return nil
+	}
+	if isQuarantined(media.ModerationStatus) {
+		return errMediaQuarantined
+	}
+	downloadURL, err := mediaService.downloadURL(media)
+	if err != nil {
+		return err
+	}
+	_, img, err := httpHelper.DownloadImageFromSignedURL(downloadURL)
+	if err != nil {
+		return err
+	}
+	faces := []mediahelpers.FaceBox{}
+	for _, face := range media.FaceBoxes {
+		faces = append(faces, mediahelpers.FaceBox{X: face.X, Y: face.Y, Width: face.Width, Height: face.Height, Score: face.Score})
+	}
+	return mediaService.CreateThumbnail(ctx, media, img, mediaService.focalPointOf(ctx, media, img, faces))
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/types/mediaServiceTypes"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mediaJobMaxAttempts  = 5
+	mediaJobRetryBackoff = 30 * time.Second
+	mediaJobTimeout      = 2 * time.Minute
+	mediaJobPollInterval = 5 * time.Second
+
+	mediaRetentionInterval = time.Hour
+)
+
+// mediaStatusQuarantined is reported instead of the job state while a media is
+// held for moderation.
+const mediaStatusQuarantined = "quarantined"
+
+// mediaJobsQueued wakes up an idle worker as soon as a job is enqueued.
+var mediaJobsQueued = make(chan struct{}, 1)
+
+// EnqueueMediaJob persists a job for the worker pool. A job of the same type
+// for the same media that has not finished yet is reused, so redelivered
+// messages don't process a media twice.
+func (mediaService *MediaService) EnqueueMediaJob(ctx context.Context, jobType string, mediaID string, profileID string) error {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		return err
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.MediaJob{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"mediaID":   mediaObjectID,
+			"type":      jobType,
+			"profileID": profileID,
+			"state":     bson.M{"$in": bson.A{models.MediaJobStatePending, models.MediaJobStateProcessing}},
+		}}},
+		{{Key: "$limit", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	if cursor.Next(ctx) {
+		return nil
+	}
+
+	_, err = models.Create(ctx, database.Mongo().Db(), models.MediaJob{
+		MediaID:   mediaObjectID,
+		ProfileID: profileID,
+		Type:      jobType,
+		State:     models.MediaJobStatePending,
+		RunAfter:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case mediaJobsQueued <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// claimMediaJob hands the next due job to a worker, including jobs whose
+// worker stopped before finishing them.
+func claimMediaJob(ctx context.Context) (*models.MediaJob, error) {
+	now := time.Now()
+	var job models.MediaJob
+	err := models.FindOneAndUpdate(ctx, database.Mongo().Db(), models.MediaJob{}, bson.M{
+		"$or": bson.A{
+			bson.M{"state": models.MediaJobStatePending, "runAfter": bson.M{"$lte": now}},
+			bson.M{"state": models.MediaJobStateProcessing, "lockedUntil": bson.M{"$lt": now}},
+		},
+	}, map[string]interface{}{
+		"state":       models.MediaJobStateProcessing,
+		"lockedUntil": now.Add(mediaJobTimeout),
+	}, options.FindOneAndUpdate().SetSort(bson.M{"runAfter": 1})).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (mediaService *MediaService) runMediaJob(job models.MediaJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mediaJobTimeout)
+	defer cancel()
+	switch job.Type {
+	case models.MediaJobTypeBlurImage:
+		var profileID *string
+		if job.ProfileID != "" {
+			profileID = &job.ProfileID
+		}
+		_, err := mediaService.BlurImage(ctx, job.MediaID.Hex(), profileID)
+		return err
+	case models.MediaJobTypeCropVariants:
+		return mediaService.CropVariants(ctx, job.MediaID)
+	case models.MediaJobTypeExportAccount:
+		return mediaService.BuildAccountExport(ctx, job.MediaID)
+	}
+	return httpErrors.HydrateHttpError("purely/media/jobs/errors/unknown-type", 400, fmt.Sprintf("Unknown job type %s", job.Type))
+}
+
+// finishMediaJob records the outcome of a run. Client errors (rejected or
+// missing media) will not go away on their own and fail the job right away,
+// anything else is retried with a growing delay.
+func finishMediaJob(job models.MediaJob, jobErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	attempts := job.Attempts + 1
+	update := map[string]interface{}{
+		"attempts": attempts,
+		"state":    models.MediaJobStateReady,
+	}
+	if jobErr != nil {
+		update["lastError"] = jobErr.Error()
+		update["state"] = models.MediaJobStateFailed
+		var httpErr *httpErrors.HttpError
+		permanent := errors.As(jobErr, &httpErr) && httpErr.StatusCode < 500
+		if !permanent && attempts < mediaJobMaxAttempts {
+			update["state"] = models.MediaJobStatePending
+			update["runAfter"] = time.Now().Add(mediaJobRetryBackoff * time.Duration(1<<(attempts-1)))
+		}
+		log.Printf("Media job %s (%s) attempt %d failed: %v", job.ID.Hex(), job.Type, attempts, jobErr)
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.MediaJob{}, job.ID, update); err != nil {
+		log.Printf("Error updating media job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+// StartMediaJobWorkers starts the given number of background workers and the
+// hourly retention, stored object and export sweep, they stop picking up work
+// once ctx is done.
+func (mediaService *MediaService) StartMediaJobWorkers(ctx context.Context, workers int) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, err := claimMediaJob(ctx)
+				if err != nil {
+					log.Printf("Error claiming media job: %v", err)
+				}
+				if job != nil {
+					finishMediaJob(*job, mediaService.runMediaJob(*job))
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-mediaJobsQueued:
+				case <-time.After(mediaJobPollInterval):
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := mediaService.BackfillModerationStatus(ctx); err != nil {
+			log.Printf("Error backfilling moderation status: %v", err)
+		}
+		for {
+			if err := mediaService.PurgeExpiredMedia(ctx); err != nil {
+				log.Printf("Error purging expired media: %v", err)
+			}
+			if err := mediaService.CollectStoredObjects(ctx); err != nil {
+				log.Printf("Error collecting stored objects: %v", err)
+			}
+			if err := mediaService.PurgeExpiredExports(ctx); err != nil {
+				log.Printf("Error purging expired exports: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(mediaRetentionInterval):
+			}
+		}
+	}()
+	log.Printf("Started %d media job workers", workers)
+	return &wg
+}
+
+// lastMediaJob returns the most recent job of a media, nil if it has none.
+func lastMediaJob(ctx context.Context, mediaID primitive.ObjectID) (*models.MediaJob, error) {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.MediaJob{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"mediaID": mediaID}}},
+		{{Key: "$sort", Value: bson.M{"createdAt": -1}}},
+		{{Key: "$limit", Value: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var jobs []models.MediaJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// GetMediaStatus reports where a media is in processing. With an AuthId only
+// media of that user are visible.
+func (mediaService *MediaService) GetMediaStatus(ctx context.Context, data mediaServiceTypes.GetMediaStatusType) (*mediaServiceTypes.MediaStatusResType, error) {
+	mediaObjectID, err := primitive.ObjectIDFromHex(data.MediaID)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Invalid media ID")
+	}
+	var media models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+		ID:     mediaObjectID,
+		AuthId: data.AuthId,
+	}).Decode(&media); err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	status := &mediaServiceTypes.MediaStatusResType{
+		MediaID: data.MediaID,
+		Status:  models.MediaJobStatePending,
+	}
+	if media.Quality != nil {
+		status.Warnings = media.Quality.Warnings
+	}
+	if isQuarantined(media.ModerationStatus) {
+		status.Status = mediaStatusQuarantined
+		return status, nil
+	}
+	if media.RejectedReason != "" {
+		status.Status = models.MediaJobStateFailed
+		status.RejectedReason = media.RejectedReason
+		return status, nil
+	}
+
+	job, err := lastMediaJob(ctx, mediaObjectID)
+	if err != nil {
+		return nil, err
+	}
+	if job != nil {
+		status.Status = job.State
+		status.Attempts = job.Attempts
+		status.LastError = job.LastError
+		return status, nil
+	}
+
+	// Media processed before jobs existed are ready once they have a variant
+	if models.FindOne(ctx, database.Mongo().Db(), models.Media{SourceID: mediaObjectID}).Err() == nil {
+		status.Status = models.MediaJobStateReady
+	}
+	return status, nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/types/mediaServiceTypes"
+	httpErrors "media/internal/utils/helpers/httpError"
+	PubSub "media/providers/pubSub"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// findOwnedMedia returns an original uploaded by authId. Media of other users
+// and variants are reported as not found.
+func (mediaService *MediaService) findOwnedMedia(ctx context.Context, authId string, mediaID string) (*models.Media, error) {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Invalid media ID")
+	}
+	var media models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+		ID:     mediaObjectID,
+		AuthId: authId,
+	}).Decode(&media); err != nil || media.Variant != "" {
+		return nil, httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	return &media, nil
+}
+
+// deleteMediaWithVariants removes an original and everything derived from it.
+// Each record is deleted first and only the call that deleted it releases its
+// object, so running it again or concurrently never drops a reference twice.
+// An object left referenced by a failure is fixed by CollectStoredObjects.
+func (mediaService *MediaService) deleteMediaWithVariants(ctx context.Context, media models.Media) error {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"sourceID": media.ID}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	var variants []models.Media
+	if err := cursor.All(ctx, &variants); err != nil {
+		return err
+	}
+
+	for _, item := range append(variants, media) {
+		result, err := models.DeleteById(ctx, database.Mongo().Db(), models.Media{}, item.ID)
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount != 1 {
+			continue
+		}
+		if err := mediaService.releaseObject(ctx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mediaService *MediaService) DeleteMedia(ctx context.Context, data mediaServiceTypes.DeleteMediaType) (string, error) {
+	media, err := mediaService.findOwnedMedia(ctx, data.AuthId, data.MediaID)
+	if err != nil {
+		return "", err
+	}
+	if err := mediaService.deleteMediaWithVariants(ctx, *media); err != nil {
+		log.Printf("Error deleting media %s: %v", data.MediaID, err)
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/could-not-delete-media", 500, "Failed to delete media")
+	}
+	notifyProfiles(ctx, "mediaDeleted", map[string]interface{}{
+		"authId":  data.AuthId,
+		"mediaID": media.ID.Hex(),
+	})
+	return "Media deleted", nil
+}
+
+// ReplaceMedia swaps a photo for a newly uploaded one. Profiles keeps the slot
+// and order of the old photo and asks for the new one to be blurred.
+func (mediaService *MediaService) ReplaceMedia(ctx context.Context, data mediaServiceTypes.ReplaceMediaType) (string, error) {
+	if data.MediaID == data.NewMediaID {
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Replacement must be a different media")
+	}
+	media, err := mediaService.findOwnedMedia(ctx, data.AuthId, data.MediaID)
+	if err != nil {
+		return "", err
+	}
+	newMedia, err := mediaService.findOwnedMedia(ctx, data.AuthId, data.NewMediaID)
+	if err != nil {
+		return "", err
+	}
+	if err := mediaService.deleteMediaWithVariants(ctx, *media); err != nil {
+		log.Printf("Error deleting replaced media %s: %v", data.MediaID, err)
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/could-not-replace-media", 500, "Failed to replace media")
+	}
+	notifyProfiles(ctx, "mediaReplaced", map[string]interface{}{
+		"authId":     data.AuthId,
+		"mediaID":    media.ID.Hex(),
+		"newMediaID": newMedia.ID.Hex(),
+	})
+	return "Media replaced", nil
+}
+
+// PurgeExpiredMedia deletes media whose purpose retention ran out.
+func (mediaService *MediaService) PurgeExpiredMedia(ctx context.Context) error {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"expiresAt": bson.M{"$lte": time.Now()},
+			"variant":   bson.M{"$exists": false},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	var expiredMedia []models.Media
+	if err := cursor.All(ctx, &expiredMedia); err != nil {
+		return err
+	}
+	for _, media := range expiredMedia {
+		if err := mediaService.deleteMediaWithVariants(ctx, media); err != nil {
+			log.Printf("Error purging expired media %s: %v", media.ID.Hex(), err)
+			continue
+		}
+		notifyProfiles(ctx, "mediaDeleted", map[string]interface{}{
+			"authId":  media.AuthId,
+			"mediaID": media.ID.Hex(),
+		})
+	}
+	return nil
+}
+
+func notifyProfiles(ctx context.Context, eventType string, data map[string]interface{}) {
+	if err := PubSub.GetClient().PublishToService(ctx, "profiles", PubSub.PubSubMessageType{
+		Type: eventType,
+		Data: data,
+	}); err != nil {
+		log.Printf("Error publishing %s: %v", eventType, err)
+	}
+}
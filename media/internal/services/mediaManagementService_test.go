@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/providers/storage"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// recordingStorage records the calls made to the storage provider, the
+// methods tests don't stub panic.
+type recordingStorage struct {
+	storage.StorageProvider
+	deleted []string
+}
+
+func (provider *recordingStorage) DeleteFile(bucket string, filePath string, fileName string, contentType string) error {
+	provider.deleted = append(provider.deleted, filePath+"/"+fileName)
+	return nil
+}
+
+// commandCount counts the commands of the given name sent to the deployment.
+func commandCount(mt *mtest.T, name string) int {
+	count := 0
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == name {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDeleteMediaWithVariantsReleasesObjectOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("delete twice", func(mt *mtest.T) {
+		database.Use(mt.DB)
+		provider := &recordingStorage{}
+		mediaService := &MediaService{StorageProvider: provider}
+		media := models.Media{ID: primitive.NewObjectID(), AuthId: "user", Path: "photos/user", FileName: "shared", ContentType: "image/jpeg"}
+		namespace := mt.DB.Name() + ".media"
+
+		mt.AddMockResponses(
+			// First run: no variants, the record is deleted and the object
+			// is still held by another user's media
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "refCount", Value: 1},
+			}}),
+			// Second run, like a redelivered message: the record is gone
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
+
+		for run := 0; run < 2; run++ {
+			if err := mediaService.deleteMediaWithVariants(context.Background(), media); err != nil {
+				mt.Fatalf("run %d: %v", run, err)
+			}
+		}
+		if releases := commandCount(mt, "findAndModify"); releases != 1 {
+			mt.Errorf("expected the object to be released once, got %d", releases)
+		}
+		if len(provider.deleted) != 0 {
+			mt.Errorf("expected the shared object to be kept, deleted %v", provider.deleted)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"media/internal/config"
+	"media/internal/database/models"
+	"media/internal/utils/constants"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"slices"
+	"time"
+)
+
+// Processing steps the media job runs on an original.
+const (
+	ProcessBlur     = "blur"
+	ProcessResize   = "resize"
+	ProcessModerate = "moderate"
+)
+
+var photoContentTypes = []string{"image/jpeg", "image/png"}
+
+// MediaPolicy decides how media of a purpose is stored and processed.
+// Retention 0 keeps media until its owner deletes it, without Quality the
+// quality of photos is not checked. Purposes with a MaxDuration take audio,
+// which is probed when its upload completes.
+type MediaPolicy struct {
+	ContentTypes []string
+	MaxSize      int
+	Prefix       string
+	Visibility   string
+	Processing   []string
+	Quality      *QualityThresholds
+	MaxDuration  time.Duration
+	Retention    time.Duration
+}
+
+var mediaPolicies = map[string]MediaPolicy{
+	constants.MediaPurposePrimaryProfilePhoto: {
+		ContentTypes: photoContentTypes,
+		MaxSize:      20 << 20,
+		Prefix:       "profiles",
+		Visibility:   models.MediaVisibilityPrivate,
+		Processing:   []string{ProcessModerate, ProcessResize, ProcessBlur},
+		Quality:      profilePhotoQuality,
+	},
+	constants.MediaPurposeProfilePhoto: {
+		ContentTypes: photoContentTypes,
+		MaxSize:      20 << 20,
+		Prefix:       "profiles",
+		Visibility:   models.MediaVisibilityPrivate,
+		Processing:   []string{ProcessModerate, ProcessResize, ProcessBlur},
+		Quality:      profilePhotoQuality,
+	},
+	constants.MediaPurposeChatPhoto: {
+		ContentTypes: photoContentTypes,
+		MaxSize:      10 << 20,
+		Prefix:       "chat",
+		Visibility:   models.MediaVisibilityPrivate,
+		Processing:   []string{ProcessModerate, ProcessResize},
+		Retention:    365 * 24 * time.Hour,
+	},
+	constants.MediaPurposeVerification: {
+		ContentTypes: []string{"image/jpeg"},
+		MaxSize:      10 << 20,
+		Prefix:       "verification",
+		Visibility:   models.MediaVisibilityPrivate,
+		Retention:    90 * 24 * time.Hour,
+	},
+	constants.MediaPurposeVoicePrompt: {
+		ContentTypes: []string{"audio/mpeg", "audio/mp4", "audio/aac", "audio/ogg"},
+		MaxSize:      5 << 20,
+		Prefix:       "voice",
+		Visibility:   models.MediaVisibilityPrivate,
+		MaxDuration:  30 * time.Second,
+	},
+}
+
+// mediaPolicyFor returns the policy of a purpose, unknown purposes can't be
+// uploaded.
+func mediaPolicyFor(purpose string) (*MediaPolicy, error) {
+	policy, ok := mediaPolicies[purpose]
+	if !ok {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/unknown-purpose", 400, "Unknown media purpose")
+	}
+	return &policy, nil
+}
+
+// processingPolicyOf returns the policy media is processed with. Media
+// uploaded before purposes were recorded are profile photos.
+func processingPolicyOf(media models.Media) MediaPolicy {
+	if policy, ok := mediaPolicies[media.Purpose]; ok {
+		return policy
+	}
+	return mediaPolicies[constants.MediaPurposeProfilePhoto]
+}
+
+// check rejects uploads the policy does not allow.
+func (policy *MediaPolicy) check(contentType string, fileSize int) error {
+	if !slices.Contains(policy.ContentTypes, contentType) {
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/content-type-not-allowed", 415, "Content type not allowed for this purpose")
+	}
+	if fileSize <= 0 || fileSize > policy.MaxSize {
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/file-too-large", 413, fmt.Sprintf("File must be between 1 and %d bytes", policy.MaxSize))
+	}
+	return nil
+}
+
+func (policy *MediaPolicy) bucket() string {
+	if policy.Visibility == models.MediaVisibilityPublic {
+		return config.GetConfig().Storage.Bucket
+	}
+	return config.GetConfig().Storage.PrivateBucket
+}
+
+func (policy *MediaPolicy) filePath(authId string, purpose string, contentType string, id string) string {
+	return fmt.Sprintf("%s/%s/media/%s/%s/%s", policy.Prefix, authId, purpose, contentType, id)
+}
+
+func (policy *MediaPolicy) processes(step string) bool {
+	return slices.Contains(policy.Processing, step)
+}
+
+// expiresAt returns when media uploaded now has to be removed, nil when it is
+// kept.
+func (policy *MediaPolicy) expiresAt() *time.Time {
+	if policy.Retention == 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(policy.Retention)
+	return &expiresAt
+}
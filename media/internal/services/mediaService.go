@@ -2,25 +2,39 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"image"
+	"io"
 	"log"
+	"media/internal/config"
 	"media/internal/database"
 	"media/internal/database/models"
 	"media/internal/types/mediaServiceTypes"
 	"media/internal/utils/constants"
+	"media/internal/utils/helpers/authHelpers"
 	httpErrors "media/internal/utils/helpers/httpError"
 	"media/internal/utils/helpers/httpHelper"
 	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+	"media/providers/moderation"
 	PubSub "media/providers/pubSub"
 	"media/providers/storage"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type MediaService struct {
 	StorageProvider storage.StorageProvider
+	Moderator       moderation.Moderator
+	FaceDetector    *mediahelpers.FaceDetector
 }
 
 func (mediaService *MediaService) BlurImage(ctx context.Context, imageID string, profileID *string) (*string, error) {
@@ -48,128 +62,165 @@ func (mediaService *MediaService) BlurImage(ctx context.Context, imageID string,
 		return nil, err
 	}
 	fmt.Println(">>BlurImage 8")
-	_, image, err := httpHelper.DownloadImageFromSignedURL(imageMediaData.URL)
-	if err != nil {
-		fmt.Println(">>BlurImage 8", err)
-		return nil, err
+	policy := processingPolicyOf(imageMediaData)
+	if len(policy.Processing) == 0 {
+		return nil, nil
 	}
-	fmt.Println(">>BlurImage 9")
-	blurredImageBytes, _, err := mediahelpers.BlurImage(image, 40)
+	downloadURL, err := mediaService.downloadURL(imageMediaData)
 	if err != nil {
-		fmt.Println(">>BlurImage 10", err)
 		return nil, err
 	}
-	fmt.Println(">>BlurImage 11")
-	rawFilePath := "blurred/" + imageMediaData.Path
-	fileName := imageMediaData.FileName
-	bucketName := "purely-public-assets"
-	fileSize := len(blurredImageBytes)
-	blurredImageType := "image/jpeg"
-	filePathSplits := strings.Split(rawFilePath, imageMediaData.ContentType)
-	filePath := filePathSplits[0] + blurredImageType + filePathSplits[1]
-	fmt.Println(">>BlurImage 12")
-	initUploadRes, err := mediaService.StorageProvider.InitiateMultipartUpload(
-		bucketName,
-		filePath,
-		fileName,
-		blurredImageType,
-		fileSize,
-	)
-	fmt.Println(">>BlurImage 13")
+	imageBytes, image, err := httpHelper.DownloadImageFromSignedURL(downloadURL)
 	if err != nil {
-		fmt.Println(">>BlurImage 14", err)
+		fmt.Println(">>BlurImage 8", err)
 		return nil, err
 	}
-	fmt.Println(">>BlurImage 15")
-	signedURLsRes, err := mediaService.StorageProvider.GenerateSignedURLsForParts(
-		bucketName,
-		filePath,
-		fileName,
-		initUploadRes.UploadId,
-		blurredImageType,
-		fileSize)
+	requestedMediaID := imageMediaData.ID
+	imageMediaData, err = mediaService.registerOriginal(ctx, imageMediaData, sha256Hex(imageBytes))
 	if err != nil {
-		fmt.Println(">>BlurImage 16", err)
-		return nil, err
+		log.Printf("Error registering original %s: %v", imageID, err)
 	}
-	fmt.Println(">>BlurImage 17")
-	uploadRes, err := mediaService.StorageProvider.UploadFile(
-		signedURLsRes.SignedUrls,
-		blurredImageBytes,
-		len(blurredImageBytes),
-		blurredImageType,
-	)
-	if err != nil {
-		fmt.Println(">>BlurImage 18", err)
-		return nil, err
+	if imageMediaData.BlurHash == "" {
+		placeholders := placeholdersOf(image)
+		if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, imageMediaData.ID, map[string]interface{}{
+			"blurHash":      placeholders.BlurHash,
+			"dominantColor": placeholders.DominantColor,
+		}); err != nil {
+			log.Printf("Error storing placeholders for %s: %v", imageID, err)
+		}
 	}
-	fmt.Println(">>BlurImage 19")
-	uploadCompleteRes, err := mediaService.StorageProvider.CompleteMultipartUpload(
-		bucketName,
-		initUploadRes.UploadId,
-		filePath,
-		fileName,
-		blurredImageType,
-		uploadRes,
-	)
+	canonicalMedia, err := mediaService.DetectDuplicates(ctx, imageMediaData, image)
 	if err != nil {
-		fmt.Println(">>BlurImage 20", err)
-		return nil, err
+		log.Printf("Error detecting duplicates for %s: %v", imageID, err)
 	}
-	existingImage := models.FindOne(ctx, database.Mongo().Db(), models.Media{
-		URL: uploadCompleteRes.URL,
-	})
-	fmt.Println(">>BlurImage 21")
-	fmt.Println("existingImage", existingImage)
-	var blurredMediaID string
-	if existingImage.Err() != nil {
-		savedImage, err := models.Create(ctx, database.Mongo().Db(), models.Media{
+	if canonicalMedia != nil {
+		imageMediaData = *canonicalMedia
+	} else {
+		faces, rejectedReason, err := mediaService.CheckFaces(ctx, imageMediaData, image)
+		if err != nil {
+			log.Printf("Error checking faces for %s: %v", imageID, err)
+		}
+		if rejectedReason == "" && policy.Quality != nil {
+			rejectedReason, err = mediaService.CheckQuality(ctx, imageMediaData, image, *policy.Quality)
+			if err != nil {
+				log.Printf("Error checking quality of %s: %v", imageID, err)
+			}
+		}
+		if rejectedReason != "" {
+			notifyProfiles(ctx, "mediaRejected", map[string]interface{}{
+				"authId":  imageMediaData.AuthId,
+				"mediaID": imageMediaData.ID.Hex(),
+				"reason":  rejectedReason,
+			})
+			return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/media-rejected", 422, "Photo does not meet the requirements of its purpose")
+		}
+		if imageMediaData.ModerationStatus == "" && policy.processes(ProcessModerate) {
+			imageMediaData.ModerationStatus, err = mediaService.ModerateMedia(ctx, imageMediaData, image)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if isQuarantined(imageMediaData.ModerationStatus) {
+			return nil, errMediaQuarantined
+		}
+		if policy.processes(ProcessResize) {
+			focus := mediaService.focalPointOf(ctx, imageMediaData, image, faces)
+			if err := mediaService.CreateThumbnail(ctx, imageMediaData, image, focus); err != nil {
+				log.Printf("Error creating thumbnail for %s: %v", imageID, err)
+			}
+		}
+	}
+	if isQuarantined(imageMediaData.ModerationStatus) {
+		return nil, errMediaQuarantined
+	}
+	if !policy.processes(ProcessBlur) {
+		return nil, nil
+	}
+	var blurredMedia models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+		SourceID: imageMediaData.ID,
+		Variant:  models.MediaVariantBlurred,
+	}).Decode(&blurredMedia); err != nil {
+		fmt.Println(">>BlurImage 9")
+		blurredImageBytes, blurredImage, err := mediahelpers.BlurImage(image, 40)
+		if err != nil {
+			fmt.Println(">>BlurImage 10", err)
+			return nil, err
+		}
+		blurredImageType := "image/jpeg"
+		bucketName := config.GetConfig().Storage.Bucket
+		object, err := mediaService.storeObject(ctx, bucketName, blurredImageType, blurredImageBytes)
+		if err != nil {
+			fmt.Println(">>BlurImage 13", err)
+			return nil, err
+		}
+		placeholders := placeholdersOf(blurredImage)
+		blurredMedia = models.Media{
 			ID:          primitive.NewObjectID(),
-			URL:         uploadCompleteRes.URL,
+			URL:         object.URL,
 			EXT:         constants.FileExtMap[blurredImageType],
-			Path:        uploadCompleteRes.Path,
-			Domain:      uploadCompleteRes.Domain,
+			Path:        object.Path,
+			Domain:      object.Domain,
 			ContentType: blurredImageType,
-			FileName:    fileName,
-			Size:        fileSize,
-		})
-		if err != nil {
+			FileName:    object.FileName,
+			Size:        object.Size,
+			SHA256:      object.SHA256,
+			AuthId:      imageMediaData.AuthId,
+			Bucket:      bucketName,
+			Visibility:  models.MediaVisibilityPublic,
+			SourceID:    imageMediaData.ID,
+			Variant:     models.MediaVariantBlurred,
+
+			ModerationStatus: imageMediaData.ModerationStatus,
+
+			BlurHash:      placeholders.BlurHash,
+			DominantColor: placeholders.DominantColor,
+		}
+		if _, err := models.Create(ctx, database.Mongo().Db(), blurredMedia); err != nil {
+			if releaseErr := mediaService.releaseObject(ctx, blurredMedia); releaseErr != nil {
+				log.Printf("Error releasing blurred image of %s: %v", imageID, releaseErr)
+			}
 			return nil, err
 		}
-		blurredMediaID = savedImage.InsertedID.(primitive.ObjectID).Hex()
 	}
-	var blurredImageData models.Media
-	if err := existingImage.Decode(&blurredImageData); err != nil {
-		return nil, err
-	}
-	blurredMediaID = blurredImageData.ID.Hex()
+	blurredMediaID := blurredMedia.ID.Hex()
 	if profileID != nil {
-		NotifyImageBlurred(ctx, imageID, blurredMediaID, *profileID)
+		NotifyImageBlurred(ctx, requestedMediaID.Hex(), imageMediaData.ID.Hex(), blurredMediaID, *profileID)
 	}
 	return &blurredMediaID, nil
 }
 
-func NotifyImageBlurred(ctx context.Context, mediaID string, blurredImageID string, profileID string) {
+// NotifyImageBlurred tells profiles about the blurred variant of a media.
+// canonicalMediaID differs from mediaID when the upload turned out to be a
+// duplicate of an existing photo of the same user and was merged into it.
+func NotifyImageBlurred(ctx context.Context, mediaID string, canonicalMediaID string, blurredImageID string, profileID string) {
+	data := map[string]interface{}{
+		"mediaID":        mediaID,
+		"profileID":      profileID,
+		"blurredImageID": blurredImageID,
+	}
+	if canonicalMediaID != mediaID {
+		data["canonicalMediaID"] = canonicalMediaID
+	}
 	pubsub := *PubSub.GetClient()
 	pubsub.PublishToService(ctx, "profiles", PubSub.PubSubMessageType{
 		Type: "imageBlurred",
-		Data: map[string]interface{}{
-			"mediaID":        mediaID,
-			"profileID":      profileID,
-			"blurredImageID": blurredImageID,
-		},
+		Data: data,
 	})
 }
 
 func (profileService *MediaService) GenerateMediaUploadSignedUrl(ctx context.Context, mediaUploadData mediaServiceTypes.GenerateMediaUploadSignedUrlType) (*mediaServiceTypes.GenerateMediaUploadSignedUrlResType, error) {
+	policy, err := mediaPolicyFor(mediaUploadData.Purpose)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.check(mediaUploadData.ContentType, mediaUploadData.FileSize); err != nil {
+		return nil, err
+	}
 	id := uuid.New()
 	signedUrlData, error := profileService.StorageProvider.GenerateSignedUrl(
-		"purely-profiles",
-		fmt.Sprintf("profiles/%s/media/%s/%s/%s",
-			mediaUploadData.AuthId,
-			mediaUploadData.Purpose,
-			mediaUploadData.ContentType,
-			id.String()),
+		policy.bucket(),
+		policy.filePath(mediaUploadData.AuthId, mediaUploadData.Purpose, mediaUploadData.ContentType, id.String()),
 		mediaUploadData.FileName,
 		mediaUploadData.ContentType,
 		mediaUploadData.FileSize)
@@ -183,14 +234,23 @@ func (profileService *MediaService) GenerateMediaUploadSignedUrl(ctx context.Con
 	}, nil
 }
 
-func (profileService *MediaService) GenerateMultipartUploadUrls(mediaUploadData mediaServiceTypes.GenerateMultipartUploadUrlsType) (*mediaServiceTypes.GenerateMultipartUploadUrlsResType, error) {
+func (profileService *MediaService) GenerateMultipartUploadUrls(ctx context.Context, mediaUploadData mediaServiceTypes.GenerateMultipartUploadUrlsType) (*mediaServiceTypes.GenerateMultipartUploadUrlsResType, error) {
+	policy, err := mediaPolicyFor(mediaUploadData.Purpose)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.check(mediaUploadData.ContentType, mediaUploadData.FileSize); err != nil {
+		return nil, err
+	}
+	release, err := reserveQuota(ctx, mediaUploadData.AuthId, mediaUploadData.FileSize, true)
+	if err != nil {
+		return nil, err
+	}
+	// Once the session is recorded it counts by itself
+	defer release()
 	id := uuid.New()
-	bucket := "purely-public-assets"
-	filePath := fmt.Sprintf("profiles/%s/media/%s/%s/%s",
-		mediaUploadData.AuthId,
-		mediaUploadData.Purpose,
-		mediaUploadData.ContentType,
-		id.String())
+	bucket := policy.bucket()
+	filePath := policy.filePath(mediaUploadData.AuthId, mediaUploadData.Purpose, mediaUploadData.ContentType, id.String())
 
 	rawFileName := mediaUploadData.FileName
 	fileNameSplit := strings.Split(rawFileName, ".")[0]
@@ -207,11 +267,37 @@ func (profileService *MediaService) GenerateMultipartUploadUrls(mediaUploadData
 		return nil, err
 	}
 
+	// Without a session the upload can't be completed or cleaned up later
+	abort := func() {
+		if err := profileService.StorageProvider.AbortMultipartUpload(bucket, uploadData.UploadId, filePath, fileName, mediaUploadData.ContentType); err != nil {
+			log.Printf("Error aborting upload %s: %v", uploadData.UploadId, err)
+		}
+	}
 	res, err := profileService.StorageProvider.GenerateSignedURLsForParts(bucket, filePath, fileName, uploadData.UploadId, mediaUploadData.ContentType, int(mediaUploadData.FileSize))
 	if err != nil {
+		abort()
+		return nil, err
+	}
+
+	session, err := models.Create(ctx, database.Mongo().Db(), models.UploadSession{
+		AuthId:      mediaUploadData.AuthId,
+		UploadID:    uploadData.UploadId,
+		Bucket:      bucket,
+		FilePath:    filePath,
+		FileName:    fileName,
+		Key:         filePath + "/" + fileName + "." + constants.FileExtMap[mediaUploadData.ContentType],
+		ContentType: mediaUploadData.ContentType,
+		FileSize:    mediaUploadData.FileSize,
+		Purpose:     mediaUploadData.Purpose,
+		ExpiresAt:   res.Expiry,
+	})
+	if err != nil {
+		log.Printf("Error creating upload session: %v", err)
+		abort()
 		return nil, err
 	}
 	return &mediaServiceTypes.GenerateMultipartUploadUrlsResType{
+		SessionID:  session.InsertedID.(primitive.ObjectID).Hex(),
 		SignedUrls: res.SignedUrls,
 		Expiry:     res.Expiry.Unix(),
 		UploadID:   uploadData.UploadId,
@@ -221,38 +307,318 @@ func (profileService *MediaService) GenerateMultipartUploadUrls(mediaUploadData
 	}, nil
 }
 
+// uploadCompletionTimeout is how long a request may hold an upload session
+// while completing it, after that another request may complete it.
+const uploadCompletionTimeout = 5 * time.Minute
+
+// claimUploadSession takes an upload session of authId that can still be
+// completed, so that retries and double submits can't complete it twice.
+// Sessions of other users are reported as not found. The claim has to be
+// released with releaseUploadSession if the upload isn't completed.
+func claimUploadSession(ctx context.Context, authId string, sessionID string) (*models.UploadSession, error) {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-session-id", 400, "Invalid upload session ID")
+	}
+	now := time.Now()
+	var session models.UploadSession
+	err = models.FindOneAndApply(ctx, database.Mongo().Db(), models.UploadSession{}, bson.M{
+		"_id":         sessionObjectID,
+		"authId":      authId,
+		"completedAt": nil,
+		"expiresAt":   bson.M{"$gt": now},
+		"$or": bson.A{
+			bson.M{"completingAt": nil},
+			bson.M{"completingAt": bson.M{"$lt": now.Add(-uploadCompletionTimeout)}},
+		},
+	}, bson.M{"$set": bson.M{"completingAt": now}}).Decode(&session)
+	if err == nil {
+		return &session, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	// Tell why it can't be claimed
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.UploadSession{
+		ID:     sessionObjectID,
+		AuthId: authId,
+	}).Decode(&session); err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+	switch {
+	case session.CompletedAt != nil:
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/upload-session-completed", 409, "Upload session already completed")
+	case !now.Before(session.ExpiresAt):
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/upload-session-expired", 410, "Upload session expired")
+	}
+	return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/upload-session-completing", 409, "Upload session is being completed")
+}
+
+// releaseUploadSession gives up the claim on an upload session that could not
+// be completed, so the client can try again.
+func releaseUploadSession(sessionID primitive.ObjectID) {
+	if err := models.FindOneAndApply(context.Background(), database.Mongo().Db(), models.UploadSession{}, bson.M{
+		"_id":         sessionID,
+		"completedAt": nil,
+	}, bson.M{"$unset": bson.M{"completingAt": ""}}).Err(); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("Error releasing upload session %s: %v", sessionID.Hex(), err)
+	}
+}
+
 func (profileService *MediaService) CompleteMultipartUpload(ctx context.Context, mediaUploadData mediaServiceTypes.CompleteMultipartUploadType) (*mediaServiceTypes.CompleteMultipartUploadResType, error) {
-	pathSplits := strings.Split(mediaUploadData.URL, "/")
-	mimeType := pathSplits[len(pathSplits)-3]
-	contentType := pathSplits[len(pathSplits)-4] + "/" + pathSplits[len(pathSplits)-3]
-	filePath := strings.Join(pathSplits[:len(pathSplits)-1], "/")
-	fileName := strings.Split(pathSplits[len(pathSplits)-1], ".")[0]
+	session, err := claimUploadSession(ctx, mediaUploadData.AuthId, mediaUploadData.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	recorded := false
+	defer func() {
+		if !recorded {
+			releaseUploadSession(session.ID)
+		}
+	}()
+
+	policy, err := mediaPolicyFor(session.Purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := profileService.StorageProvider.CompleteMultipartUpload(session.Bucket, session.UploadID, session.FilePath, session.FileName, session.ContentType, mediaUploadData.Parts)
+	if err != nil {
+		return nil, err
+	}
+	// The declared size was checked when the session was created, the parts
+	// uploaded may still add up to more
+	if err := policy.check(session.ContentType, int(res.FileSize)); err != nil {
+		if deleteErr := profileService.StorageProvider.DeleteFile(session.Bucket, session.FilePath, session.FileName, session.ContentType); deleteErr != nil {
+			log.Printf("Error deleting rejected upload %s: %v", session.Key, deleteErr)
+		}
+		return nil, err
+	}
+
+	media, err := createUploadedMedia(ctx, models.Media{
+		AuthId:      session.AuthId,
+		Bucket:      session.Bucket,
+		ContentType: session.ContentType,
+		Path:        session.FilePath,
+		FileName:    session.FileName,
+		Purpose:     session.Purpose,
+	}, policy, res)
+	if err != nil {
+		return nil, err
+	}
+	// The media is recorded, a retry must not record it again
+	recorded = true
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.UploadSession{}, session.ID, map[string]interface{}{
+		"completedAt": time.Now(),
+		"mediaID":     media.ID,
+	}); err != nil {
+		log.Printf("Error completing upload session %s: %v", session.ID.Hex(), err)
+	}
+	if policy.MaxDuration > 0 {
+		if err := profileService.analyzeAudio(ctx, media, policy); err != nil {
+			return nil, err
+		}
+	}
+	return &mediaServiceTypes.CompleteMultipartUploadResType{
+		URL:        res.URL,
+		ID:         media.ID.Hex(),
+		DurationMs: media.DurationMs,
+		Waveform:   media.Waveform,
+	}, nil
+}
 
-	res, err := profileService.StorageProvider.CompleteMultipartUpload("purely-public-assets", mediaUploadData.UploadID, filePath, fileName, contentType, mediaUploadData.Parts)
+// createUploadedMedia records an original once its upload completed, taking
+// visibility and retention from the policy of its purpose.
+func createUploadedMedia(ctx context.Context, media models.Media, policy *MediaPolicy, res *storage.CompletedMultipartUploadResponseType) (*models.Media, error) {
+	media.ID = primitive.NewObjectID()
+	media.URL = res.URL
+	media.Domain = res.Domain
+	media.Size = int(res.FileSize)
+	media.EXT = constants.FileExtMap[media.ContentType]
+	media.Visibility = policy.Visibility
+	media.ExpiresAt = policy.expiresAt()
+	if _, err := models.Create(ctx, database.Mongo().Db(), media); err != nil {
+		log.Printf("Error creating media entry: %v", err)
+		return nil, err
+	}
+	return &media, nil
+}
+
+// DirectUpload stores a small file posted as multipart/form-data in one
+// request, for clients that can't upload in parts. It is checked against the
+// policy of its purpose like multipart uploads and answers the same way.
+func (mediaService *MediaService) DirectUpload(ctx context.Context, data mediaServiceTypes.DirectUploadType) (*mediaServiceTypes.CompleteMultipartUploadResType, error) {
+	maxSize := config.GetConfig().DirectUploadMaxSize
+	if data.File.Size > int64(maxSize) {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/file-too-large", 413, fmt.Sprintf("Direct uploads are limited to %d bytes, upload in parts instead", maxSize))
+	}
+	policy, err := mediaPolicyFor(data.Purpose)
 	if err != nil {
 		return nil, err
 	}
+	contentType := data.File.Header.Get("Content-Type")
+	if err := policy.check(contentType, int(data.File.Size)); err != nil {
+		return nil, err
+	}
+	release, err := reserveQuota(ctx, data.AuthId, int(data.File.Size), false)
+	if err != nil {
+		return nil, err
+	}
+	// Once the media is recorded it counts by itself
+	defer release()
 
-	media, err := models.Create(ctx, database.Mongo().Db(), models.Media{
-		ID:          primitive.NewObjectID(),
-		URL:         res.URL,
-		EXT:         mimeType,
+	file, err := data.File.Open()
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Could not read the file")
+	}
+	defer file.Close()
+
+	bucket := policy.bucket()
+	filePath := policy.filePath(data.AuthId, data.Purpose, contentType, uuid.New().String())
+	fileName := strings.Split(path.Base(data.File.Filename), ".")[0]
+	hash := sha256.New()
+	res, err := storage.UploadStream(mediaService.StorageProvider, bucket, filePath, fileName, contentType, io.TeeReader(file, hash), int(data.File.Size))
+	if err != nil {
+		log.Printf("Error uploading %s directly: %v", filePath, err)
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/could-not-upload", 500, "Failed to upload file")
+	}
+
+	media, err := createUploadedMedia(ctx, models.Media{
+		AuthId:      data.AuthId,
+		Bucket:      bucket,
 		ContentType: contentType,
 		Path:        filePath,
 		FileName:    fileName,
-		Domain:      res.Domain,
-		Size:        int(res.FileSize),
-	})
+		Purpose:     data.Purpose,
+	}, policy, res)
 	if err != nil {
-		log.Printf("Error creating media entry: %v", err)
 		return nil, err
 	}
+	if policy.MaxDuration > 0 {
+		if err := mediaService.analyzeAudio(ctx, media, policy); err != nil {
+			return nil, err
+		}
+	}
+	completedAt := time.Now()
+	if _, err := models.Create(ctx, database.Mongo().Db(), models.UploadSession{
+		AuthId:      data.AuthId,
+		Bucket:      bucket,
+		FilePath:    filePath,
+		FileName:    fileName,
+		Key:         filePath + "/" + fileName + "." + constants.FileExtMap[contentType],
+		ContentType: contentType,
+		FileSize:    media.Size,
+		Purpose:     data.Purpose,
+		ExpiresAt:   completedAt,
+		CompletedAt: &completedAt,
+		MediaID:     media.ID,
+	}); err != nil {
+		log.Printf("Error recording direct upload %s: %v", media.ID.Hex(), err)
+	}
+	// The bytes went through here, identical uploads are shared right away
+	if registered, err := mediaService.registerOriginal(ctx, *media, hex.EncodeToString(hash.Sum(nil))); err != nil {
+		log.Printf("Error registering original %s: %v", media.ID.Hex(), err)
+	} else {
+		media = &registered
+	}
 	return &mediaServiceTypes.CompleteMultipartUploadResType{
-		URL: res.URL,
-		ID:  media.InsertedID.(primitive.ObjectID).Hex(),
+		URL:        media.URL,
+		ID:         media.ID.Hex(),
+		DurationMs: media.DurationMs,
+		Waveform:   media.Waveform,
 	}, nil
 }
 
+// bucketOf returns the bucket a media is stored in. Media created before
+// originals moved to the private bucket don't record it.
+func bucketOf(media models.Media) string {
+	if media.Bucket != "" {
+		return media.Bucket
+	}
+	return config.GetConfig().Storage.Bucket
+}
+
+// downloadURL returns a URL the service can fetch the media from, private
+// media are only reachable through a short lived signed URL.
+func (mediaService *MediaService) downloadURL(media models.Media) (string, error) {
+	if media.Visibility != models.MediaVisibilityPrivate {
+		return media.URL, nil
+	}
+	signedUrl, err := mediaService.StorageProvider.GenerateSignedUrl(bucketOf(media), media.Path, media.FileName, media.ContentType, media.Size)
+	if err != nil {
+		return "", err
+	}
+	return signedUrl.SignedUrl, nil
+}
+
+// GenerateSignedDownloadUrls issues short lived download URLs for private
+// media. It is only reachable internally, profiles decides which viewers
+// are entitled to see the originals before asking for them. With a viewer
+// profile ID the originals of other users are watermarked for that viewer.
+func (mediaService *MediaService) GenerateSignedDownloadUrls(ctx context.Context, data mediaServiceTypes.GenerateSignedDownloadUrlsType) (*mediaServiceTypes.GenerateSignedDownloadUrlsResType, error) {
+	viewerProfileID := primitive.NilObjectID
+	if data.ViewerProfileID != "" {
+		var err error
+		viewerProfileID, err = primitive.ObjectIDFromHex(data.ViewerProfileID)
+		if err != nil {
+			return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-profile-id", 400, "Invalid viewer profile ID")
+		}
+	}
+	mediaIDs := []primitive.ObjectID{}
+	for _, mediaID := range data.MediaIDs {
+		mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+		if err != nil {
+			return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Invalid media ID")
+		}
+		mediaIDs = append(mediaIDs, mediaObjectID)
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": mediaIDs}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var mediaList []models.Media
+	if err := cursor.All(ctx, &mediaList); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Issuing signed download URLs for %d media to viewer %s", len(mediaList), data.ViewerAuthId)
+	urls := map[string]mediaServiceTypes.SignedDownloadUrlType{}
+	for _, item := range mediaList {
+		if item.Purpose == constants.MediaPurposeVerification && item.AuthId != data.ViewerAuthId {
+			continue
+		}
+		// Other viewers get a rendition marked with their profile ID, never
+		// the unmarked original. Audio can't carry a visible mark.
+		media := item
+		if viewerProfileID != primitive.NilObjectID && item.AuthId != data.ViewerAuthId && item.Variant == "" && item.Visibility == models.MediaVisibilityPrivate && strings.HasPrefix(item.ContentType, "image/") {
+			rendition, err := mediaService.watermarkedRendition(ctx, item, viewerProfileID)
+			if err != nil {
+				log.Printf("Error creating watermarked rendition of %s: %v", item.ID.Hex(), err)
+				continue
+			}
+			media = *rendition
+		}
+		if media.Visibility != models.MediaVisibilityPrivate {
+			urls[item.ID.Hex()] = mediaServiceTypes.SignedDownloadUrlType{URL: media.URL}
+			continue
+		}
+		signedUrl, err := mediaService.StorageProvider.GenerateSignedUrl(bucketOf(media), media.Path, media.FileName, media.ContentType, media.Size)
+		if err != nil {
+			log.Printf("Error signing download URL for %s: %v", media.ID.Hex(), err)
+			return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/could-not-generate-signed-url", 500, "Failed to generate signed URL")
+		}
+		urls[item.ID.Hex()] = mediaServiceTypes.SignedDownloadUrlType{
+			URL:    signedUrl.SignedUrl,
+			Expiry: signedUrl.Expires.Unix(),
+		}
+	}
+	return &mediaServiceTypes.GenerateSignedDownloadUrlsResType{URLs: urls}, nil
+}
+
 func (i *MediaService) HandlePubSubMessage(ctx context.Context, data PubSub.PubSubMessageType) bool {
 	fmt.Println("handlePubSubMessage data", data)
 	fmt.Println("handlePubSubMessage data type", data.Type)
@@ -260,10 +626,79 @@ func (i *MediaService) HandlePubSubMessage(ctx context.Context, data PubSub.PubS
 	case "blurImage":
 		{
 			fmt.Println("handlePubSubMessage blurImage")
-			profileID := data.Data["profileID"].(string)
-			_, err := i.BlurImage(ctx, data.Data["mediaID"].(string), &profileID)
+			// Processing happens in the worker pool, the push is acknowledged
+			// as soon as the job is stored
+			profileID, _ := data.Data["profileID"].(string)
+			mediaID, _ := data.Data["mediaID"].(string)
+			if err := i.EnqueueMediaJob(ctx, models.MediaJobTypeBlurImage, mediaID, profileID); err != nil {
+				log.Printf("Error enqueueing media job: %v", err)
+				return false
+			}
+			return true
+		}
+	// The owner picked where photos are cropped on their profile, no focal
+	// point goes back to the detected one
+	case "focalPointChanged":
+		{
+			authId, _ := data.Data["authId"].(string)
+			mediaID, _ := data.Data["mediaID"].(string)
+			var focalPoint *models.FocalPoint
+			if point, ok := data.Data["focalPoint"].(map[string]interface{}); ok {
+				x, _ := point["x"].(float64)
+				y, _ := point["y"].(float64)
+				focalPoint = &models.FocalPoint{X: x, Y: y}
+			}
+			if err := i.SetFocalPoint(ctx, authId, mediaID, focalPoint); err != nil {
+				log.Printf("Error setting focal point of %s: %v", mediaID, err)
+				var httpErr *httpErrors.HttpError
+				// Bad requests won't get better on redelivery
+				return errors.As(err, &httpErr) && httpErr.StatusCode < 500
+			}
+			return true
+		}
+	// Data exports start in auth, profiles adds its part before they get here
+	case "exportAccount":
+		{
+			exportID, _ := data.Data["exportID"].(string)
+			authId, _ := data.Data["authId"].(string)
+			if authId == "" {
+				return true
+			}
+			sections := map[string]interface{}{
+				"auth":     data.Data["auth"],
+				"profiles": data.Data["profiles"],
+			}
+			if err := i.QueueAccountExport(ctx, exportID, authId, sections); err != nil {
+				log.Printf("Error queueing export %s: %v", exportID, err)
+				var httpErr *httpErrors.HttpError
+				return errors.As(err, &httpErr) && httpErr.StatusCode < 500
+			}
+			return true
+		}
+	// Part of the account deletion saga run by auth, redelivered until acknowledged
+	case "deleteAccount":
+		{
+			authId, _ := data.Data["authId"].(string)
+			sagaID, _ := data.Data["sagaID"].(string)
+			if authId == "" {
+				return true
+			}
+			// Only deletions auth is running are carried out
+			saga, err := authHelpers.GetDeletionSaga(ctx, authId)
 			if err != nil {
-				log.Printf("Error handling pubsub message: %v", err)
+				log.Printf("Error checking deletion %s: %v", sagaID, err)
+				return false
+			}
+			if saga == nil || saga.ID != sagaID || saga.Status != authHelpers.DeletionSagaStatusInProgress {
+				log.Printf("Ignoring deletion %s of %s, not running in auth", sagaID, authId)
+				return true
+			}
+			if err := i.DeleteAccountMedia(ctx, authId); err != nil {
+				log.Printf("Error deleting media of %s: %v", authId, err)
+				return false
+			}
+			if err := acknowledgeAccountDeletion(ctx, sagaID, authId); err != nil {
+				log.Printf("Error acknowledging deletion %s: %v", sagaID, err)
 				return false
 			}
 			return true
@@ -271,3 +706,22 @@ func (i *MediaService) HandlePubSubMessage(ctx context.Context, data PubSub.PubS
 	}
 	return true
 }
+
+// imagePlaceholders are what clients render before an image loads.
+type imagePlaceholders struct {
+	BlurHash      string
+	DominantColor string
+}
+
+// placeholdersOf computes the BlurHash and dominant color of an image, as
+// stored on its media.
+func placeholdersOf(img image.Image) imagePlaceholders {
+	blurHash, err := mediahelpers.BlurHash(img, mediahelpers.BlurHashXComponents, mediahelpers.BlurHashYComponents)
+	if err != nil {
+		log.Printf("Error computing blurhash: %v", err)
+	}
+	return imagePlaceholders{
+		BlurHash:      blurHash,
+		DominantColor: mediahelpers.DominantColor(img),
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"media/internal/database"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestClaimUploadSessionOnlyOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("double submit", func(mt *mtest.T) {
+		database.Use(mt.DB)
+		sessionID := primitive.NewObjectID()
+		session := bson.D{
+			{Key: "_id", Value: sessionID},
+			{Key: "authId", Value: "user"},
+			{Key: "expiresAt", Value: time.Now().Add(time.Hour)},
+			{Key: "completingAt", Value: time.Now()},
+		}
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: session}),
+			// The second submit finds the session claimed
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".uploadSessions", mtest.FirstBatch, session),
+		)
+
+		claimed, err := claimUploadSession(context.Background(), "user", sessionID.Hex())
+		if err != nil || claimed.ID != sessionID {
+			mt.Fatalf("expected the first submit to claim the session, got %v", err)
+		}
+		_, err = claimUploadSession(context.Background(), "user", sessionID.Hex())
+		var httpErr *httpErrors.HttpError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != 409 {
+			mt.Fatalf("expected the second submit to be refused with a 409, got %v", err)
+		}
+	})
+}
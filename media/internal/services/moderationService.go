@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"image"
+	"log"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/types/mediaServiceTypes"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"media/providers/moderation"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const moderationQueuePageSize = 20
+
+var moderationStatuses = map[string]string{
+	moderation.VerdictApprove: models.MediaModerationApproved,
+	moderation.VerdictReview:  models.MediaModerationReview,
+	moderation.VerdictReject:  models.MediaModerationRejected,
+}
+
+var errMediaQuarantined = httpErrors.HydrateHttpError("purely/media/requests/errors/media-quarantined", 422, "Photo is held for moderation")
+
+func isQuarantined(moderationStatus string) bool {
+	return moderationStatus == models.MediaModerationReview || moderationStatus == models.MediaModerationRejected
+}
+
+// ModerateMedia runs an original through the moderator and stores the
+// verdict. Without a moderator every photo is approved.
+func (mediaService *MediaService) ModerateMedia(ctx context.Context, media models.Media, img image.Image) (string, error) {
+	update := map[string]interface{}{
+		"moderationStatus": models.MediaModerationApproved,
+	}
+	if mediaService.Moderator != nil {
+		result, err := mediaService.Moderator.Moderate(ctx, img)
+		if err != nil {
+			return "", err
+		}
+		status, ok := moderationStatuses[result.Verdict]
+		if !ok {
+			status = models.MediaModerationReview
+		}
+		update["moderationStatus"] = status
+		update["moderationLabels"] = result.Labels
+		update["moderationProvider"] = result.Provider
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, update); err != nil {
+		return "", err
+	}
+	return update["moderationStatus"].(string), nil
+}
+
+// BackfillModerationStatus approves the media processed before photos were
+// moderated, profiles only show approved media. Originals with variants went
+// through processing, those without are left to it. Variants take the status
+// of their original.
+func (mediaService *MediaService) BackfillModerationStatus(ctx context.Context) error {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"moderationStatus": bson.M{"$exists": false},
+			"variant":          bson.M{"$exists": false},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "media",
+			"localField":   "_id",
+			"foreignField": "sourceID",
+			"as":           "variants",
+		}}},
+		{{Key: "$match", Value: bson.M{"variants.0": bson.M{"$exists": true}}}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	var processed []models.Media
+	err = cursor.All(ctx, &processed)
+	cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+	processedIDs := make([]primitive.ObjectID, len(processed))
+	for i, media := range processed {
+		processedIDs[i] = media.ID
+	}
+	if len(processedIDs) > 0 {
+		if _, err := models.UpdateMany(ctx, database.Mongo().Db(), models.Media{}, bson.M{
+			"_id":              bson.M{"$in": processedIDs},
+			"moderationStatus": bson.M{"$exists": false},
+		}, map[string]interface{}{
+			"moderationStatus": models.MediaModerationApproved,
+		}); err != nil {
+			return err
+		}
+	}
+
+	cursor, err = models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"moderationStatus": bson.M{"$exists": false},
+			"variant":          bson.M{"$exists": true},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "media",
+			"localField":   "sourceID",
+			"foreignField": "_id",
+			"as":           "source",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"$first": "$source.moderationStatus"},
+			"mediaIDs": bson.M{"$push": "$_id"},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	var variantsByStatus []struct {
+		Status   string               `bson:"_id"`
+		MediaIDs []primitive.ObjectID `bson:"mediaIDs"`
+	}
+	err = cursor.All(ctx, &variantsByStatus)
+	cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+	for _, variants := range variantsByStatus {
+		if variants.Status == "" {
+			continue
+		}
+		if _, err := models.UpdateMany(ctx, database.Mongo().Db(), models.Media{}, bson.M{
+			"_id": bson.M{"$in": variants.MediaIDs},
+		}, map[string]interface{}{
+			"moderationStatus": variants.Status,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetModerationQueue lists the quarantined originals with a given status,
+// oldest first, with short lived URLs for moderators to look at them.
+func (mediaService *MediaService) GetModerationQueue(ctx context.Context, data mediaServiceTypes.GetModerationQueueType) (*mediaServiceTypes.ModerationQueueResType, error) {
+	status := data.Status
+	if status == "" {
+		status = models.MediaModerationReview
+	}
+	if !isQuarantined(status) {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-moderation-status", 400, "Invalid moderation status")
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"moderationStatus": status,
+			"variant":          bson.M{"$exists": false},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"media": bson.A{
+				bson.M{"$sort": bson.M{"_id": 1}},
+				bson.M{"$skip": data.Page * moderationQueuePageSize},
+				bson.M{"$limit": moderationQueuePageSize},
+			},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var pages []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Media []models.Media `bson:"media"`
+	}
+	if err := cursor.All(ctx, &pages); err != nil {
+		return nil, err
+	}
+
+	queue := &mediaServiceTypes.ModerationQueueResType{
+		Media: []mediaServiceTypes.ModerationQueueItemType{},
+	}
+	if len(pages) == 0 {
+		return queue, nil
+	}
+	if len(pages[0].Total) > 0 {
+		queue.Total = pages[0].Total[0].Count
+	}
+	for _, media := range pages[0].Media {
+		url, err := mediaService.downloadURL(media)
+		if err != nil {
+			log.Printf("Error signing moderation URL for %s: %v", media.ID.Hex(), err)
+		}
+		queue.Media = append(queue.Media, mediaServiceTypes.ModerationQueueItemType{
+			MediaID:          media.ID.Hex(),
+			AuthId:           media.AuthId,
+			Purpose:          media.Purpose,
+			URL:              url,
+			ModerationStatus: media.ModerationStatus,
+			ModerationLabels: media.ModerationLabels,
+		})
+	}
+	return queue, nil
+}
+
+// OverrideModerationVerdict records the verdict of a moderator on an original
+// and its variants. Approved photos go back into processing.
+func (mediaService *MediaService) OverrideModerationVerdict(ctx context.Context, data mediaServiceTypes.OverrideModerationVerdictType) (string, error) {
+	status, ok := moderationStatuses[data.Verdict]
+	if !ok {
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-verdict", 400, "Invalid verdict")
+	}
+	if data.ModeratorID == "" {
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-data", 400, "Moderator is required")
+	}
+	mediaObjectID, err := primitive.ObjectIDFromHex(data.MediaID)
+	if err != nil {
+		return "", httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Invalid media ID")
+	}
+	var media models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+		ID: mediaObjectID,
+	}).Decode(&media); err != nil || media.Variant != "" {
+		return "", httpErrors.HydrateHttpError("purely/media/notFound", 404, "Not found")
+	}
+
+	_, err = models.UpdateMany(ctx, database.Mongo().Db(), models.Media{}, bson.M{
+		"$or": bson.A{
+			bson.M{"_id": media.ID},
+			bson.M{"sourceID": media.ID},
+		},
+	}, map[string]interface{}{
+		"moderationStatus": status,
+		"moderatedBy":      data.ModeratorID,
+		"moderatedAt":      time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if isQuarantined(media.ModerationStatus) && status == models.MediaModerationApproved {
+		profileID := ""
+		if job, err := lastMediaJob(ctx, media.ID); err == nil && job != nil {
+			profileID = job.ProfileID
+		}
+		if err := mediaService.EnqueueMediaJob(ctx, models.MediaJobTypeBlurImage, media.ID.Hex(), profileID); err != nil {
+			log.Printf("Error enqueueing approved media %s: %v", media.ID.Hex(), err)
+		}
+	}
+	return "Verdict updated", nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"media/internal/config"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/providers/storage"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Objects are only collected once they were left alone for this long, so an
+// object stored for a media that is still being created is not taken away.
+const storedObjectGracePeriod = time.Hour
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func objectAddress(bucket string, filePath string, fileName string) string {
+	return bucket + "/" + filePath + "/" + fileName
+}
+
+// contentAddress returns the path and file name derived assets with the given
+// SHA-256 are stored under.
+func contentAddress(sha string) (string, string) {
+	return "objects/" + sha[:2], sha
+}
+
+// retainObject adds a reference to the object with the given bytes in bucket,
+// nil when there is none yet.
+func retainObject(ctx context.Context, bucket string, sha string) (*models.StoredObject, error) {
+	var object models.StoredObject
+	err := models.FindOneAndIncrement(ctx, database.Mongo().Db(), models.StoredObject{}, bson.M{
+		"bucket":   bucket,
+		"sha256":   sha,
+		"refCount": bson.M{"$gt": 0},
+	}, map[string]interface{}{"refCount": 1}).Decode(&object)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// storeObject stores data under its content address and returns the object
+// with a reference taken for the caller. Identical bytes are uploaded once.
+func (mediaService *MediaService) storeObject(ctx context.Context, bucket string, contentType string, data []byte) (*models.StoredObject, error) {
+	sha := sha256Hex(data)
+	if object, err := retainObject(ctx, bucket, sha); err != nil || object != nil {
+		return object, err
+	}
+
+	filePath, fileName := contentAddress(sha)
+	uploadRes, err := storage.UploadObject(mediaService.StorageProvider, bucket, filePath, fileName, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	object := models.StoredObject{
+		Address:     objectAddress(bucket, filePath, fileName),
+		Bucket:      bucket,
+		Path:        filePath,
+		FileName:    fileName,
+		ContentType: contentType,
+		SHA256:      sha,
+		Size:        len(data),
+		URL:         uploadRes.URL,
+		Domain:      uploadRes.Domain,
+		RefCount:    1,
+	}
+	if _, err := models.Create(ctx, database.Mongo().Db(), object); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+		// Stored concurrently with the same bytes, share that record
+		if err := models.FindOneAndIncrement(ctx, database.Mongo().Db(), models.StoredObject{}, bson.M{
+			"address": object.Address,
+		}, map[string]interface{}{"refCount": 1}).Decode(&object); err != nil {
+			return nil, err
+		}
+	}
+	return &object, nil
+}
+
+// registerOriginal records the SHA-256 of an uploaded original. When a media
+// with the same bytes is already stored the original is pointed at that
+// object and the new upload is deleted.
+func (mediaService *MediaService) registerOriginal(ctx context.Context, media models.Media, sha string) (models.Media, error) {
+	if media.SHA256 != "" {
+		return media, nil
+	}
+	bucket := bucketOf(media)
+	address := objectAddress(bucket, media.Path, media.FileName)
+	existing, err := retainObject(ctx, bucket, sha)
+	if err != nil {
+		return media, err
+	}
+	if existing != nil && existing.Address == address {
+		// Registered before, undo the reference just taken
+		if err := releaseReference(ctx, existing.Address); err != nil {
+			return media, err
+		}
+		existing = nil
+	}
+
+	update := map[string]interface{}{"sha256": sha}
+	if existing != nil {
+		if err := mediaService.StorageProvider.DeleteFile(bucket, media.Path, media.FileName, media.ContentType); err != nil {
+			log.Printf("Error deleting duplicate upload of %s: %v", media.ID.Hex(), err)
+		}
+		update["path"] = existing.Path
+		update["fileName"] = existing.FileName
+		update["url"] = existing.URL
+		update["domain"] = existing.Domain
+		media.Path, media.FileName, media.URL, media.Domain = existing.Path, existing.FileName, existing.URL, existing.Domain
+	} else if _, err := models.Create(ctx, database.Mongo().Db(), models.StoredObject{
+		Address:     address,
+		Bucket:      bucket,
+		Path:        media.Path,
+		FileName:    media.FileName,
+		ContentType: media.ContentType,
+		SHA256:      sha,
+		Size:        media.Size,
+		URL:         media.URL,
+		Domain:      media.Domain,
+		RefCount:    1,
+	}); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return media, err
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, update); err != nil {
+		return media, err
+	}
+	media.SHA256 = sha
+	return media, nil
+}
+
+func releaseReference(ctx context.Context, address string) error {
+	return models.FindOneAndIncrement(ctx, database.Mongo().Db(), models.StoredObject{}, bson.M{
+		"address": address,
+	}, map[string]interface{}{"refCount": -1}).Err()
+}
+
+// releaseObject drops the reference a media holds on its object and deletes
+// the object once nothing points at it. Media stored before objects were
+// counted own their object alone.
+func (mediaService *MediaService) releaseObject(ctx context.Context, media models.Media) error {
+	bucket := bucketOf(media)
+	var object models.StoredObject
+	err := models.FindOneAndIncrement(ctx, database.Mongo().Db(), models.StoredObject{}, bson.M{
+		"address": objectAddress(bucket, media.Path, media.FileName),
+	}, map[string]interface{}{"refCount": -1}).Decode(&object)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return mediaService.StorageProvider.DeleteFile(bucket, media.Path, media.FileName, media.ContentType)
+	}
+	if err != nil {
+		return err
+	}
+	if object.RefCount > 0 {
+		return nil
+	}
+	return mediaService.deleteStoredObject(ctx, object, bson.M{"refCount": bson.M{"$lte": 0}})
+}
+
+// deleteStoredObject deletes an object nothing points at. The record is
+// claimed first with filter, which must still match it, and the file is only
+// deleted if the claim succeeded. An object taken again by a concurrent upload
+// of the same bytes is left alone.
+func (mediaService *MediaService) deleteStoredObject(ctx context.Context, object models.StoredObject, filter bson.M) error {
+	filter["_id"] = object.ID
+	result, err := models.DeleteOne(ctx, database.Mongo().Db(), models.StoredObject{}, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return nil
+	}
+	return mediaService.StorageProvider.DeleteFile(object.Bucket, object.Path, object.FileName, object.ContentType)
+}
+
+// CollectStoredObjects recounts the media pointing at every object that was
+// not touched for a while, fixing counts left wrong by interrupted work, and
+// deletes the objects nothing points at anymore. Media stored before buckets
+// were recorded are in the default bucket.
+func (mediaService *MediaService) CollectStoredObjects(ctx context.Context) error {
+	untouchedSince := time.Now().Add(-storedObjectGracePeriod)
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.StoredObject{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"updatedAt": bson.M{"$lt": untouchedSince}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "media",
+			"let":  bson.M{"path": "$path", "fileName": "$fileName", "bucket": "$bucket"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$path", "$$path"}},
+					bson.M{"$eq": bson.A{"$fileName", "$$fileName"}},
+					bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$bucket", config.GetConfig().Storage.Bucket}}, "$$bucket"}},
+				}}}},
+				bson.M{"$count": "count"},
+			},
+			"as": "references",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"references": bson.M{"$ifNull": bson.A{bson.M{"$first": "$references.count"}, 0}}}}},
+		{{Key: "$match", Value: bson.M{"$expr": bson.M{"$ne": bson.A{"$references", "$refCount"}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	var objects []struct {
+		models.StoredObject `bson:",inline"`
+		References          int `bson:"references"`
+	}
+	if err := cursor.All(ctx, &objects); err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if object.References == 0 {
+			// Only while nobody took or released it since it was counted
+			if err := mediaService.deleteStoredObject(ctx, object.StoredObject, bson.M{
+				"refCount":  object.RefCount,
+				"updatedAt": bson.M{"$lt": untouchedSince},
+			}); err != nil {
+				log.Printf("Error collecting stored object %s: %v", object.Address, err)
+			}
+			continue
+		}
+		if _, err := models.UpdateOne(ctx, database.Mongo().Db(), models.StoredObject{}, bson.M{
+			"_id":       object.ID,
+			"refCount":  object.RefCount,
+			"updatedAt": bson.M{"$lt": untouchedSince},
+		}, map[string]interface{}{
+			"refCount": object.References,
+		}); err != nil {
+			log.Printf("Error recounting stored object %s: %v", object.Address, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"image"
+	"media/internal/database"
+	"media/internal/database/models"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+)
+
+// Quality issues, used both as warnings and as rejection reasons.
+const (
+	MediaQualityTooBlurry      = "tooBlurry"
+	MediaQualityTooDark        = "tooDark"
+	MediaQualityTooBright      = "tooBright"
+	MediaQualityOverexposed    = "clipped"
+	MediaQualityTooSmall       = "tooSmall"
+	MediaQualityBadAspectRatio = "badAspectRatio"
+)
+
+// QualityLimits bound the quality metrics of a photo, zero values are not
+// checked.
+type QualityLimits struct {
+	MinSharpness   float64
+	MinBrightness  float64
+	MaxBrightness  float64
+	MaxClipped     float64
+	MinShortSide   int
+	MaxAspectRatio float64
+}
+
+// QualityThresholds of a purpose: photos past Reject are rejected, photos
+// past Warn are kept with a warning.
+type QualityThresholds struct {
+	Warn   QualityLimits
+	Reject QualityLimits
+}
+
+var profilePhotoQuality = &QualityThresholds{
+	Warn: QualityLimits{
+		MinSharpness:   50,
+		MinBrightness:  50,
+		MaxBrightness:  210,
+		MaxClipped:     0.3,
+		MinShortSide:   600,
+		MaxAspectRatio: 2,
+	},
+	Reject: QualityLimits{
+		MinSharpness:   10,
+		MinBrightness:  25,
+		MaxBrightness:  235,
+		MaxClipped:     0.7,
+		MinShortSide:   320,
+		MaxAspectRatio: 3,
+	},
+}
+
+// issues returns the quality issues of metrics outside the limits, the most
+// telling first.
+func (limits QualityLimits) issues(metrics mediahelpers.QualityMetrics) []string {
+	issues := []string{}
+	if limits.MinShortSide > 0 && min(metrics.Width, metrics.Height) < limits.MinShortSide {
+		issues = append(issues, MediaQualityTooSmall)
+	}
+	if limits.MaxAspectRatio > 0 && metrics.AspectRatio > limits.MaxAspectRatio {
+		issues = append(issues, MediaQualityBadAspectRatio)
+	}
+	if limits.MinBrightness > 0 && metrics.Brightness < limits.MinBrightness {
+		issues = append(issues, MediaQualityTooDark)
+	}
+	if limits.MaxBrightness > 0 && metrics.Brightness > limits.MaxBrightness {
+		issues = append(issues, MediaQualityTooBright)
+	}
+	if limits.MaxClipped > 0 && metrics.Clipped > limits.MaxClipped {
+		issues = append(issues, MediaQualityOverexposed)
+	}
+	if limits.MinSharpness > 0 && metrics.Sharpness < limits.MinSharpness {
+		issues = append(issues, MediaQualityTooBlurry)
+	}
+	return issues
+}
+
+// CheckQuality measures an original, stores the metrics with the warnings
+// raised by the thresholds and returns the reason the photo is rejected, if
+// any. Profiles is told so it can weigh the photo in the profile completion.
+func (mediaService *MediaService) CheckQuality(ctx context.Context, media models.Media, img image.Image, thresholds QualityThresholds) (string, error) {
+	metrics := mediahelpers.MeasureQuality(img)
+	quality := models.MediaQuality{
+		Sharpness:   metrics.Sharpness,
+		Brightness:  metrics.Brightness,
+		Clipped:     metrics.Clipped,
+		Width:       metrics.Width,
+		Height:      metrics.Height,
+		AspectRatio: metrics.AspectRatio,
+		Warnings:    thresholds.Warn.issues(metrics),
+	}
+	update := map[string]interface{}{"quality": quality}
+	rejectedReason := ""
+	if rejections := thresholds.Reject.issues(metrics); len(rejections) > 0 {
+		rejectedReason = rejections[0]
+		update["rejectedReason"] = rejectedReason
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Media{}, media.ID, update); err != nil {
+		return "", err
+	}
+	if rejectedReason == "" {
+		notifyProfiles(ctx, "mediaQualityChecked", map[string]interface{}{
+			"authId":   media.AuthId,
+			"mediaID":  media.ID.Hex(),
+			"warnings": quality.Warnings,
+		})
+	}
+	return rejectedReason, nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"media/internal/config"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/types/mediaServiceTypes"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Uploads are rate limited over this window.
+	uploadRateWindow = time.Hour
+	// Reservations left behind stop counting after this long, well past the
+	// longest direct upload.
+	quotaReservationTTL = 15 * time.Minute
+	// Reservations retried when others get in first.
+	quotaReserveAttempts = 5
+)
+
+// quotaLimitsOf returns the limits of a user, the defaults from the config
+// with the overrides an admin set for them.
+func quotaLimitsOf(ctx context.Context, authId string) (mediaServiceTypes.QuotaLimitsType, bool, error) {
+	defaults := config.GetConfig().Quota
+	limits := mediaServiceTypes.QuotaLimitsType{
+		MaxBytes:          defaults.MaxBytes,
+		MaxObjects:        defaults.MaxObjects,
+		MaxActiveSessions: defaults.MaxActiveSessions,
+		UploadsPerHour:    defaults.UploadsPerHour,
+	}
+	var quota models.StorageQuota
+	err := models.FindOne(ctx, database.Mongo().Db(), models.StorageQuota{AuthId: authId}).Decode(&quota)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return limits, false, nil
+	}
+	if err != nil {
+		return limits, false, err
+	}
+	if quota.MaxBytes > 0 {
+		limits.MaxBytes = quota.MaxBytes
+	}
+	if quota.MaxObjects > 0 {
+		limits.MaxObjects = quota.MaxObjects
+	}
+	if quota.MaxActiveSessions > 0 {
+		limits.MaxActiveSessions = quota.MaxActiveSessions
+	}
+	if quota.UploadsPerHour > 0 {
+		limits.UploadsPerHour = quota.UploadsPerHour
+	}
+	return limits, true, nil
+}
+
+// quotaUsageOf adds up what a user stores and is uploading. Only originals
+// count, variants are made by the service. Usage is computed from media and
+// upload sessions every time, so it can't drift from what is stored.
+func quotaUsageOf(ctx context.Context, authId string) (mediaServiceTypes.QuotaUsageType, error) {
+	usage := mediaServiceTypes.QuotaUsageType{}
+	mediaCursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"authId": authId, "sourceID": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"bytes":   bson.M{"$sum": "$size"},
+			"objects": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return usage, err
+	}
+	defer mediaCursor.Close(ctx)
+	var stored []struct {
+		Bytes   int64 `bson:"bytes"`
+		Objects int   `bson:"objects"`
+	}
+	if err := mediaCursor.All(ctx, &stored); err != nil {
+		return usage, err
+	}
+	if len(stored) > 0 {
+		usage.Bytes, usage.Objects = stored[0].Bytes, stored[0].Objects
+	}
+
+	now := time.Now()
+	sessionCursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.UploadSession{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"authId": authId}}},
+		{{Key: "$facet", Value: bson.M{
+			"active": bson.A{
+				bson.M{"$match": bson.M{"completedAt": nil, "expiresAt": bson.M{"$gt": now}}},
+				bson.M{"$group": bson.M{"_id": nil, "count": bson.M{"$sum": 1}, "bytes": bson.M{"$sum": "$fileSize"}}},
+			},
+			"recent": bson.A{
+				bson.M{"$match": bson.M{"createdAt": bson.M{"$gte": now.Add(-uploadRateWindow)}}},
+				bson.M{"$count": "count"},
+			},
+		}}},
+	})
+	if err != nil {
+		return usage, err
+	}
+	defer sessionCursor.Close(ctx)
+	var sessions []struct {
+		Active []struct {
+			Count int   `bson:"count"`
+			Bytes int64 `bson:"bytes"`
+		} `bson:"active"`
+		Recent []struct {
+			Count int `bson:"count"`
+		} `bson:"recent"`
+	}
+	if err := sessionCursor.All(ctx, &sessions); err != nil {
+		return usage, err
+	}
+	if len(sessions) > 0 {
+		if len(sessions[0].Active) > 0 {
+			usage.ActiveSessions, usage.PendingBytes = sessions[0].Active[0].Count, sessions[0].Active[0].Bytes
+		}
+		if len(sessions[0].Recent) > 0 {
+			usage.UploadsLastHour = sessions[0].Recent[0].Count
+		}
+	}
+	return usage, nil
+}
+
+// checkQuota rejects an upload of fileSize bytes that would take a user past
+// their quota. multipart is set for uploads that open a session.
+func checkQuota(limits mediaServiceTypes.QuotaLimitsType, usage mediaServiceTypes.QuotaUsageType, fileSize int, multipart bool) error {
+	switch {
+	case usage.UploadsLastHour >= limits.UploadsPerHour:
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/upload-rate-exceeded", 429, fmt.Sprintf("At most %d uploads per hour", limits.UploadsPerHour))
+	case multipart && usage.ActiveSessions >= limits.MaxActiveSessions:
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/quota-sessions-exceeded", 429, fmt.Sprintf("At most %d uploads can be in progress", limits.MaxActiveSessions))
+	case usage.Objects+usage.ActiveSessions >= limits.MaxObjects:
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/quota-objects-exceeded", 403, fmt.Sprintf("At most %d files can be stored", limits.MaxObjects))
+	case usage.Bytes+usage.PendingBytes+int64(fileSize) > limits.MaxBytes:
+		return httpErrors.HydrateHttpError("purely/media/requests/errors/quota-storage-exceeded", 403, fmt.Sprintf("At most %d bytes can be stored", limits.MaxBytes))
+	}
+	return nil
+}
+
+// addReservations counts the uploads that passed the check but aren't
+// recorded yet as if they were. Direct uploads count as stored objects.
+func addReservations(usage mediaServiceTypes.QuotaUsageType, reservations []models.QuotaReservationEntry) mediaServiceTypes.QuotaUsageType {
+	for _, reservation := range reservations {
+		usage.PendingBytes += reservation.Bytes
+		usage.UploadsLastHour++
+		if reservation.Session {
+			usage.ActiveSessions++
+		} else {
+			usage.Objects++
+		}
+	}
+	return usage
+}
+
+// liveReservations drops the reservations that expired.
+func liveReservations(reservations []models.QuotaReservationEntry, now time.Time) []models.QuotaReservationEntry {
+	live := make([]models.QuotaReservationEntry, 0, len(reservations)+1)
+	for _, reservation := range reservations {
+		if reservation.ExpiresAt.After(now) {
+			live = append(live, reservation)
+		}
+	}
+	return live
+}
+
+// reserveQuota checks an upload of fileSize bytes against the quota of a user
+// and holds its share of the quota until release is called, once the upload is
+// recorded as an upload session or media. The reservation is only added if no
+// other one was added or released since usage was read, so parallel uploads
+// can't overrun the quota. multipart is set for uploads that open a session.
+func reserveQuota(ctx context.Context, authId string, fileSize int, multipart bool) (func(), error) {
+	limits, _, err := quotaLimitsOf(ctx, authId)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; attempt < quotaReserveAttempts; attempt++ {
+		// Read before usage: uploads released after this are recorded by then
+		var current models.QuotaReservation
+		err := models.FindOne(ctx, database.Mongo().Db(), models.QuotaReservation{AuthId: authId}).Decode(&current)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		now := time.Now()
+		reservations := liveReservations(current.Reservations, now)
+		usage, err := quotaUsageOf(ctx, authId)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkQuota(limits, addReservations(usage, reservations), fileSize, multipart); err != nil {
+			return nil, err
+		}
+
+		reservation := models.QuotaReservationEntry{
+			ID:        primitive.NewObjectID(),
+			Bytes:     int64(fileSize),
+			Session:   multipart,
+			ExpiresAt: now.Add(quotaReservationTTL),
+		}
+		err = models.FindOneAndApply(ctx, database.Mongo().Db(), models.QuotaReservation{}, bson.M{
+			"authId":  authId,
+			"version": current.Version,
+		}, bson.M{
+			"$set":         bson.M{"reservations": append(reservations, reservation)},
+			"$inc":         bson.M{"version": 1},
+			"$setOnInsert": bson.M{"createdAt": now},
+		}, options.FindOneAndUpdate().SetUpsert(true)).Err()
+		// Another reservation got in first, the document exists with another
+		// version and the upsert hits the unique index
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func() { releaseQuota(authId, reservation.ID) }, nil
+	}
+	return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/quota-busy", 429, "Too many uploads are being started, try again")
+}
+
+// releaseQuota drops a reservation. It runs once the request is done, so it
+// doesn't use the request context.
+func releaseQuota(authId string, reservationID primitive.ObjectID) {
+	if err := models.FindOneAndApply(context.Background(), database.Mongo().Db(), models.QuotaReservation{}, bson.M{
+		"authId": authId,
+	}, bson.M{
+		"$pull": bson.M{"reservations": bson.M{"id": reservationID}},
+		"$inc":  bson.M{"version": 1},
+	}).Err(); err != nil {
+		// It stops counting when it expires
+		log.Printf("Error releasing quota reservation %s of %s: %v", reservationID.Hex(), authId, err)
+	}
+}
+
+// GetStorageQuota reports the usage and limits of a user.
+func (mediaService *MediaService) GetStorageQuota(ctx context.Context, authId string) (*mediaServiceTypes.StorageQuotaResType, error) {
+	if authId == "" {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-auth-id", 400, "Invalid auth ID")
+	}
+	limits, overridden, err := quotaLimitsOf(ctx, authId)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := quotaUsageOf(ctx, authId)
+	if err != nil {
+		return nil, err
+	}
+	return &mediaServiceTypes.StorageQuotaResType{
+		AuthId:     authId,
+		Usage:      usage,
+		Limits:     limits,
+		Overridden: overridden,
+	}, nil
+}
+
+// UpdateStorageQuota replaces the overrides of a user, limits left at 0 go
+// back to the defaults.
+func (mediaService *MediaService) UpdateStorageQuota(ctx context.Context, data mediaServiceTypes.UpdateStorageQuotaType) (*mediaServiceTypes.StorageQuotaResType, error) {
+	if data.AuthId == "" {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-auth-id", 400, "Invalid auth ID")
+	}
+	if data.MaxBytes < 0 || data.MaxObjects < 0 || data.MaxActiveSessions < 0 || data.UploadsPerHour < 0 {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-quota", 400, "Quota limits can't be negative")
+	}
+	if _, err := models.Upsert(ctx, database.Mongo().Db(), bson.M{"authId": data.AuthId}, models.StorageQuota{
+		AuthId:            data.AuthId,
+		MaxBytes:          data.MaxBytes,
+		MaxObjects:        data.MaxObjects,
+		MaxActiveSessions: data.MaxActiveSessions,
+		UploadsPerHour:    data.UploadsPerHour,
+	}); err != nil {
+		return nil, err
+	}
+	return mediaService.GetStorageQuota(ctx, data.AuthId)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"log"
+	"media/internal/config"
+	"media/internal/database"
+	"media/internal/database/models"
+	"media/internal/types/mediaServiceTypes"
+	"media/internal/utils/constants"
+	httpErrors "media/internal/utils/helpers/httpError"
+	"media/internal/utils/helpers/httpHelper"
+	mediahelpers "media/internal/utils/helpers/mediaHelpers"
+
+	"github.com/disintegration/imaging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// watermarkedRendition returns the rendition of an original that carries the
+// viewer's profile ID, made on first use and kept for later reveals.
+func (mediaService *MediaService) watermarkedRendition(ctx context.Context, media models.Media, viewerProfileID primitive.ObjectID) (*models.Media, error) {
+	filter := models.Media{
+		SourceID:        media.ID,
+		Variant:         models.MediaVariantWatermarked,
+		ViewerProfileID: viewerProfileID,
+	}
+	var rendition models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), filter).Decode(&rendition); err == nil {
+		return &rendition, nil
+	}
+
+	downloadURL, err := mediaService.downloadURL(media)
+	if err != nil {
+		return nil, err
+	}
+	_, img, err := httpHelper.DownloadImageFromSignedURL(downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	marked, err := mediahelpers.EmbedWatermark(img, viewerProfileID)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, marked, &jpeg.Options{Quality: 92}); err != nil {
+		return nil, err
+	}
+
+	contentType := "image/jpeg"
+	bucket := config.GetConfig().Storage.PrivateBucket
+	object, err := mediaService.storeObject(ctx, bucket, contentType, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	rendition = models.Media{
+		ID:              primitive.NewObjectID(),
+		URL:             object.URL,
+		EXT:             constants.FileExtMap[contentType],
+		Path:            object.Path,
+		Domain:          object.Domain,
+		ContentType:     contentType,
+		FileName:        object.FileName,
+		Size:            object.Size,
+		SHA256:          object.SHA256,
+		AuthId:          media.AuthId,
+		Bucket:          bucket,
+		Visibility:      models.MediaVisibilityPrivate,
+		SourceID:        media.ID,
+		Variant:         models.MediaVariantWatermarked,
+		ViewerProfileID: viewerProfileID,
+		Width:           marked.Rect.Dx(),
+		Height:          marked.Rect.Dy(),
+		BlurHash:        media.BlurHash,
+		DominantColor:   media.DominantColor,
+
+		ModerationStatus: media.ModerationStatus,
+	}
+	if _, err := models.Create(ctx, database.Mongo().Db(), rendition); err != nil {
+		if releaseErr := mediaService.releaseObject(ctx, rendition); releaseErr != nil {
+			log.Printf("Error releasing rendition of %s: %v", media.ID.Hex(), releaseErr)
+		}
+		return nil, err
+	}
+	return &rendition, nil
+}
+
+// DecodeWatermark reads the viewer profile ID out of a leaked photo. Crops
+// are read as they are. Leaks are also often screenshots at another size, with
+// the ID of the original media they are scaled back to the size of its
+// renditions when the image doesn't carry the mark as it is.
+func (mediaService *MediaService) DecodeWatermark(ctx context.Context, data mediaServiceTypes.DecodeWatermarkType) (*mediaServiceTypes.DecodeWatermarkResType, error) {
+	img, err := imaging.Decode(bytes.NewReader(data.Image))
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-image", 400, "Could not decode image")
+	}
+	var rendition models.Media
+	if data.MediaID != "" {
+		mediaObjectID, err := primitive.ObjectIDFromHex(data.MediaID)
+		if err != nil {
+			return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/invalid-media-id", 400, "Invalid media ID")
+		}
+		if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{
+			SourceID: mediaObjectID,
+			Variant:  models.MediaVariantWatermarked,
+		}).Decode(&rendition); err != nil {
+			return nil, httpErrors.HydrateHttpError("purely/media/notFound", 404, "No watermarked renditions of this media")
+		}
+	}
+
+	payload, confidence, err := mediahelpers.ExtractWatermark(img)
+	if bounds := img.Bounds(); err != nil && data.MediaID != "" && (bounds.Dx() != rendition.Width || bounds.Dy() != rendition.Height) {
+		payload, confidence, err = mediahelpers.ExtractWatermark(imaging.Resize(img, rendition.Width, rendition.Height, imaging.Lanczos))
+	}
+	if err != nil {
+		log.Printf("No watermark found in submitted image (confidence %.2f)", confidence)
+		return nil, httpErrors.HydrateHttpError("purely/media/requests/errors/no-watermark", 422, "No watermark found")
+	}
+	return &mediaServiceTypes.DecodeWatermarkResType{
+		ViewerProfileID: primitive.ObjectID(payload).Hex(),
+		Confidence:      confidence,
+	}, nil
+}
@@ -16,7 +16,10 @@ type GenerateMultipartMediaUploadSignedUrls struct {
 }
 
 type CompleteMultipartUpload struct {
-	UploadID string         `json:"uploadID"`
-	URL      string         `json:"url"`
-	Parts    map[int]string `json:"parts"`
+	SessionID string         `json:"sessionID"`
+	Parts     map[int]string `json:"parts"`
+}
+
+type ReplaceMediaType struct {
+	MediaID *string `json:"mediaID"`
 }
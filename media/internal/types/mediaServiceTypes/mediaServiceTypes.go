@@ -1,5 +1,7 @@
 package mediaServiceTypes
 
+import "mime/multipart"
+
 type GenerateMediaUploadSignedUrlType struct {
 	FileName    string `json:"fileName"`
 	ContentType string `json:"contentType"`
@@ -23,6 +25,7 @@ type GenerateMultipartUploadUrlsType struct {
 }
 
 type GenerateMultipartUploadUrlsResType struct {
+	SessionID  string         `json:"sessionID"`
 	SignedUrls map[int]string `json:"signedUrls"`
 	Expiry     int64          `json:"expiry"`
 	UploadID   string         `json:"uploadID"`
@@ -32,12 +35,122 @@ type GenerateMultipartUploadUrlsResType struct {
 }
 
 type CompleteMultipartUploadType struct {
-	UploadID string         `json:"uploadID"`
-	URL      string         `json:"url"`
-	Parts    map[int]string `json:"parts"`
+	AuthId    string         `json:"authId"`
+	SessionID string         `json:"sessionID"`
+	Parts     map[int]string `json:"parts"`
+}
+
+type DirectUploadType struct {
+	AuthId  string
+	Purpose string
+	File    *multipart.FileHeader
 }
 
 type CompleteMultipartUploadResType struct {
-	URL string `json:"url"`
-	ID  string `json:"id"`
+	URL        string `json:"url"`
+	ID         string `json:"id"`
+	DurationMs int    `json:"durationMs,omitempty"`
+	Waveform   []int  `json:"waveform,omitempty"`
+}
+
+type GenerateSignedDownloadUrlsType struct {
+	ViewerAuthId    string   `json:"viewerAuthId"`
+	ViewerProfileID string   `json:"viewerProfileID"`
+	MediaIDs        []string `json:"mediaIDs"`
+}
+
+type SignedDownloadUrlType struct {
+	URL    string `json:"url"`
+	Expiry int64  `json:"expiry,omitempty"`
+}
+
+type GenerateSignedDownloadUrlsResType struct {
+	URLs map[string]SignedDownloadUrlType `json:"urls"`
+}
+
+type DeleteMediaType struct {
+	AuthId  string `json:"authId"`
+	MediaID string `json:"mediaID"`
+}
+
+type ReplaceMediaType struct {
+	AuthId     string `json:"authId"`
+	MediaID    string `json:"mediaID"`
+	NewMediaID string `json:"newMediaID"`
+}
+
+type GetMediaStatusType struct {
+	AuthId  string `json:"authId"`
+	MediaID string `json:"mediaID"`
+}
+
+type MediaStatusResType struct {
+	MediaID        string   `json:"mediaID"`
+	Status         string   `json:"status"`
+	Attempts       int      `json:"attempts,omitempty"`
+	LastError      string   `json:"lastError,omitempty"`
+	RejectedReason string   `json:"rejectedReason,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+type GetModerationQueueType struct {
+	Status string `json:"status"`
+	Page   int64  `json:"page"`
+}
+
+type ModerationQueueItemType struct {
+	MediaID          string             `json:"mediaID"`
+	AuthId           string             `json:"authId"`
+	Purpose          string             `json:"purpose,omitempty"`
+	URL              string             `json:"url"`
+	ModerationStatus string             `json:"moderationStatus"`
+	ModerationLabels map[string]float64 `json:"moderationLabels,omitempty"`
+}
+
+type ModerationQueueResType struct {
+	Media []ModerationQueueItemType `json:"media"`
+	Total int64                     `json:"total"`
+}
+
+type OverrideModerationVerdictType struct {
+	MediaID     string `json:"mediaID"`
+	Verdict     string `json:"verdict"`
+	ModeratorID string `json:"moderatorID"`
+}
+
+type DecodeWatermarkType struct {
+	Image   []byte `json:"-"`
+	MediaID string `json:"mediaID"`
+}
+
+type DecodeWatermarkResType struct {
+	ViewerProfileID string  `json:"viewerProfileID"`
+	Confidence      float64 `json:"confidence"`
+}
+
+type QuotaLimitsType struct {
+	MaxBytes          int64 `json:"maxBytes"`
+	MaxObjects        int   `json:"maxObjects"`
+	MaxActiveSessions int   `json:"maxActiveSessions"`
+	UploadsPerHour    int   `json:"uploadsPerHour"`
+}
+
+type QuotaUsageType struct {
+	Bytes           int64 `json:"bytes"`
+	Objects         int   `json:"objects"`
+	ActiveSessions  int   `json:"activeSessions"`
+	PendingBytes    int64 `json:"pendingBytes"`
+	UploadsLastHour int   `json:"uploadsLastHour"`
+}
+
+type StorageQuotaResType struct {
+	AuthId     string          `json:"authId"`
+	Usage      QuotaUsageType  `json:"usage"`
+	Limits     QuotaLimitsType `json:"limits"`
+	Overridden bool            `json:"overridden"`
+}
+
+type UpdateStorageQuotaType struct {
+	AuthId string `json:"authId"`
+	QuotaLimitsType
 }
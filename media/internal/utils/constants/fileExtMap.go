@@ -3,4 +3,10 @@ package constants
 var FileExtMap = map[string]string{
 	"image/jpeg": "jpg",
 	"image/png":  "png",
+	"audio/mpeg": "mp3",
+	"audio/mp4":  "m4a",
+	"audio/aac":  "aac",
+	"audio/ogg":  "ogg",
+
+	"application/zip": "zip",
 }
@@ -0,0 +1,11 @@
+package constants
+
+// Purposes clients upload media for, the purpose is part of the object path.
+const (
+	MediaPurposePrimaryProfilePhoto = "primaryProfilePhoto"
+	MediaPurposeProfilePhoto        = "profilePhoto"
+	// Selfies handed in to verify a profile, only their owner gets URLs
+	MediaPurposeVerification = "verification"
+	MediaPurposeChatPhoto    = "chatPhoto"
+	MediaPurposeVoicePrompt  = "voicePrompt"
+)
@@ -0,0 +1,58 @@
+package authHelpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"media/internal/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+const DeletionSagaStatusInProgress = "inProgress"
+
+type DeletionSaga struct {
+	ID     string `json:"id"`
+	AuthId string `json:"authId"`
+	Status string `json:"status"`
+}
+
+type deletionSagaResponse struct {
+	Data DeletionSaga `json:"data"`
+}
+
+// GetDeletionSaga asks the auth service for the deletion saga of a user, nil
+// if none was requested.
+func GetDeletionSaga(ctx context.Context, authId string) (*DeletionSaga, error) {
+	authServiceURL := config.GetConfig().Auth.ServiceURL
+	if authServiceURL == "" {
+		return nil, fmt.Errorf("AUTH_SERVICE_URL is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(authServiceURL, "/")+"/internal/deletions/"+url.PathEscape(authId), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Access-Token", config.GetConfig().InternalAccessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth service responded with status %d", resp.StatusCode)
+	}
+
+	var res deletionSagaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
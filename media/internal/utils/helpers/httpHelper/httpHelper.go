@@ -89,36 +89,42 @@ func SendErrorResponse(c *fiber.Ctx, err error) error {
 	})
 }
 
-func DownloadImageFromSignedURL(signedURL string) ([]byte, image.Image, error) {
+func DownloadFromSignedURL(signedURL string) ([]byte, error) {
 	// Create HTTP client with timeout
 	client := &http.Client{}
 
 	// Create a new request
 	req, err := http.NewRequest("GET", signedURL, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error creating request: %v", err)
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	// Send the request
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error sending request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf("bad status: %s", resp.Status)
+		return nil, fmt.Errorf("bad status: %s", resp.Status)
 	}
 
 	// Read the entire response body into memory
 	var buf bytes.Buffer
 	if _, err := io.Copy(&buf, resp.Body); err != nil {
-		return nil, nil, fmt.Errorf("error reading response body: %v", err)
+		return nil, fmt.Errorf("error reading response body: %v", err)
 	}
+	return buf.Bytes(), nil
+}
 
+func DownloadImageFromSignedURL(signedURL string) ([]byte, image.Image, error) {
 	// Get the raw bytes
-	imgBytes := buf.Bytes()
+	imgBytes, err := DownloadFromSignedURL(signedURL)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Decode the image (requires importing the appropriate image package)
 	img, _, err := image.Decode(bytes.NewReader(imgBytes))
@@ -0,0 +1,110 @@
+package jwtHelpers
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"media/internal/config"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Keys are fetched again after this long so rotated keys are picked up.
+	jwksCacheTTL = 10 * time.Minute
+	// A token signed with an unknown key triggers a fetch at most this often.
+	jwksMinRefetchInterval = time.Minute
+)
+
+// Verifier checks access tokens against the keys the auth service publishes,
+// cached in memory.
+type Verifier struct {
+	url    string
+	issuer string
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+var (
+	verifier     *Verifier
+	verifierOnce sync.Once
+)
+
+// GetVerifier returns the verifier for the configured JWKS, nil when none is.
+func GetVerifier() *Verifier {
+	verifierOnce.Do(func() {
+		authConfig := config.GetConfig().Auth
+		if authConfig.JwksURL == "" {
+			return
+		}
+		verifier = NewVerifier(authConfig.JwksURL, authConfig.Issuer)
+	})
+	return verifier
+}
+
+func NewVerifier(url string, issuer string) *Verifier {
+	return &Verifier{
+		url:    url,
+		issuer: issuer,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Verify checks token locally. Keys are fetched when the cache is stale or
+// the token names a key the cache doesn't know.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	v.mu.RLock()
+	keys, fetchedAt := v.keys, v.fetchedAt
+	v.mu.RUnlock()
+
+	if keys == nil || time.Since(fetchedAt) > jwksCacheTTL {
+		keys = v.refresh()
+	}
+	claims, err := Verify(token, keys, v.issuer, time.Now())
+	if errors.Is(err, ErrUnknownKey) {
+		claims, err = Verify(token, v.refresh(), v.issuer, time.Now())
+	}
+	return claims, err
+}
+
+// refresh fetches the keys unless it was attempted too recently, and returns
+// the freshest keys known. A failed fetch keeps the cached keys.
+func (v *Verifier) refresh() map[string]*rsa.PublicKey {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if time.Since(v.attemptedAt) < jwksMinRefetchInterval {
+		return v.keys
+	}
+	v.attemptedAt = time.Now()
+	keys, err := v.fetch()
+	if err != nil {
+		log.Printf("Error fetching JWKS from %s: %v", v.url, err)
+		return v.keys
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	return v.keys
+}
+
+func (v *Verifier) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := v.client.Get(v.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+	return jwks.PublicKeys(), nil
+}
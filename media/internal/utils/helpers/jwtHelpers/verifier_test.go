@@ -0,0 +1,84 @@
+package jwtHelpers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims Claims) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	claimsJSON, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifierCachesKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{
+			Kty: "RSA",
+			Alg: "RS256",
+			Kid: "key-1",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	verifier := NewVerifier(server.URL, "purely-auth")
+	now := time.Now()
+	claims := Claims{Issuer: "purely-auth", Subject: "auth-1", ID: "auth-1", IssuedAt: now.Unix(), ExpiresAt: now.Add(10 * time.Minute).Unix()}
+
+	for i := 0; i < 3; i++ {
+		verified, err := verifier.Verify(signToken(t, key, "key-1", claims))
+		if err != nil {
+			t.Fatalf("expected a valid token, got %v", err)
+		}
+		if verified.ID != "auth-1" {
+			t.Errorf("expected auth-1, got %s", verified.ID)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected the keys to be fetched once, got %d", fetches.Load())
+	}
+
+	// An unknown key refetches at most once a minute
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(signToken(t, key, "key-2", claims)); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("expected an unknown key, got %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("expected no refetch right after a fetch, got %d fetches", fetches.Load())
+	}
+
+	claims.ExpiresAt = now.Add(-time.Hour).Unix()
+	if _, err := verifier.Verify(signToken(t, key, "key-1", claims)); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected an expired token, got %v", err)
+	}
+	if IssuerOf(signToken(t, key, "key-1", claims)) != "purely-auth" {
+		t.Errorf("expected the issuer to be read")
+	}
+}
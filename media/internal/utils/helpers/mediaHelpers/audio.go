@@ -0,0 +1,400 @@
+package mediahelpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// Audio formats recognised by ProbeAudio.
+const (
+	AudioFormatMP3  = "mp3"
+	AudioFormatAAC  = "aac"
+	AudioFormatMP4  = "mp4"
+	AudioFormatOpus = "opus"
+	AudioFormatOgg  = "vorbis"
+)
+
+// WaveformBars is the number of bars of the waveform preview.
+const WaveformBars = 64
+
+// The waveform spans this many decibels below the loudest frame.
+const waveformRangeDB = 40.0
+
+// At most this many levels are kept per file, evenly spread over it. That is
+// plenty for the waveform and bounds what a forged sample count can allocate.
+const maxAudioLevels = 4096
+
+var ErrNotAudio = errors.New("not a supported audio file")
+
+// AudioInfo is what ProbeAudio reads from the headers of an audio file.
+// Levels holds one loudness estimate per frame or packet, in decibels
+// relative to an arbitrary reference. The files are not decoded: MP3 levels
+// come from the global gain of each frame, other formats use the size of
+// each packet, which follows the loudness closely enough for a preview.
+type AudioInfo struct {
+	Format     string
+	Duration   time.Duration
+	SampleRate int
+	Levels     []float64
+}
+
+// ProbeAudio parses the headers of an MP3, ADTS AAC, MP4/M4A or Ogg
+// (Opus or Vorbis) file. Anything else is reported as ErrNotAudio.
+func ProbeAudio(data []byte) (*AudioInfo, error) {
+	switch {
+	case len(data) >= 8 && string(data[4:8]) == "ftyp":
+		return probeMP4(data)
+	case len(data) >= 4 && string(data[:4]) == "OggS":
+		return probeOgg(data)
+	}
+	data = skipID3v2(data)
+	if len(data) >= 2 && data[0] == 0xFF && data[1]&0xF6 == 0xF0 {
+		return probeADTS(data)
+	}
+	if len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 {
+		return probeMP3(data)
+	}
+	return nil, ErrNotAudio
+}
+
+func sizeLevel(size int) float64 {
+	return 20 * math.Log10(float64(max(size, 1)))
+}
+
+// Waveform reduces levels to bars from 0 to 100, the loudest bar is 100 and
+// anything waveformRangeDB quieter than it is 0.
+func Waveform(levels []float64, bars int) []int {
+	waveform := make([]int, bars)
+	if len(levels) == 0 {
+		return waveform
+	}
+	averages := make([]float64, bars)
+	loudest := math.Inf(-1)
+	for bar := range averages {
+		start := bar * len(levels) / bars
+		end := max((bar+1)*len(levels)/bars, start+1)
+		end = min(end, len(levels))
+		if start >= len(levels) {
+			start = len(levels) - 1
+		}
+		var sum float64
+		for _, level := range levels[start:end] {
+			sum += level
+		}
+		averages[bar] = sum / float64(end-start)
+		loudest = math.Max(loudest, averages[bar])
+	}
+	for bar, average := range averages {
+		value := 1 + (average-loudest)/waveformRangeDB
+		waveform[bar] = int(math.Round(math.Max(0, math.Min(1, value)) * 100))
+	}
+	return waveform
+}
+
+func skipID3v2(data []byte) []byte {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return data
+	}
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10
+	}
+	if size > len(data) {
+		return nil
+	}
+	return data[size:]
+}
+
+var (
+	mp3Bitrates = [2][16]int{
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	}
+	mp3SampleRates = [3]int{44100, 48000, 32000}
+)
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (reader *bitReader) read(bits int) int {
+	value := 0
+	for i := 0; i < bits; i++ {
+		bit := 0
+		if reader.pos/8 < len(reader.data) {
+			bit = int(reader.data[reader.pos/8]>>(7-reader.pos%8)) & 1
+		}
+		value = value<<1 | bit
+		reader.pos++
+	}
+	return value
+}
+
+// mp3FrameGain returns the largest global gain of the granules of a Layer III
+// frame, -1 when all of them are silent.
+func mp3FrameGain(frame []byte, mpeg1 bool, channels int) int {
+	reader := bitReader{data: frame, pos: 32}
+	if frame[1]&1 == 0 {
+		reader.pos += 16
+	}
+	granules := 1
+	if mpeg1 {
+		granules = 2
+		reader.read(9)
+		if channels == 1 {
+			reader.read(5)
+		} else {
+			reader.read(3)
+		}
+		reader.read(4 * channels)
+	} else {
+		reader.read(8)
+		reader.read(channels)
+	}
+	gain := -1
+	for granule := 0; granule < granules; granule++ {
+		for channel := 0; channel < channels; channel++ {
+			part23Length := reader.read(12)
+			reader.read(9)
+			globalGain := reader.read(8)
+			if part23Length > 0 {
+				gain = max(gain, globalGain)
+			}
+			if mpeg1 {
+				reader.read(59 - 29)
+			} else {
+				reader.read(63 - 29)
+			}
+		}
+	}
+	return gain
+}
+
+func probeMP3(data []byte) (*AudioInfo, error) {
+	info := &AudioInfo{Format: AudioFormatMP3}
+	var samples int
+	for pos := 0; pos+4 <= len(data); {
+		header := data[pos : pos+4]
+		if header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+			break
+		}
+		version := int(header[1]>>3) & 3
+		layer := int(header[1]>>1) & 3
+		bitrateIndex := int(header[2] >> 4)
+		sampleRateIndex := int(header[2]>>2) & 3
+		if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+			break
+		}
+		mpeg1 := version == 3
+		sampleRate := mp3SampleRates[sampleRateIndex]
+		bitrate := mp3Bitrates[0][bitrateIndex]
+		frameSamples, coefficient := 1152, 144000
+		if !mpeg1 {
+			sampleRate /= 2
+			if version == 0 {
+				sampleRate /= 2
+			}
+			bitrate = mp3Bitrates[1][bitrateIndex]
+			frameSamples, coefficient = 576, 72000
+		}
+		frameLength := coefficient*bitrate/sampleRate + int(header[2]>>1)&1
+		if pos+frameLength > len(data) {
+			break
+		}
+		channels := 2
+		if header[3]>>6 == 3 {
+			channels = 1
+		}
+		level := 0.0
+		if gain := mp3FrameGain(data[pos:pos+frameLength], mpeg1, channels); gain >= 0 {
+			// A global gain step is 1.5dB
+			level = 1.5 * float64(gain)
+		}
+		info.Levels = append(info.Levels, level)
+		info.SampleRate = sampleRate
+		samples += frameSamples
+		pos += frameLength
+	}
+	if len(info.Levels) == 0 {
+		return nil, ErrNotAudio
+	}
+	info.Duration = time.Duration(samples) * time.Second / time.Duration(info.SampleRate)
+	return info, nil
+}
+
+var adtsSampleRates = [13]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+func probeADTS(data []byte) (*AudioInfo, error) {
+	info := &AudioInfo{Format: AudioFormatAAC}
+	var samples int
+	for pos := 0; pos+7 <= len(data); {
+		header := data[pos : pos+7]
+		if header[0] != 0xFF || header[1]&0xF6 != 0xF0 {
+			break
+		}
+		sampleRateIndex := int(header[2]>>2) & 0xF
+		frameLength := int(header[3]&3)<<11 | int(header[4])<<3 | int(header[5]>>5)
+		if sampleRateIndex >= len(adtsSampleRates) || frameLength < 7 || pos+frameLength > len(data) {
+			break
+		}
+		info.SampleRate = adtsSampleRates[sampleRateIndex]
+		samples += (int(header[6]&3) + 1) * 1024
+		info.Levels = append(info.Levels, sizeLevel(frameLength-7))
+		pos += frameLength
+	}
+	if len(info.Levels) == 0 {
+		return nil, ErrNotAudio
+	}
+	info.Duration = time.Duration(samples) * time.Second / time.Duration(info.SampleRate)
+	return info, nil
+}
+
+// mp4Boxes returns the payloads of the child boxes of the given type.
+func mp4Boxes(data []byte, boxType string) [][]byte {
+	boxes := [][]byte{}
+	for pos := 0; pos+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		header := 8
+		switch size {
+		case 0:
+			size = len(data) - pos
+		case 1:
+			if pos+16 > len(data) {
+				return boxes
+			}
+			size = int(binary.BigEndian.Uint64(data[pos+8:]))
+			header = 16
+		}
+		if size < header || size > len(data)-pos {
+			return boxes
+		}
+		if string(data[pos+4:pos+8]) == boxType {
+			boxes = append(boxes, data[pos+header:pos+size])
+		}
+		pos += size
+	}
+	return boxes
+}
+
+// mp4Box returns the payload of the first child box of the given type.
+func mp4Box(data []byte, boxType string) []byte {
+	if boxes := mp4Boxes(data, boxType); len(boxes) > 0 {
+		return boxes[0]
+	}
+	return nil
+}
+
+func probeMP4(data []byte) (*AudioInfo, error) {
+	moov := mp4Box(data, "moov")
+	for _, trak := range mp4Boxes(moov, "trak") {
+		mdia := mp4Box(trak, "mdia")
+		hdlr := mp4Box(mdia, "hdlr")
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+			continue
+		}
+		mdhd := mp4Box(mdia, "mdhd")
+		var timescale, duration uint64
+		switch {
+		case len(mdhd) >= 32 && mdhd[0] == 1:
+			timescale = uint64(binary.BigEndian.Uint32(mdhd[20:]))
+			duration = binary.BigEndian.Uint64(mdhd[24:])
+		case len(mdhd) >= 20:
+			timescale = uint64(binary.BigEndian.Uint32(mdhd[12:]))
+			duration = uint64(binary.BigEndian.Uint32(mdhd[16:]))
+		}
+		if timescale == 0 {
+			continue
+		}
+		info := &AudioInfo{
+			Format:     AudioFormatMP4,
+			Duration:   time.Duration(float64(duration) / float64(timescale) * float64(time.Second)),
+			SampleRate: int(timescale),
+		}
+		stsz := mp4Box(mp4Box(mp4Box(mdia, "minf"), "stbl"), "stsz")
+		if len(stsz) >= 12 {
+			sampleSize := int(binary.BigEndian.Uint32(stsz[4:]))
+			// The count comes from the file: every sample lasts at least a
+			// tick, and variable sizes are listed in the box itself
+			sampleCount := min(uint64(binary.BigEndian.Uint32(stsz[8:])), duration)
+			if sampleSize == 0 {
+				sampleCount = min(sampleCount, uint64(len(stsz)-12)/4)
+			}
+			step := max(1, int((sampleCount+maxAudioLevels-1)/maxAudioLevels))
+			for i := 0; i < int(sampleCount); i += step {
+				size := sampleSize
+				if size == 0 {
+					if 12+4*i+4 > len(stsz) {
+						break
+					}
+					size = int(binary.BigEndian.Uint32(stsz[12+4*i:]))
+				}
+				info.Levels = append(info.Levels, sizeLevel(size))
+			}
+		}
+		return info, nil
+	}
+	return nil, ErrNotAudio
+}
+
+// probeOgg reads the codec from the first packet and the duration from the
+// granule position of the last page.
+func probeOgg(data []byte) (*AudioInfo, error) {
+	var info *AudioInfo
+	var preSkip, headerPackets, packets int
+	var packet int
+	var granule uint64
+	for pos := 0; pos+27 <= len(data) && string(data[pos:pos+4]) == "OggS"; {
+		segmentCount := int(data[pos+26])
+		if pos+27+segmentCount > len(data) {
+			break
+		}
+		segments := data[pos+27 : pos+27+segmentCount]
+		body := pos + 27 + segmentCount
+		if pageGranule := binary.LittleEndian.Uint64(data[pos+6:]); pageGranule != math.MaxUint64 {
+			granule = pageGranule
+		}
+		for _, segment := range segments {
+			if info == nil && packets == 0 && body+int(segment) <= len(data) {
+				first := data[body : body+int(segment)]
+				switch {
+				case len(first) >= 19 && bytes.HasPrefix(first, []byte("OpusHead")):
+					info = &AudioInfo{Format: AudioFormatOpus, SampleRate: 48000}
+					preSkip = int(binary.LittleEndian.Uint16(first[10:]))
+					headerPackets = 2
+				case len(first) >= 16 && bytes.HasPrefix(first, []byte("\x01vorbis")):
+					info = &AudioInfo{Format: AudioFormatOgg, SampleRate: int(binary.LittleEndian.Uint32(first[12:]))}
+					headerPackets = 3
+				default:
+					return nil, ErrNotAudio
+				}
+			}
+			if info == nil {
+				return nil, ErrNotAudio
+			}
+			packet += int(segment)
+			body += int(segment)
+			if segment < 255 {
+				if packets >= headerPackets {
+					info.Levels = append(info.Levels, sizeLevel(packet))
+				}
+				packets++
+				packet = 0
+			}
+		}
+		if body > len(data) {
+			break
+		}
+		pos = body
+	}
+	if info == nil || info.SampleRate == 0 {
+		return nil, ErrNotAudio
+	}
+	samples := int64(granule) - int64(preSkip)
+	info.Duration = time.Duration(max(samples, 0)) * time.Second / time.Duration(info.SampleRate)
+	return info, nil
+}
@@ -0,0 +1,188 @@
+package mediahelpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+type bitWriter struct {
+	data []byte
+	pos  int
+}
+
+func (writer *bitWriter) write(value int, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if writer.pos/8 >= len(writer.data) {
+			writer.data = append(writer.data, 0)
+		}
+		writer.data[writer.pos/8] |= byte(value>>i&1) << (7 - writer.pos%8)
+		writer.pos++
+	}
+}
+
+// mp3Frame builds a 128kbps 44.1kHz stereo MPEG-1 Layer III frame whose
+// granules all have the given global gain.
+func mp3Frame(gain int) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	writer := bitWriter{}
+	writer.write(0, 9+3+8)
+	for i := 0; i < 4; i++ {
+		writer.write(100, 12)
+		writer.write(0, 9)
+		writer.write(gain, 8)
+		writer.write(0, 30)
+	}
+	copy(frame[4:], writer.data)
+	return frame
+}
+
+func TestProbeAudioMP3(t *testing.T) {
+	data := []byte("ID3\x04\x00\x00\x00\x00\x00\x04tags")
+	for i := 0; i < 100; i++ {
+		gain := 110
+		if i >= 50 {
+			gain = 170
+		}
+		data = append(data, mp3Frame(gain)...)
+	}
+	info, err := ProbeAudio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != AudioFormatMP3 || info.SampleRate != 44100 || len(info.Levels) != 100 {
+		t.Fatalf("unexpected probe result %+v", info)
+	}
+	if want := 100 * 1152 * time.Second / 44100; info.Duration != want {
+		t.Errorf("expected a duration of %v, got %v", want, info.Duration)
+	}
+	waveform := Waveform(info.Levels, 4)
+	if waveform[0] != 0 || waveform[3] != 100 {
+		t.Errorf("expected the quiet half to be silent and the loud half full, got %v", waveform)
+	}
+}
+
+func TestProbeAudioADTS(t *testing.T) {
+	var data []byte
+	for i := 0; i < 47; i++ {
+		frame := make([]byte, 200)
+		// 44.1kHz, one raw block of 1024 samples per frame
+		copy(frame, []byte{0xFF, 0xF1, 0x50, 0x80, byte(200 >> 3), byte(200&7)<<5 | 0x1F, 0xFC})
+		data = append(data, frame...)
+	}
+	info, err := ProbeAudio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != AudioFormatAAC || info.Duration != 47*1024*time.Second/44100 {
+		t.Errorf("unexpected probe result %+v", info)
+	}
+}
+
+func mp4TestBox(boxType string, payloads ...[]byte) []byte {
+	payload := bytes.Join(payloads, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(box, boxType...), payload...)
+}
+
+func TestProbeAudioMP4(t *testing.T) {
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:], 44100)
+	binary.BigEndian.PutUint32(mdhd[16:], 44100*12)
+	hdlr := append(make([]byte, 8), "soun"...)
+	stsz := make([]byte, 12)
+	binary.BigEndian.PutUint32(stsz[8:], 3)
+	for _, size := range []uint32{10, 300, 300} {
+		stsz = binary.BigEndian.AppendUint32(stsz, size)
+	}
+	data := append(mp4TestBox("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4TestBox("moov",
+		mp4TestBox("trak", mp4TestBox("mdia",
+			mp4TestBox("mdhd", mdhd),
+			mp4TestBox("hdlr", hdlr),
+			mp4TestBox("minf", mp4TestBox("stbl", mp4TestBox("stsz", stsz))),
+		)),
+	)...)
+	info, err := ProbeAudio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != AudioFormatMP4 || info.Duration != 12*time.Second || len(info.Levels) != 3 {
+		t.Errorf("unexpected probe result %+v", info)
+	}
+}
+
+func TestProbeAudioMP4ClampsForgedSampleCount(t *testing.T) {
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:], 44100)
+	binary.BigEndian.PutUint32(mdhd[16:], 44100*30)
+	hdlr := append(make([]byte, 8), "soun"...)
+	// A fixed sample size with a sample count the file doesn't have
+	stsz := make([]byte, 12)
+	binary.BigEndian.PutUint32(stsz[4:], 400)
+	binary.BigEndian.PutUint32(stsz[8:], math.MaxUint32)
+	data := append(mp4TestBox("ftyp", []byte("M4A \x00\x00\x00\x00")), mp4TestBox("moov",
+		mp4TestBox("trak", mp4TestBox("mdia",
+			mp4TestBox("mdhd", mdhd),
+			mp4TestBox("hdlr", hdlr),
+			mp4TestBox("minf", mp4TestBox("stbl", mp4TestBox("stsz", stsz))),
+		)),
+	)...)
+	info, err := ProbeAudio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Levels) == 0 || len(info.Levels) > maxAudioLevels {
+		t.Errorf("expected at most %d levels, got %d", maxAudioLevels, len(info.Levels))
+	}
+}
+
+func oggPage(granule uint64, packets ...[]byte) []byte {
+	var segments, body []byte
+	for _, packet := range packets {
+		for size := len(packet); ; size -= 255 {
+			if size < 255 {
+				segments = append(segments, byte(size))
+				break
+			}
+			segments = append(segments, 255)
+		}
+		body = append(body, packet...)
+	}
+	page := append([]byte("OggS"), 0, 0)
+	page = binary.LittleEndian.AppendUint64(page, granule)
+	page = append(page, make([]byte, 12)...)
+	page = append(page, byte(len(segments)))
+	return append(append(page, segments...), body...)
+}
+
+func TestProbeAudioOpus(t *testing.T) {
+	head := append([]byte("OpusHead\x01\x01"), 0x38, 0x01)
+	head = append(head, make([]byte, 7)...)
+	data := oggPage(0, head)
+	data = append(data, oggPage(0, []byte("OpusTags"))...)
+	data = append(data, oggPage(312+48000*5/2, make([]byte, 40), make([]byte, 400))...)
+	info, err := ProbeAudio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != AudioFormatOpus || info.Duration != 2500*time.Millisecond || len(info.Levels) != 2 {
+		t.Errorf("unexpected probe result %+v", info)
+	}
+}
+
+func TestProbeAudioRejectsOtherFiles(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("\x89PNG\r\n\x1a\n0000"),
+		{0xFF, 0xD8, 0xFF, 0xE0},
+		oggPage(0, []byte("\x80theora")),
+		nil,
+	} {
+		if _, err := ProbeAudio(data); !errors.Is(err, ErrNotAudio) {
+			t.Errorf("expected %q to be rejected, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package mediahelpers
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// Faces are searched on a copy scaled down to this size, pico cascades work on
+// small grayscale images and the boxes are scaled back afterwards.
+const faceDetectionMaxDimension = 640
+
+const (
+	faceMinSize        = 20
+	faceShiftFactor    = 0.1
+	faceScaleFactor    = 1.1
+	faceIoUThreshold   = 0.2
+	faceScoreThreshold = 5.0
+)
+
+// FaceBox is a detected face in the coordinates of the original image.
+type FaceBox struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+	Score  float64
+}
+
+// FaceDetector runs a pico cascade (the facefinder cascade shipped with
+// pico/pigo). It is a pure Go port of the pico object detection algorithm:
+// binary pixel comparison trees evaluated over a sliding window.
+type FaceDetector struct {
+	treeDepth     uint32
+	treeNum       uint32
+	treeCodes     []int8
+	treePred      []float32
+	treeThreshold []float32
+}
+
+type faceDetection struct {
+	row   int
+	col   int
+	scale int
+	score float32
+}
+
+// The facefinder cascade of pico, as published with pigo under the MIT license
+// kept next to it in cascades/LICENSE.
+//
+//go:embed cascades/facefinder
+var facefinderCascade []byte
+
+// DefaultFaceDetector returns a detector running the facefinder cascade
+// shipped with this package.
+func DefaultFaceDetector() (*FaceDetector, error) {
+	return UnpackFaceCascade(facefinderCascade)
+}
+
+// LoadFaceDetector reads a pico cascade file.
+func LoadFaceDetector(path string) (*FaceDetector, error) {
+	packet, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return UnpackFaceCascade(packet)
+}
+
+// UnpackFaceCascade decodes a pico cascade: an 8 byte header, the depth and
+// number of trees, then for every tree its node tests, leaf predictions and
+// the rejection threshold.
+func UnpackFaceCascade(packet []byte) (*FaceDetector, error) {
+	if len(packet) < 16 {
+		return nil, fmt.Errorf("cascade is too short")
+	}
+	pos := 8
+	detector := &FaceDetector{
+		treeDepth: binary.LittleEndian.Uint32(packet[pos:]),
+		treeNum:   binary.LittleEndian.Uint32(packet[pos+4:]),
+	}
+	pos += 8
+	if detector.treeDepth == 0 || detector.treeDepth > 16 {
+		return nil, fmt.Errorf("invalid tree depth %d", detector.treeDepth)
+	}
+
+	leaves := 1 << detector.treeDepth
+	codesSize := 4*leaves - 4
+	treeSize := codesSize + 4*leaves + 4
+	if len(packet)-pos < int(detector.treeNum)*treeSize {
+		return nil, fmt.Errorf("cascade is truncated")
+	}
+	for t := 0; t < int(detector.treeNum); t++ {
+		// The root slot is unused, node tests start at index 1
+		detector.treeCodes = append(detector.treeCodes, 0, 0, 0, 0)
+		for _, code := range packet[pos : pos+codesSize] {
+			detector.treeCodes = append(detector.treeCodes, int8(code))
+		}
+		pos += codesSize
+		for i := 0; i < leaves; i++ {
+			detector.treePred = append(detector.treePred, math.Float32frombits(binary.LittleEndian.Uint32(packet[pos:])))
+			pos += 4
+		}
+		detector.treeThreshold = append(detector.treeThreshold, math.Float32frombits(binary.LittleEndian.Uint32(packet[pos:])))
+		pos += 4
+	}
+	return detector, nil
+}
+
+// classifyRegion scores the square of size scale centered at (row, col), a
+// negative score means a tree rejected it.
+func (detector *FaceDetector) classifyRegion(row int, col int, scale int, pixels []uint8, dim int) float32 {
+	leaves := 1 << detector.treeDepth
+	root := 0
+	var out float32
+	row *= 256
+	col *= 256
+	for i := 0; i < int(detector.treeNum); i++ {
+		idx := 1
+		for j := 0; j < int(detector.treeDepth); j++ {
+			code := detector.treeCodes[root+4*idx:]
+			p1 := ((row+int(code[0])*scale)>>8)*dim + ((col + int(code[1])*scale) >> 8)
+			p2 := ((row+int(code[2])*scale)>>8)*dim + ((col + int(code[3])*scale) >> 8)
+			idx = 2 * idx
+			if pixels[p1] <= pixels[p2] {
+				idx++
+			}
+		}
+		out += detector.treePred[leaves*i+idx-leaves]
+		if out <= detector.treeThreshold[i] {
+			return -1
+		}
+		root += 4 * leaves
+	}
+	return out - detector.treeThreshold[detector.treeNum-1]
+}
+
+// Detect returns the faces found in img, best first.
+func (detector *FaceDetector) Detect(img image.Image) []FaceBox {
+	bounds := img.Bounds()
+	ratio := 1.0
+	if longest := max(bounds.Dx(), bounds.Dy()); longest > faceDetectionMaxDimension {
+		ratio = float64(longest) / faceDetectionMaxDimension
+		img = imaging.Resize(img, int(float64(bounds.Dx())/ratio), int(float64(bounds.Dy())/ratio), imaging.Box)
+	}
+	gray := imaging.Grayscale(img)
+	cols, rows := gray.Bounds().Dx(), gray.Bounds().Dy()
+	pixels := make([]uint8, cols*rows)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			pixels[y*cols+x] = gray.NRGBAAt(x, y).R
+		}
+	}
+
+	var detections []faceDetection
+	maxSize := min(rows, cols)
+	for scale := faceMinSize; scale <= maxSize; scale = int(float64(scale) * faceScaleFactor) {
+		step := max(int(faceShiftFactor*float64(scale)), 1)
+		offset := scale/2 + 1
+		for row := offset; row <= rows-offset; row += step {
+			for col := offset; col <= cols-offset; col += step {
+				if score := detector.classifyRegion(row, col, scale, pixels, cols); score > 0 {
+					detections = append(detections, faceDetection{row, col, scale, score})
+				}
+			}
+		}
+	}
+
+	faces := []FaceBox{}
+	for _, detection := range clusterFaceDetections(detections) {
+		if detection.score < faceScoreThreshold {
+			continue
+		}
+		size := float64(detection.scale) * ratio
+		faces = append(faces, FaceBox{
+			X:      bounds.Min.X + int(float64(detection.col)*ratio-size/2),
+			Y:      bounds.Min.Y + int(float64(detection.row)*ratio-size/2),
+			Width:  int(size),
+			Height: int(size),
+			Score:  float64(detection.score),
+		})
+	}
+	return faces
+}
+
+func faceIoU(a faceDetection, b faceDetection) float64 {
+	overRow := math.Max(0, math.Min(float64(a.row)+float64(a.scale)/2, float64(b.row)+float64(b.scale)/2)-
+		math.Max(float64(a.row)-float64(a.scale)/2, float64(b.row)-float64(b.scale)/2))
+	overCol := math.Max(0, math.Min(float64(a.col)+float64(a.scale)/2, float64(b.col)+float64(b.scale)/2)-
+		math.Max(float64(a.col)-float64(a.scale)/2, float64(b.col)-float64(b.scale)/2))
+	overlap := overRow * overCol
+	return overlap / (float64(a.scale*a.scale) + float64(b.scale*b.scale) - overlap)
+}
+
+// clusterFaceDetections merges overlapping windows into one detection per
+// face, averaging their position and size and summing their scores.
+func clusterFaceDetections(detections []faceDetection) []faceDetection {
+	sort.Slice(detections, func(i, j int) bool {
+		return detections[i].score > detections[j].score
+	})
+	assigned := make([]bool, len(detections))
+	clusters := []faceDetection{}
+	for i := range detections {
+		if assigned[i] {
+			continue
+		}
+		var row, col, scale, count int
+		var score float32
+		for j := i; j < len(detections); j++ {
+			if assigned[j] || faceIoU(detections[i], detections[j]) <= faceIoUThreshold {
+				continue
+			}
+			assigned[j] = true
+			row += detections[j].row
+			col += detections[j].col
+			scale += detections[j].scale
+			score += detections[j].score
+			count++
+		}
+		clusters = append(clusters, faceDetection{row / count, col / count, scale / count, score})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].score > clusters[j].score
+	})
+	return clusters
+}
+
+// FaceCrop returns the largest square of img centered on the faces, or on the
+// image when there are none, for thumbnails that keep the person in frame.
+func FaceCrop(bounds image.Rectangle, faces []FaceBox) image.Rectangle {
+	focus, ok := FacesFocus(bounds, faces)
+	if !ok {
+		focus = FocalPoint{X: 0.5, Y: 0.5}
+	}
+	return FocalCrop(bounds, focus, 1)
+}
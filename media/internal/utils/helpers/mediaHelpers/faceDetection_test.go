@@ -0,0 +1,77 @@
+package mediahelpers
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+	"testing"
+)
+
+// singleTestCascade builds a one tree cascade of depth 1 that accepts a
+// window when the pixel left of its center is not brighter than the one right
+// of it.
+func singleTestCascade() []byte {
+	packet := make([]byte, 8)
+	packet = binary.LittleEndian.AppendUint32(packet, 1)
+	packet = binary.LittleEndian.AppendUint32(packet, 1)
+	left, right := int8(-100), int8(100)
+	packet = append(packet, 0, byte(left), 0, byte(right))
+	packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(-1))
+	packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(10))
+	packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(0))
+	return packet
+}
+
+func TestFaceCascadeClassifiesRegions(t *testing.T) {
+	detector, err := UnpackFaceCascade(singleTestCascade())
+	if err != nil {
+		t.Fatalf("error unpacking cascade: %v", err)
+	}
+
+	dim := 40
+	pixels := make([]uint8, dim*dim)
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			pixels[y*dim+x] = uint8(x * 5)
+		}
+	}
+	if score := detector.classifyRegion(20, 20, 20, pixels, dim); score <= 0 {
+		t.Errorf("expected region to be accepted, got %f", score)
+	}
+	for i := range pixels {
+		pixels[i] = 255 - pixels[i]
+	}
+	if score := detector.classifyRegion(20, 20, 20, pixels, dim); score > 0 {
+		t.Errorf("expected region to be rejected, got %f", score)
+	}
+}
+
+func TestUnpackFaceCascadeRejectsTruncatedFile(t *testing.T) {
+	packet := singleTestCascade()
+	if _, err := UnpackFaceCascade(packet[:len(packet)-2]); err == nil {
+		t.Error("expected truncated cascade to fail")
+	}
+}
+
+func TestFaceCropCentersOnFaces(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 200)
+	crop := FaceCrop(bounds, []FaceBox{{X: 330, Y: 50, Width: 40, Height: 40}})
+	if crop != image.Rect(200, 0, 400, 200) {
+		t.Errorf("expected crop clamped to the right edge, got %v", crop)
+	}
+	crop = FaceCrop(bounds, nil)
+	if crop != image.Rect(100, 0, 300, 200) {
+		t.Errorf("expected centered crop, got %v", crop)
+	}
+}
+
+func TestDefaultFaceDetectorUnpacksShippedCascade(t *testing.T) {
+	detector, err := DefaultFaceDetector()
+	if err != nil {
+		t.Fatalf("error unpacking shipped cascade: %v", err)
+	}
+	blank := image.NewGray(image.Rect(0, 0, 200, 200))
+	if faces := detector.Detect(blank); len(faces) != 0 {
+		t.Errorf("expected no faces on a blank image, got %d", len(faces))
+	}
+}
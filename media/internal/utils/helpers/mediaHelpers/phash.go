@@ -0,0 +1,57 @@
+package mediahelpers
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+)
+
+// PHashBandsCount is the number of 8 bit bands a 64 bit hash is split into.
+// Two hashes within a hamming distance smaller than PHashBandsCount always
+// share at least one band, which is what makes the banded index lookup exact.
+const PHashBandsCount = 8
+
+// DHash computes a 64 bit difference hash of the image. The image is reduced
+// to a 9x8 grayscale thumbnail and every bit records whether a pixel is
+// brighter than its right neighbour.
+func DHash(img image.Image) uint64 {
+	thumb := imaging.Resize(imaging.Grayscale(img), 9, 8, imaging.Lanczos)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := thumb.NRGBAAt(x, y).R
+			right := thumb.NRGBAAt(x+1, y).R
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+func FormatPHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func ParsePHash(hash string) (uint64, error) {
+	return strconv.ParseUint(hash, 16, 64)
+}
+
+func HammingDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// PHashBands returns the hash split into bands prefixed with their position,
+// e.g. "0:a3", so equal bytes at different positions don't match.
+func PHashBands(hash uint64) []string {
+	bands := make([]string, 0, PHashBandsCount)
+	for i := 0; i < PHashBandsCount; i++ {
+		shift := uint(8 * (PHashBandsCount - 1 - i))
+		bands = append(bands, fmt.Sprintf("%d:%02x", i, (hash>>shift)&0xff))
+	}
+	return bands
+}
@@ -0,0 +1,59 @@
+package mediahelpers
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func gradientImage(width int, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*255/width + y*64/height) % 256)
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: 255 - v, B: v / 2, A: 255})
+		}
+	}
+	return img
+}
+
+func TestDHashStableAcrossResize(t *testing.T) {
+	original := gradientImage(400, 300)
+	resized := imaging.Resize(original, 200, 150, imaging.Lanczos)
+
+	distance := HammingDistance(DHash(original), DHash(resized))
+	if distance >= PHashBandsCount {
+		t.Errorf("expected resized image to be a near duplicate, got distance %d", distance)
+	}
+}
+
+func TestDHashDiffersForDifferentImages(t *testing.T) {
+	a := gradientImage(400, 300)
+	b := imaging.FlipH(a)
+
+	distance := HammingDistance(DHash(a), DHash(b))
+	if distance < PHashBandsCount {
+		t.Errorf("expected flipped image to differ, got distance %d", distance)
+	}
+}
+
+func TestPHashRoundTripAndBands(t *testing.T) {
+	hash := uint64(0xa1b2c3d4e5f60718)
+	parsed, err := ParsePHash(FormatPHash(hash))
+	if err != nil {
+		t.Fatalf("error parsing hash: %v", err)
+	}
+	if parsed != hash {
+		t.Errorf("expected %x; got %x", hash, parsed)
+	}
+
+	bands := PHashBands(hash)
+	if len(bands) != PHashBandsCount {
+		t.Fatalf("expected %d bands; got %d", PHashBandsCount, len(bands))
+	}
+	if bands[0] != "0:a1" || bands[7] != "7:18" {
+		t.Errorf("unexpected bands %v", bands)
+	}
+}
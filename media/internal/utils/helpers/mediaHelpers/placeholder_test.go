@@ -0,0 +1,55 @@
+package mediahelpers
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func decodeBase83(value string) int {
+	decoded := 0
+	for _, char := range value {
+		decoded = decoded*83 + strings.IndexRune(base83Characters, char)
+	}
+	return decoded
+}
+
+func TestBlurHashEncodesSizeAndAverageColor(t *testing.T) {
+	img := imaging.New(120, 80, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	hash, err := BlurHash(img, BlurHashXComponents, BlurHashYComponents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != 4+2*BlurHashXComponents*BlurHashYComponents {
+		t.Fatalf("unexpected hash length %d: %s", len(hash), hash)
+	}
+	if sizeFlag := decodeBase83(hash[:1]); sizeFlag != (BlurHashXComponents-1)+(BlurHashYComponents-1)*9 {
+		t.Errorf("unexpected size flag %d", sizeFlag)
+	}
+	dc := decodeBase83(hash[2:6])
+	if r, g, b := dc>>16, (dc>>8)&255, dc&255; r != 200 || g != 100 || b != 50 {
+		t.Errorf("expected average color 200,100,50, got %d,%d,%d", r, g, b)
+	}
+}
+
+func TestBlurHashDiffersForDifferentImages(t *testing.T) {
+	first, _ := BlurHash(gradientImage(200, 100), 4, 3)
+	second, _ := BlurHash(imaging.FlipH(gradientImage(200, 100)), 4, 3)
+	if first == second {
+		t.Errorf("expected mirrored images to hash differently, both are %s", first)
+	}
+	if _, err := BlurHash(gradientImage(10, 10), 0, 3); err == nil {
+		t.Error("expected an error for invalid components")
+	}
+}
+
+func TestDominantColorPicksLargestArea(t *testing.T) {
+	img := imaging.New(100, 100, color.NRGBA{R: 20, G: 120, B: 220, A: 255})
+	img = imaging.Paste(img, imaging.New(30, 100, color.NRGBA{R: 250, G: 10, B: 10, A: 255}), image.Pt(0, 0))
+	if dominant := DominantColor(img); dominant != "#1478dc" {
+		t.Errorf("expected #1478dc, got %s", dominant)
+	}
+}
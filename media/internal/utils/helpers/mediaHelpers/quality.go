@@ -0,0 +1,82 @@
+package mediahelpers
+
+import (
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Sharpness is measured on a copy scaled down to this size so scores of
+// photos taken at different resolutions can be compared.
+const qualityMaxDimension = 512
+
+// Luma values at or beyond these count as clipped shadows or highlights.
+const (
+	qualityShadowLuma    = 8
+	qualityHighlightLuma = 247
+)
+
+// QualityMetrics are cheap signals of how usable a photo is. Sharpness is the
+// variance of the Laplacian of the luma, Brightness the mean luma (0-255) and
+// Clipped the share of pixels that are crushed to black or blown to white.
+// AspectRatio is the long side over the short side.
+type QualityMetrics struct {
+	Sharpness   float64
+	Brightness  float64
+	Clipped     float64
+	Width       int
+	Height      int
+	AspectRatio float64
+}
+
+// MeasureQuality computes the quality metrics of img.
+func MeasureQuality(img image.Image) QualityMetrics {
+	bounds := img.Bounds()
+	metrics := QualityMetrics{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+	}
+	if metrics.Width == 0 || metrics.Height == 0 {
+		return metrics
+	}
+	metrics.AspectRatio = float64(max(metrics.Width, metrics.Height)) / float64(min(metrics.Width, metrics.Height))
+
+	if longest := max(metrics.Width, metrics.Height); longest > qualityMaxDimension {
+		img = imaging.Resize(img, metrics.Width*qualityMaxDimension/longest, metrics.Height*qualityMaxDimension/longest, imaging.Box)
+	}
+	gray := imaging.Grayscale(img)
+	cols, rows := gray.Bounds().Dx(), gray.Bounds().Dy()
+	luma := func(x int, y int) float64 {
+		return float64(gray.Pix[y*gray.Stride+x*4])
+	}
+
+	var sum float64
+	var clipped int
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			value := luma(x, y)
+			sum += value
+			if value <= qualityShadowLuma || value >= qualityHighlightLuma {
+				clipped++
+			}
+		}
+	}
+	metrics.Brightness = sum / float64(cols*rows)
+	metrics.Clipped = float64(clipped) / float64(cols*rows)
+
+	if cols < 3 || rows < 3 {
+		return metrics
+	}
+	var lapSum, lapSquares float64
+	for y := 1; y < rows-1; y++ {
+		for x := 1; x < cols-1; x++ {
+			laplacian := luma(x-1, y) + luma(x+1, y) + luma(x, y-1) + luma(x, y+1) - 4*luma(x, y)
+			lapSum += laplacian
+			lapSquares += laplacian * laplacian
+		}
+	}
+	count := float64((cols - 2) * (rows - 2))
+	mean := lapSum / count
+	metrics.Sharpness = lapSquares/count - mean*mean
+	return metrics
+}
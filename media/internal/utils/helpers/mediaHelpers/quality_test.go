@@ -0,0 +1,55 @@
+package mediahelpers
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func checkerboardImage(width int, height int) *image.NRGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value := uint8((x/16+y/16)%2*120 + 60 + rng.Intn(20))
+			img.SetNRGBA(x, y, color.NRGBA{R: value, G: value, B: value, A: 255})
+		}
+	}
+	return img
+}
+
+func TestMeasureQualityScoresBlurAsLessSharp(t *testing.T) {
+	img := checkerboardImage(1024, 768)
+	sharp := MeasureQuality(img)
+	blurred := MeasureQuality(imaging.Blur(img, 8))
+	if sharp.Sharpness < 1000 {
+		t.Errorf("expected a sharp image to score high, got %.1f", sharp.Sharpness)
+	}
+	if blurred.Sharpness > 10 {
+		t.Errorf("expected a heavily blurred image to score low, got %.1f", blurred.Sharpness)
+	}
+}
+
+func TestMeasureQualityReportsExposure(t *testing.T) {
+	dark := MeasureQuality(imaging.New(200, 200, color.NRGBA{R: 4, G: 4, B: 4, A: 255}))
+	if dark.Brightness > 5 || dark.Clipped != 1 {
+		t.Errorf("expected a black image to be dark and clipped, got %+v", dark)
+	}
+	mid := MeasureQuality(imaging.New(200, 200, color.NRGBA{R: 128, G: 128, B: 128, A: 255}))
+	if mid.Brightness < 127 || mid.Brightness > 129 || mid.Clipped != 0 {
+		t.Errorf("expected a mid gray image to be well exposed, got %+v", mid)
+	}
+}
+
+func TestMeasureQualityReportsResolutionAndAspectRatio(t *testing.T) {
+	metrics := MeasureQuality(checkerboardImage(300, 900))
+	if metrics.Width != 300 || metrics.Height != 900 {
+		t.Errorf("expected the original size, got %dx%d", metrics.Width, metrics.Height)
+	}
+	if metrics.AspectRatio != 3 {
+		t.Errorf("expected aspect ratio 3, got %.2f", metrics.AspectRatio)
+	}
+}
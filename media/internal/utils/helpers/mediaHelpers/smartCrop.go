@@ -0,0 +1,104 @@
+package mediahelpers
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Saliency is measured on a copy of the image no larger than this.
+const saliencySize = 96
+
+// FocalPoint is the point crops are centered on, relative to the image: 0,0
+// is the top left corner and 1,1 the bottom right one.
+type FocalPoint struct {
+	X float64
+	Y float64
+}
+
+// FacesFocus returns the center of the faces, false when there are none.
+func FacesFocus(bounds image.Rectangle, faces []FaceBox) (FocalPoint, bool) {
+	if len(faces) == 0 || bounds.Empty() {
+		return FocalPoint{}, false
+	}
+	union := image.Rect(faces[0].X, faces[0].Y, faces[0].X+faces[0].Width, faces[0].Y+faces[0].Height)
+	for _, face := range faces[1:] {
+		union = union.Union(image.Rect(face.X, face.Y, face.X+face.Width, face.Y+face.Height))
+	}
+	return FocalPoint{
+		X: float64(union.Min.X+union.Dx()/2-bounds.Min.X) / float64(bounds.Dx()),
+		Y: float64(union.Min.Y+union.Dy()/2-bounds.Min.Y) / float64(bounds.Dy()),
+	}, true
+}
+
+// SaliencyFocus returns the center of the most eye catching region of img.
+// Every pixel is scored by its local contrast, saturation and whether it
+// looks like skin, and the focus is the centroid of the pixels scoring above
+// average, slightly pulled towards the center of the image.
+func SaliencyFocus(img image.Image) FocalPoint {
+	small := imaging.Fit(img, saliencySize, saliencySize, imaging.Box)
+	width, height := small.Bounds().Dx(), small.Bounds().Dy()
+	if width < 3 || height < 3 {
+		return FocalPoint{X: 0.5, Y: 0.5}
+	}
+	luma := make([]float64, width*height)
+	saliency := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := small.Pix[y*small.Stride+x*4 : y*small.Stride+x*4+3]
+			r, g, b := float64(pixel[0]), float64(pixel[1]), float64(pixel[2])
+			luma[y*width+x] = 0.299*r + 0.587*g + 0.114*b
+			high, low := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+			saliency[y*width+x] = 0.5 * (high - low) / 255
+			if r > 95 && g > 40 && b > 20 && r > g && r > b && r-low > 15 {
+				saliency[y*width+x] += 0.5
+			}
+		}
+	}
+	var total float64
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			i := y*width + x
+			contrast := math.Abs(luma[i+1]-luma[i-1]) + math.Abs(luma[i+width]-luma[i-width])
+			saliency[i] += contrast / 255
+			total += saliency[i]
+		}
+	}
+	mean := total / float64((width-2)*(height-2))
+
+	var sumX, sumY, sumWeight float64
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			excess := saliency[y*width+x] - mean
+			if excess <= 0 {
+				continue
+			}
+			dx, dy := (float64(x)+0.5)/float64(width)-0.5, (float64(y)+0.5)/float64(height)-0.5
+			weight := excess * excess * (1 - math.Hypot(dx, dy)/2)
+			sumX += weight * (float64(x) + 0.5)
+			sumY += weight * (float64(y) + 0.5)
+			sumWeight += weight
+		}
+	}
+	if sumWeight == 0 {
+		return FocalPoint{X: 0.5, Y: 0.5}
+	}
+	return FocalPoint{X: sumX / sumWeight / float64(width), Y: sumY / sumWeight / float64(height)}
+}
+
+// FocalCrop returns the largest rectangle with the given width to height
+// ratio inside bounds, centered on focus as far as the edges allow.
+func FocalCrop(bounds image.Rectangle, focus FocalPoint, aspectRatio float64) image.Rectangle {
+	width, height := bounds.Dx(), bounds.Dy()
+	if float64(width) > float64(height)*aspectRatio {
+		width = int(math.Round(float64(height) * aspectRatio))
+	} else {
+		height = int(math.Round(float64(width) / aspectRatio))
+	}
+	centerX := bounds.Min.X + int(math.Round(focus.X*float64(bounds.Dx())))
+	centerY := bounds.Min.Y + int(math.Round(focus.Y*float64(bounds.Dy())))
+	x := min(max(centerX-width/2, bounds.Min.X), bounds.Max.X-width)
+	y := min(max(centerY-height/2, bounds.Min.Y), bounds.Max.Y-height)
+	return image.Rect(x, y, x+width, y+height)
+}
@@ -0,0 +1,33 @@
+package mediahelpers
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestSaliencyFocusFindsDetail(t *testing.T) {
+	img := imaging.New(600, 400, color.NRGBA{R: 120, G: 120, B: 120, A: 255})
+	detail := checkerboardImage(120, 120)
+	img = imaging.Paste(img, detail, image.Pt(420, 40))
+	focus := SaliencyFocus(img)
+	if focus.X < 0.7 || focus.X > 0.9 || focus.Y < 0.1 || focus.Y > 0.3 {
+		t.Errorf("expected the focus on the detailed patch around (0.8, 0.25), got %+v", focus)
+	}
+	flat := SaliencyFocus(imaging.New(200, 200, color.NRGBA{R: 10, G: 200, B: 10, A: 255}))
+	if flat != (FocalPoint{X: 0.5, Y: 0.5}) {
+		t.Errorf("expected a flat image to be focused on its center, got %+v", flat)
+	}
+}
+
+func TestFocalCropKeepsAspectRatioInsideBounds(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 300)
+	if crop := FocalCrop(bounds, FocalPoint{X: 0.9, Y: 0.1}, 1); crop != image.Rect(100, 0, 400, 300) {
+		t.Errorf("expected a square clamped to the right edge, got %v", crop)
+	}
+	if crop := FocalCrop(bounds, FocalPoint{X: 0.5, Y: 0.1}, 2); crop != image.Rect(0, 0, 400, 200) {
+		t.Errorf("expected a wide crop clamped to the top edge, got %v", crop)
+	}
+}
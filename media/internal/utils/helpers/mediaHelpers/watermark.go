@@ -0,0 +1,239 @@
+package mediahelpers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// Watermarks carry a 12 byte payload (a viewer profile ID) and its CRC-32,
+// one bit per 8x8 luminance block: the sign of the difference of two low
+// frequency DCT coefficients. Unlike the coefficients themselves the sign is
+// kept through recompression and the loss of contrast rescaling causes. The
+// bits are laid out on a tile of 16x8 blocks repeated over the whole image, so
+// any crop holding a tile carries every bit, and read back by majority vote.
+const (
+	WatermarkPayloadSize = 12
+	watermarkBits        = WatermarkPayloadSize*8 + 32
+	watermarkTileWidth   = 16
+	watermarkTileHeight  = watermarkBits / watermarkTileWidth
+	watermarkBlockSize   = 8
+	// Minimum difference the coefficients are pushed apart by. Larger margins
+	// survive stronger compression but become visible.
+	watermarkMargin = 24.0
+	// Blocks that would need a larger change are left alone, the other
+	// copies of their bit carry it.
+	watermarkMaxChange = 48.0
+	// Blocks whose coefficients differ by less are skipped when reading.
+	watermarkMinDifference = 1.0
+	// Block grids tried when reading a mark, best agreement first.
+	watermarkGridCandidates = 4
+)
+
+// The coefficient pair, (u, v) frequencies of the two basis functions. The
+// lowest horizontal and vertical frequencies are the ones downscaling keeps.
+var watermarkCoefficients = [2][2]int{{1, 0}, {0, 1}}
+
+var dctCosines = func() [watermarkBlockSize][watermarkBlockSize]float64 {
+	var cosines [watermarkBlockSize][watermarkBlockSize]float64
+	for k := 0; k < watermarkBlockSize; k++ {
+		scale := math.Sqrt(2.0 / watermarkBlockSize)
+		if k == 0 {
+			scale = math.Sqrt(1.0 / watermarkBlockSize)
+		}
+		for n := 0; n < watermarkBlockSize; n++ {
+			cosines[k][n] = scale * math.Cos(math.Pi*float64(2*n+1)*float64(k)/(2*watermarkBlockSize))
+		}
+	}
+	return cosines
+}()
+
+// blockCoefficient returns a DCT coefficient of the block at (x0, y0).
+func blockCoefficient(luma []float64, width int, x0 int, y0 int, u int, v int) float64 {
+	var coefficient float64
+	for y := 0; y < watermarkBlockSize; y++ {
+		for x := 0; x < watermarkBlockSize; x++ {
+			coefficient += dctCosines[v][y] * dctCosines[u][x] * luma[(y0+y)*width+x0+x]
+		}
+	}
+	return coefficient
+}
+
+func blockDifference(luma []float64, width int, x0 int, y0 int) float64 {
+	first, second := watermarkCoefficients[0], watermarkCoefficients[1]
+	return blockCoefficient(luma, width, x0, y0, first[0], first[1]) - blockCoefficient(luma, width, x0, y0, second[0], second[1])
+}
+
+func watermarkPayloadBits(payload [WatermarkPayloadSize]byte) []int {
+	message := make([]byte, WatermarkPayloadSize+4)
+	copy(message, payload[:])
+	binary.BigEndian.PutUint32(message[WatermarkPayloadSize:], crc32.ChecksumIEEE(payload[:]))
+	bits := make([]int, 0, watermarkBits)
+	for _, b := range message {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, int(b>>i)&1)
+		}
+	}
+	return bits
+}
+
+// watermarkBitIndex returns the bit carried by the block at (bx, by) of a
+// grid whose first block is at (tileX, tileY) of the tile.
+func watermarkBitIndex(bx int, by int, tileX int, tileY int) int {
+	return ((by+tileY)%watermarkTileHeight)*watermarkTileWidth + (bx+tileX)%watermarkTileWidth
+}
+
+func lumaOf(img *image.NRGBA) []float64 {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	luma := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := img.NRGBAAt(x, y)
+			luma[y*width+x] = 0.299*float64(pixel.R) + 0.587*float64(pixel.G) + 0.114*float64(pixel.B)
+		}
+	}
+	return luma
+}
+
+func clampByte(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(value))))
+}
+
+// EmbedWatermark returns a copy of img carrying payload. The tile starts at
+// the top left block, a 1 makes the first coefficient of the pair larger than
+// the second, a 0 smaller.
+func EmbedWatermark(img image.Image, payload [WatermarkPayloadSize]byte) (*image.NRGBA, error) {
+	marked := imaging.Clone(img)
+	width, height := marked.Rect.Dx(), marked.Rect.Dy()
+	blocksX, blocksY := width/watermarkBlockSize, height/watermarkBlockSize
+	if blocksX < watermarkTileWidth || blocksY < watermarkTileHeight {
+		return nil, fmt.Errorf("image is too small to carry a watermark")
+	}
+	bits := watermarkPayloadBits(payload)
+	luma := lumaOf(marked)
+	first, second := watermarkCoefficients[0], watermarkCoefficients[1]
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			x0, y0 := bx*watermarkBlockSize, by*watermarkBlockSize
+			target := watermarkMargin
+			if bits[watermarkBitIndex(bx, by, 0, 0)] == 0 {
+				target = -watermarkMargin
+			}
+			difference := blockDifference(luma, width, x0, y0)
+			if (target > 0 && difference >= target) || (target < 0 && difference <= target) {
+				continue
+			}
+			delta := (target - difference) / 2
+			if math.Abs(delta) > watermarkMaxChange {
+				continue
+			}
+			// Adding a multiple of a basis function changes only its own
+			// coefficient, the same shift is applied to all three channels
+			for y := 0; y < watermarkBlockSize; y++ {
+				for x := 0; x < watermarkBlockSize; x++ {
+					shift := delta * (dctCosines[first[1]][y]*dctCosines[first[0]][x] - dctCosines[second[1]][y]*dctCosines[second[0]][x])
+					offset := marked.PixOffset(x0+x, y0+y)
+					marked.Pix[offset] = clampByte(float64(marked.Pix[offset]) + shift)
+					marked.Pix[offset+1] = clampByte(float64(marked.Pix[offset+1]) + shift)
+					marked.Pix[offset+2] = clampByte(float64(marked.Pix[offset+2]) + shift)
+				}
+			}
+		}
+	}
+	return marked, nil
+}
+
+// watermarkGrid holds the votes of the blocks of a grid starting at pixel
+// (offsetX, offsetY), summed per position on the tile.
+type watermarkGrid struct {
+	votes      [watermarkBits]int
+	counts     [watermarkBits]int
+	confidence float64
+}
+
+func readWatermarkGrid(luma []float64, width int, height int, offsetX int, offsetY int) watermarkGrid {
+	var grid watermarkGrid
+	blocksX, blocksY := (width-offsetX)/watermarkBlockSize, (height-offsetY)/watermarkBlockSize
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			index := watermarkBitIndex(bx, by, 0, 0)
+			difference := blockDifference(luma, width, offsetX+bx*watermarkBlockSize, offsetY+by*watermarkBlockSize)
+			// Flat blocks, like borders around a screenshot, carry no bit
+			if math.Abs(difference) < watermarkMinDifference {
+				continue
+			}
+			if difference > 0 {
+				grid.votes[index]++
+			}
+			grid.counts[index]++
+		}
+	}
+	agreeing, total := 0, 0
+	for i := range grid.votes {
+		agreeing += max(grid.votes[i], grid.counts[i]-grid.votes[i])
+		total += grid.counts[i]
+	}
+	if total > 0 {
+		grid.confidence = float64(agreeing) / float64(total)
+	}
+	return grid
+}
+
+// decode reads the message with the tile shifted by (tileX, tileY) blocks and
+// reports whether its checksum matches.
+func (grid *watermarkGrid) decode(tileX int, tileY int) ([WatermarkPayloadSize]byte, bool) {
+	var payload [WatermarkPayloadSize]byte
+	message := make([]byte, WatermarkPayloadSize+4)
+	for y := 0; y < watermarkTileHeight; y++ {
+		for x := 0; x < watermarkTileWidth; x++ {
+			position := y*watermarkTileWidth + x
+			if grid.votes[position]*2 <= grid.counts[position] {
+				continue
+			}
+			bit := watermarkBitIndex(x, y, tileX, tileY)
+			message[bit/8] |= byte(1 << (7 - bit%8))
+		}
+	}
+	copy(payload[:], message[:WatermarkPayloadSize])
+	return payload, binary.BigEndian.Uint32(message[WatermarkPayloadSize:]) == crc32.ChecksumIEEE(payload[:])
+}
+
+// ExtractWatermark reads the payload back from a marked image at the scale of
+// the rendition it was cut from. The image may be cropped anywhere: every
+// alignment of the block grid is tried, and the tile is found at every shift
+// by its checksum. Confidence is the share of blocks that agreed with the
+// majority, 0.5 means noise.
+func ExtractWatermark(img image.Image) ([WatermarkPayloadSize]byte, float64, error) {
+	var payload [WatermarkPayloadSize]byte
+	marked := imaging.Clone(img)
+	width, height := marked.Rect.Dx(), marked.Rect.Dy()
+	if width/watermarkBlockSize-1 < watermarkTileWidth || height/watermarkBlockSize-1 < watermarkTileHeight {
+		return payload, 0, fmt.Errorf("image is too small to carry a watermark")
+	}
+	luma := lumaOf(marked)
+	grids := make([]watermarkGrid, 0, watermarkBlockSize*watermarkBlockSize)
+	for offsetY := 0; offsetY < watermarkBlockSize; offsetY++ {
+		for offsetX := 0; offsetX < watermarkBlockSize; offsetX++ {
+			grids = append(grids, readWatermarkGrid(luma, width, height, offsetX, offsetY))
+		}
+	}
+	// The aligned grid agrees the most, straddling blocks look like noise
+	sort.SliceStable(grids, func(i, j int) bool {
+		return grids[i].confidence > grids[j].confidence
+	})
+	for _, grid := range grids[:watermarkGridCandidates] {
+		for tileY := 0; tileY < watermarkTileHeight; tileY++ {
+			for tileX := 0; tileX < watermarkTileWidth; tileX++ {
+				if payload, ok := grid.decode(tileX, tileY); ok {
+					return payload, grid.confidence, nil
+				}
+			}
+		}
+	}
+	return payload, grids[0].confidence, fmt.Errorf("no watermark found")
+}
@@ -0,0 +1,109 @@
+package mediahelpers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func photoLikeImage(width int, height int) image.Image {
+	img := gradientImage(width, height).(*image.NRGBA)
+	random := rand.New(rand.NewPCG(1, 2))
+	for i := range img.Pix {
+		if i%4 != 3 {
+			img.Pix[i] = clampByte(float64(img.Pix[i]) + random.NormFloat64()*6)
+		}
+	}
+	return img
+}
+
+func reencode(t *testing.T, img image.Image, quality int) image.Image {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestWatermarkSurvivesJPEGAndRescaling(t *testing.T) {
+	payload := [WatermarkPayloadSize]byte{0x65, 0x1f, 0x2a, 0x9c, 0x00, 0x11, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45}
+	original := photoLikeImage(640, 800)
+	marked, err := EmbedWatermark(original, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaked := reencode(t, marked, 75)
+	extracted, confidence, err := ExtractWatermark(leaked)
+	if err != nil {
+		t.Fatalf("could not extract after recompression: %v (confidence %.2f)", err, confidence)
+	}
+	if extracted != payload {
+		t.Errorf("expected %x, got %x", payload, extracted)
+	}
+
+	// A screenshot at another size, scaled back to the rendition size
+	screenshot := reencode(t, imaging.Resize(marked, 480, 600, imaging.Lanczos), 85)
+	extracted, _, err = ExtractWatermark(imaging.Resize(screenshot, 640, 800, imaging.Lanczos))
+	if err != nil || extracted != payload {
+		t.Errorf("could not extract after rescaling: %v", err)
+	}
+}
+
+func TestWatermarkSurvivesCropping(t *testing.T) {
+	payload := [WatermarkPayloadSize]byte{0x65, 0x1f, 0x2a, 0x9c, 0x00, 0x11, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45}
+	marked, err := EmbedWatermark(photoLikeImage(640, 800), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A crop off the block grid, pasted into a screenshot with app chrome
+	crop := imaging.Crop(marked, image.Rect(37, 53, 437, 553))
+	screenshot := imaging.New(460, 700, color.White)
+	screenshot = imaging.Paste(screenshot, crop, image.Pt(30, 115))
+	extracted, confidence, err := ExtractWatermark(reencode(t, screenshot, 85))
+	if err != nil {
+		t.Fatalf("could not extract from a crop: %v (confidence %.2f)", err, confidence)
+	}
+	if extracted != payload {
+		t.Errorf("expected %x, got %x", payload, extracted)
+	}
+}
+
+func TestWatermarkIsInvisibleAndAbsentFromUnmarkedImages(t *testing.T) {
+	original := photoLikeImage(320, 320)
+	marked, err := EmbedWatermark(original, [WatermarkPayloadSize]byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := imaging.Clone(original)
+	var squaredError float64
+	for i := range source.Pix {
+		if i%4 == 3 {
+			continue
+		}
+		diff := float64(source.Pix[i]) - float64(marked.Pix[i])
+		squaredError += diff * diff
+	}
+	psnr := 10 * math.Log10(255*255/(squaredError/float64(len(source.Pix)/4*3)))
+	if psnr < 38 {
+		t.Errorf("watermark is too visible, PSNR %.1f dB", psnr)
+	}
+
+	if _, _, err := ExtractWatermark(original); err == nil {
+		t.Error("expected no watermark in an unmarked image")
+	}
+	if _, err := EmbedWatermark(photoLikeImage(40, 40), [WatermarkPayloadSize]byte{}); err == nil {
+		t.Error("expected an error for an image too small to carry the payload")
+	}
+}
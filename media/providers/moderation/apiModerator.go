@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"time"
+)
+
+// APIModerator sends photos to an external moderation API. The photo is
+// posted as {"image": "<base64 jpeg>"} and the API answers with the score of
+// every category it checked, {"labels": {"nudity": 0.92, ...}}.
+type APIModerator struct {
+	URL        string
+	APIKey     string
+	Thresholds Thresholds
+	Client     *http.Client
+}
+
+func NewAPIModerator(url string, apiKey string, thresholds Thresholds) *APIModerator {
+	return &APIModerator{
+		URL:        url,
+		APIKey:     apiKey,
+		Thresholds: thresholds,
+		Client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type apiModerationRequest struct {
+	Image string `json:"image"`
+}
+
+type apiModerationResponse struct {
+	Labels map[string]float64 `json:"labels"`
+}
+
+func (moderator *APIModerator) Moderate(ctx context.Context, img image.Image) (*ModerationResult, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(apiModerationRequest{
+		Image: base64.StdEncoding.EncodeToString(buf.Bytes()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, moderator.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if moderator.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+moderator.APIKey)
+	}
+	res, err := moderator.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation API responded with status %d", res.StatusCode)
+	}
+	var moderationRes apiModerationResponse
+	if err := json.NewDecoder(res.Body).Decode(&moderationRes); err != nil {
+		return nil, err
+	}
+	return &ModerationResult{
+		Verdict:  moderator.Thresholds.verdict(moderationRes.Labels),
+		Labels:   moderationRes.Labels,
+		Provider: "api",
+	}, nil
+}
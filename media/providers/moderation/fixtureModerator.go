@@ -0,0 +1,63 @@
+package moderation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"os"
+
+	"github.com/disintegration/imaging"
+)
+
+// FixtureModerator replays recorded verdicts, keyed by the Fingerprint of the
+// photo. Photos without a recording get the Fallback verdict. It is meant for
+// tests and local runs where the outcome has to be known up front.
+type FixtureModerator struct {
+	Fixtures map[string]ModerationResult
+	Fallback string
+}
+
+func NewFixtureModerator(fixtures map[string]ModerationResult, fallback string) *FixtureModerator {
+	if fallback == "" {
+		fallback = VerdictApprove
+	}
+	return &FixtureModerator{
+		Fixtures: fixtures,
+		Fallback: fallback,
+	}
+}
+
+// LoadFixtureModerator reads recordings from a JSON object of fingerprint to
+// result.
+func LoadFixtureModerator(path string, fallback string) (*FixtureModerator, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixtures := map[string]ModerationResult{}
+	if err := json.Unmarshal(file, &fixtures); err != nil {
+		return nil, err
+	}
+	return NewFixtureModerator(fixtures, fallback), nil
+}
+
+// Fingerprint hashes the decoded pixels, so the same photo matches its
+// recording whatever format it was stored in.
+func Fingerprint(img image.Image) string {
+	pixels := imaging.Clone(img)
+	hash := sha256.New()
+	hash.Write([]byte{byte(pixels.Rect.Dx() >> 8), byte(pixels.Rect.Dx()), byte(pixels.Rect.Dy() >> 8), byte(pixels.Rect.Dy())})
+	hash.Write(pixels.Pix)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (moderator *FixtureModerator) Moderate(ctx context.Context, img image.Image) (*ModerationResult, error) {
+	result, ok := moderator.Fixtures[Fingerprint(img)]
+	if !ok {
+		result = ModerationResult{Verdict: moderator.Fallback}
+	}
+	result.Provider = "fixture"
+	return &result, nil
+}
@@ -0,0 +1,62 @@
+package moderation
+
+import (
+	"context"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// The photo is scanned on a copy scaled down to this width.
+const heuristicSampleWidth = 128
+
+// HeuristicModerator is the local moderator, it needs no external service.
+// It scores the share of skin toned pixels, which is high for photos showing
+// a lot of bare skin. It never rejects on its own, photos above the threshold
+// are sent to review.
+type HeuristicModerator struct {
+	Thresholds Thresholds
+}
+
+func NewHeuristicModerator(reviewThreshold float64) *HeuristicModerator {
+	return &HeuristicModerator{
+		Thresholds: Thresholds{Review: reviewThreshold},
+	}
+}
+
+// isSkin is the RGB skin color rule of Kovac et al. for uniform daylight.
+func isSkin(r int, g int, b int) bool {
+	return r > 95 && g > 40 && b > 20 &&
+		max(r, g, b)-min(r, g, b) > 15 &&
+		r-g > 15 && r > b
+}
+
+func SkinRatio(img image.Image) float64 {
+	sample := imaging.Resize(img, heuristicSampleWidth, 0, imaging.Box)
+	bounds := sample.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return 0
+	}
+	skin := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := sample.NRGBAAt(x, y)
+			if isSkin(int(pixel.R), int(pixel.G), int(pixel.B)) {
+				skin++
+			}
+		}
+	}
+	return float64(skin) / float64(total)
+}
+
+func (moderator *HeuristicModerator) Moderate(ctx context.Context, img image.Image) (*ModerationResult, error) {
+	labels := map[string]float64{
+		"skin": SkinRatio(img),
+	}
+	return &ModerationResult{
+		Verdict:  moderator.Thresholds.verdict(labels),
+		Labels:   labels,
+		Provider: "heuristic",
+	}, nil
+}
@@ -0,0 +1,93 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestHeuristicModeratorReviewsSkinHeavyPhotos(t *testing.T) {
+	moderator := NewHeuristicModerator(0.5)
+
+	skin := imaging.New(64, 64, color.NRGBA{R: 220, G: 160, B: 130, A: 255})
+	result, err := moderator.Moderate(context.Background(), skin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != VerdictReview {
+		t.Errorf("expected review, got %s (%v)", result.Verdict, result.Labels)
+	}
+
+	landscape := imaging.New(64, 64, color.NRGBA{R: 40, G: 120, B: 200, A: 255})
+	result, err = moderator.Moderate(context.Background(), landscape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != VerdictApprove {
+		t.Errorf("expected approve, got %s (%v)", result.Verdict, result.Labels)
+	}
+}
+
+func TestFixtureModeratorReplaysRecordings(t *testing.T) {
+	flagged := imaging.New(8, 8, color.NRGBA{R: 255, A: 255})
+	moderator := NewFixtureModerator(map[string]ModerationResult{
+		Fingerprint(flagged): {Verdict: VerdictReject, Labels: map[string]float64{"nudity": 0.97}},
+	}, "")
+
+	result, _ := moderator.Moderate(context.Background(), flagged)
+	if result.Verdict != VerdictReject || result.Labels["nudity"] != 0.97 {
+		t.Errorf("recording not replayed: %+v", result)
+	}
+	result, _ = moderator.Moderate(context.Background(), imaging.New(8, 8, color.NRGBA{B: 255, A: 255}))
+	if result.Verdict != VerdictApprove {
+		t.Errorf("expected fallback approve, got %s", result.Verdict)
+	}
+}
+
+func TestAPIModeratorMapsScoresToVerdicts(t *testing.T) {
+	scores := map[string]float64{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body apiModerationRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Image == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(apiModerationResponse{Labels: scores})
+	}))
+	defer server.Close()
+
+	moderator := NewAPIModerator(server.URL, "key", Thresholds{Review: 0.5, Reject: 0.9})
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for _, tc := range []struct {
+		score   float64
+		verdict string
+	}{
+		{0.1, VerdictApprove},
+		{0.6, VerdictReview},
+		{0.95, VerdictReject},
+	} {
+		scores["nudity"] = tc.score
+		result, err := moderator.Moderate(context.Background(), img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Verdict != tc.verdict {
+			t.Errorf("score %v: expected %s, got %s", tc.score, tc.verdict, result.Verdict)
+		}
+	}
+
+	moderator.APIKey = "wrong"
+	if _, err := moderator.Moderate(context.Background(), img); err == nil {
+		t.Error("expected an error when the API refuses the request")
+	}
+}
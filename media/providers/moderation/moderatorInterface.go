@@ -0,0 +1,11 @@
+package moderation
+
+import (
+	"context"
+	"image"
+)
+
+// Moderator screens an uploaded photo and returns a verdict for it.
+type Moderator interface {
+	Moderate(ctx context.Context, img image.Image) (*ModerationResult, error)
+}
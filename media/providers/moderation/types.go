@@ -0,0 +1,36 @@
+package moderation
+
+const (
+	VerdictApprove = "approve"
+	VerdictReview  = "review"
+	VerdictReject  = "reject"
+)
+
+// ModerationResult is the verdict for a photo. Labels holds the score (0 to 1)
+// of every category the moderator checked, Provider names the moderator that
+// produced it.
+type ModerationResult struct {
+	Verdict  string             `json:"verdict"`
+	Labels   map[string]float64 `json:"labels,omitempty"`
+	Provider string             `json:"provider,omitempty"`
+}
+
+// Thresholds turn the highest label score into a verdict.
+type Thresholds struct {
+	Review float64
+	Reject float64
+}
+
+func (thresholds Thresholds) verdict(labels map[string]float64) string {
+	highest := 0.0
+	for _, score := range labels {
+		highest = max(highest, score)
+	}
+	if thresholds.Reject > 0 && highest >= thresholds.Reject {
+		return VerdictReject
+	}
+	if thresholds.Review > 0 && highest >= thresholds.Review {
+		return VerdictReview
+	}
+	return VerdictApprove
+}
@@ -1,12 +1,9 @@
 package storage
 
 import (
-	"bytes"
 	"fmt"
-	"math"
 	"media/internal/utils/constants"
-	"net/http"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,9 +20,10 @@ var (
 
 type AWSStorageProvider struct {
 	clientInstance *s3.S3
+	publicBaseURL  string
 }
 
-func NewAWSStorageProvider(region string, accessKey string, secretKey string) (*AWSStorageProvider, error) {
+func NewAWSStorageProvider(region string, accessKey string, secretKey string, publicBaseURL string) (*AWSStorageProvider, error) {
 	var err error
 	once.Do(func() {
 		sess, sessionErr := session.NewSession(&aws.Config{
@@ -39,6 +37,7 @@ func NewAWSStorageProvider(region string, accessKey string, secretKey string) (*
 
 		instance = &AWSStorageProvider{
 			clientInstance: s3.New(sess),
+			publicBaseURL:  publicBaseURL,
 		}
 	})
 
@@ -94,8 +93,7 @@ func (provider *AWSStorageProvider) GenerateSignedURLsForParts(bucket string, fi
 	presignedURLs := make(map[int]string)
 	client := provider.clientInstance
 	expiry := 10 * time.Minute
-	partSize := 5 * 1024 * 1024
-	partsCount := int(math.Ceil(float64(fileSize) / float64(partSize)))
+	partsCount := PartsCount(fileSize)
 	ext := constants.FileExtMap[contentType]
 	formattedFilePath := filePath + "/" + fileName + "." + ext
 	for partNumber := 1; partNumber <= partsCount; partNumber++ {
@@ -131,12 +129,16 @@ func (provider *AWSStorageProvider) CompleteMultipartUpload(bucket string, uploa
 	ext := constants.FileExtMap[contentType]
 	formattedFilePath := filePath + "/" + fileName + "." + ext
 
+	// Parts have to be listed in ascending order
 	for key, value := range parts {
 		partList = append(partList, &s3.CompletedPart{
 			PartNumber: aws.Int64(int64(key)),
 			ETag:       aws.String(value),
 		})
 	}
+	sort.Slice(partList, func(i, j int) bool {
+		return *partList[i].PartNumber < *partList[j].PartNumber
+	})
 	input := &s3.CompleteMultipartUploadInput{
 		Bucket:   aws.String(bucket),
 		UploadId: aws.String(uploadID),
@@ -149,7 +151,7 @@ func (provider *AWSStorageProvider) CompleteMultipartUpload(bucket string, uploa
 	if err != nil {
 		return nil, err
 	}
-	awsBaseURL := "https://dl1b79m70nfwv.cloudfront.net"
+	awsBaseURL := provider.publicBaseURL
 	objUrl := fmt.Sprintf("%s/%s", awsBaseURL, formattedFilePath)
 
 	headObjectInput := &s3.HeadObjectInput{
@@ -172,66 +174,33 @@ func (provider *AWSStorageProvider) CompleteMultipartUpload(bucket string, uploa
 	return &res, nil
 }
 
-func uploadFile(signedURL string, data []byte, contentType string) (*string, error) {
-	req, err := http.NewRequest("PUT", signedURL, bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", contentType)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (provider *AWSStorageProvider) AbortMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string) error {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	_, err := provider.clientInstance.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(formattedFilePath),
+		UploadId: aws.String(uploadID),
+	})
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("upload failed with status code %d", resp.StatusCode)
-	}
-
-	etag := resp.Header.Get("ETag")
-	if etag == "" {
-		return nil, fmt.Errorf("ETag not found in response headers")
+		return fmt.Errorf("failed to abort upload of %s: %v", formattedFilePath, err)
 	}
-
-	etag = strings.Replace(etag, "\"", "", -1)
-
-	return &etag, nil
+	return nil
 }
 
 func (provider *AWSStorageProvider) UploadFile(signedUrls map[int]string, file []byte, partSize int, contentType string) (map[int]string, error) {
-	var partPromises []chan string
-	var wg sync.WaitGroup
-	for partIndex, signedURL := range signedUrls {
-		wg.Add(1)
-		start := (partIndex - 1) * partSize
-		end := int(math.Min(float64(start+partSize), float64(len(file))))
-
-		filePart := file[start:end]
-
-		errChan := make(chan string, 1)
-		partPromises = append(partPromises, errChan)
-
-		go func(signedURL string, filePart []byte, errChan chan string) {
-			defer wg.Done()
-			etagPtr, err := uploadFile(signedURL, filePart, contentType)
-			if err != nil {
-				errChan <- ""
-			} else {
-				errChan <- *etagPtr
-			}
-		}(signedURL, filePart, errChan)
-	}
-
-	wg.Wait()
+	return uploadParts(signedUrls, file, partSize, contentType)
+}
 
-	var result map[int]string = map[int]string{}
-	for idx, resChan := range partPromises {
-		etag := <-resChan
-		result[idx+1] = etag
+func (provider *AWSStorageProvider) DeleteFile(bucket string, filePath string, fileName string, contentType string) error {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	_, err := provider.clientInstance.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(formattedFilePath),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %v", formattedFilePath, err)
 	}
-
-	return result, nil
+	return nil
 }
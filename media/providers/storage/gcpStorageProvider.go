@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"media/internal/utils/constants"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// GCPStorageProvider stores media in Google Cloud Storage. GCS has no S3 style
+// multipart API in its JSON API, so uploads go through the XML API multipart
+// upload, which accepts V4 signed URLs and keeps the same contract as S3:
+// initiate, PUT every part to its own signed URL, complete with the ETags.
+type GCPStorageProvider struct {
+	clientInstance *gcs.Client
+	httpClient     *http.Client
+	publicBaseURL  string
+}
+
+type gcsInitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type gcsCompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type gcsCompleteMultipartUpload struct {
+	XMLName xml.Name           `xml:"CompleteMultipartUpload"`
+	Parts   []gcsCompletedPart `xml:"Part"`
+}
+
+func NewGCPStorageProvider(ctx context.Context, credentialsFile string, publicBaseURL string) (*GCPStorageProvider, error) {
+	var opts []option.ClientOption
+	if credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(credentialsFile))
+	}
+	client, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &GCPStorageProvider{
+		clientInstance: client,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		publicBaseURL:  strings.TrimRight(publicBaseURL, "/"),
+	}, nil
+}
+
+func (provider *GCPStorageProvider) signURL(bucket string, key string, method string, contentType string, query url.Values, expiry time.Time) (string, error) {
+	return provider.clientInstance.Bucket(bucket).SignedURL(key, &gcs.SignedURLOptions{
+		Scheme:          gcs.SigningSchemeV4,
+		Method:          method,
+		Expires:         expiry,
+		ContentType:     contentType,
+		QueryParameters: query,
+	})
+}
+
+// xmlRequest signs and sends a request to the XML API on behalf of the service.
+func (provider *GCPStorageProvider) xmlRequest(bucket string, key string, method string, contentType string, query url.Values, body []byte) ([]byte, error) {
+	signedURL, err := provider.signURL(bucket, key, method, contentType, query, time.Now().Add(5*time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, signedURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := provider.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("gcs %s %s failed with status %d: %s", method, key, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+func (provider *GCPStorageProvider) GenerateSignedUrl(bucket string, filePath string, fileName string, contentType string, fileSize int) (*UploadSignedUrl, error) {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	expiry := time.Now().Add(10 * time.Minute)
+	signedUrl, err := provider.signURL(bucket, formattedFilePath, "GET", "", nil, expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadSignedUrl{
+		Bucket:    bucket,
+		FilePath:  formattedFilePath,
+		SignedUrl: signedUrl,
+		Expires:   expiry,
+	}, nil
+}
+
+func (provider *GCPStorageProvider) InitiateMultipartUpload(bucket string, filePath string, fileName string, contentType string, fileSize int) (*InitiateMultipartUpload, error) {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	respBody, err := provider.xmlRequest(bucket, formattedFilePath, "POST", contentType, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result gcsInitiateMultipartUploadResult
+	if err := xml.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse initiate multipart response: %v", err)
+	}
+	return &InitiateMultipartUpload{
+		Bucket:   bucket,
+		FilePath: formattedFilePath,
+		UploadId: result.UploadID,
+	}, nil
+}
+
+func (provider *GCPStorageProvider) GenerateSignedURLsForParts(bucket string, filePath string, fileName string, uploadID string, contentType string, fileSize int) (*GenerateSignedURLsForPartsResType, error) {
+	presignedURLs := make(map[int]string)
+	expiry := time.Now().Add(10 * time.Minute)
+	partsCount := PartsCount(fileSize)
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	for partNumber := 1; partNumber <= partsCount; partNumber++ {
+		query := url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {uploadID},
+		}
+		signedURL, err := provider.signURL(bucket, formattedFilePath, "PUT", "", query, expiry)
+		if err != nil {
+			return nil, err
+		}
+		presignedURLs[partNumber] = signedURL
+	}
+	return &GenerateSignedURLsForPartsResType{
+		SignedUrls: presignedURLs,
+		Expiry:     expiry,
+		PartsCount: partsCount,
+		URL:        formattedFilePath,
+	}, nil
+}
+
+func (provider *GCPStorageProvider) CompleteMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string, parts map[int]string) (*CompletedMultipartUploadResponseType, error) {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+
+	// Parts have to be listed in ascending order
+	completeUpload := gcsCompleteMultipartUpload{}
+	for partNumber, etag := range parts {
+		completeUpload.Parts = append(completeUpload.Parts, gcsCompletedPart{
+			PartNumber: partNumber,
+			ETag:       fmt.Sprintf("\"%s\"", strings.Trim(etag, "\"")),
+		})
+	}
+	sort.Slice(completeUpload.Parts, func(i, j int) bool {
+		return completeUpload.Parts[i].PartNumber < completeUpload.Parts[j].PartNumber
+	})
+	body, err := xml.Marshal(completeUpload)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := provider.xmlRequest(bucket, formattedFilePath, "POST", "application/xml", url.Values{"uploadId": {uploadID}}, body); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	attrs, err := provider.clientInstance.Bucket(bucket).Object(formattedFilePath).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve object metadata: %v", err)
+	}
+
+	// Buckets are served under the same host, the URL names the bucket
+	domain := provider.publicBaseURL + "/" + bucket
+	return &CompletedMultipartUploadResponseType{
+		URL:      fmt.Sprintf("%s/%s", domain, formattedFilePath),
+		Path:     filePath,
+		Domain:   domain,
+		FileSize: attrs.Size,
+	}, nil
+}
+
+func (provider *GCPStorageProvider) AbortMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string) error {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	if _, err := provider.xmlRequest(bucket, formattedFilePath, "DELETE", "", url.Values{"uploadId": {uploadID}}, nil); err != nil {
+		return fmt.Errorf("failed to abort upload of %s: %v", formattedFilePath, err)
+	}
+	return nil
+}
+
+func (provider *GCPStorageProvider) UploadFile(signedUrls map[int]string, file []byte, partSize int, contentType string) (map[int]string, error) {
+	return uploadParts(signedUrls, file, partSize, contentType)
+}
+
+func (provider *GCPStorageProvider) DeleteFile(bucket string, filePath string, fileName string, contentType string) error {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := provider.clientInstance.Bucket(bucket).Object(formattedFilePath).Delete(ctx)
+	if err != nil && err != gcs.ErrObjectNotExist {
+		return fmt.Errorf("failed to delete object %s: %v", formattedFilePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"media/internal/utils/constants"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+	ErrObjectNotFound   = errors.New("object not found")
+	ErrUploadNotFound   = errors.New("multipart upload not found")
+	ErrBadDigest        = errors.New("content does not match the provided MD5")
+)
+
+// LocalStorageProvider keeps objects on the local filesystem and hands out
+// signed URLs pointing back at the media service, which serves them through
+// the storage routes. It is meant for offline development.
+type LocalStorageProvider struct {
+	rootDir       string
+	baseURL       string
+	signingSecret string
+	publicBuckets map[string]bool
+}
+
+type localMultipartUpload struct {
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+}
+
+// NewLocalStorageProvider creates the provider, objects in publicBuckets can
+// be read without a signature like a bucket behind a CDN.
+func NewLocalStorageProvider(rootDir string, baseURL string, signingSecret string, publicBuckets []string) (*LocalStorageProvider, error) {
+	if err := os.MkdirAll(filepath.Join(rootDir, ".multipart"), 0o755); err != nil {
+		return nil, err
+	}
+	public := map[string]bool{}
+	for _, bucket := range publicBuckets {
+		public[bucket] = true
+	}
+	return &LocalStorageProvider{
+		rootDir:       rootDir,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		signingSecret: signingSecret,
+		publicBuckets: public,
+	}, nil
+}
+
+func (provider *LocalStorageProvider) IsPublicBucket(bucket string) bool {
+	return provider.publicBuckets[bucket]
+}
+
+func (provider *LocalStorageProvider) objectPath(bucket string, key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if bucket == "" || strings.Contains(bucket, "/") || strings.HasPrefix(bucket, ".") {
+		return "", fmt.Errorf("invalid bucket %q", bucket)
+	}
+	return filepath.Join(provider.rootDir, bucket, cleaned), nil
+}
+
+func (provider *LocalStorageProvider) uploadDir(uploadID string) (string, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", ErrUploadNotFound
+	}
+	return filepath.Join(provider.rootDir, ".multipart", uploadID), nil
+}
+
+func (provider *LocalStorageProvider) signature(method string, bucket string, key string, uploadID string, partNumber string, expires string) string {
+	mac := hmac.New(sha256.New, []byte(provider.signingSecret))
+	mac.Write([]byte(strings.Join([]string{method, bucket, key, uploadID, partNumber, expires}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (provider *LocalStorageProvider) signedURL(method string, bucket string, key string, uploadID string, partNumber string, expiry time.Time) string {
+	expires := strconv.FormatInt(expiry.Unix(), 10)
+	query := url.Values{}
+	if uploadID != "" {
+		query.Set("uploadId", uploadID)
+		query.Set("partNumber", partNumber)
+	}
+	query.Set("expires", expires)
+	query.Set("signature", provider.signature(method, bucket, key, uploadID, partNumber, expires))
+	return fmt.Sprintf("%s/%s/%s?%s", provider.baseURL, bucket, key, query.Encode())
+}
+
+// VerifySignature checks a signed URL issued by this provider.
+func (provider *LocalStorageProvider) VerifySignature(method string, bucket string, key string, uploadID string, partNumber string, expires string, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+	expected := provider.signature(method, bucket, key, uploadID, partNumber, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (provider *LocalStorageProvider) GenerateSignedUrl(bucket string, filePath string, fileName string, contentType string, fileSize int) (*UploadSignedUrl, error) {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	expiry := time.Now().Add(10 * time.Minute)
+	return &UploadSignedUrl{
+		Bucket:    bucket,
+		FilePath:  formattedFilePath,
+		SignedUrl: provider.signedURL("GET", bucket, formattedFilePath, "", "", expiry),
+		Expires:   expiry,
+	}, nil
+}
+
+func (provider *LocalStorageProvider) InitiateMultipartUpload(bucket string, filePath string, fileName string, contentType string, fileSize int) (*InitiateMultipartUpload, error) {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	uploadID := uuid.New().String()
+	dir, err := provider.uploadDir(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	meta, err := json.Marshal(localMultipartUpload{
+		Bucket:      bucket,
+		Key:         formattedFilePath,
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "upload.json"), meta, 0o644); err != nil {
+		return nil, err
+	}
+	return &InitiateMultipartUpload{
+		Bucket:   bucket,
+		FilePath: formattedFilePath,
+		UploadId: uploadID,
+	}, nil
+}
+
+func (provider *LocalStorageProvider) GenerateSignedURLsForParts(bucket string, filePath string, fileName string, uploadID string, contentType string, fileSize int) (*GenerateSignedURLsForPartsResType, error) {
+	presignedURLs := make(map[int]string)
+	expiry := time.Now().Add(10 * time.Minute)
+	partsCount := PartsCount(fileSize)
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	for partNumber := 1; partNumber <= partsCount; partNumber++ {
+		presignedURLs[partNumber] = provider.signedURL("PUT", bucket, formattedFilePath, uploadID, strconv.Itoa(partNumber), expiry)
+	}
+	return &GenerateSignedURLsForPartsResType{
+		SignedUrls: presignedURLs,
+		Expiry:     expiry,
+		PartsCount: partsCount,
+		URL:        formattedFilePath,
+	}, nil
+}
+
+func (provider *LocalStorageProvider) readUpload(uploadID string) (string, *localMultipartUpload, error) {
+	dir, err := provider.uploadDir(uploadID)
+	if err != nil {
+		return "", nil, err
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return "", nil, ErrUploadNotFound
+	}
+	var upload localMultipartUpload
+	if err := json.Unmarshal(raw, &upload); err != nil {
+		return "", nil, err
+	}
+	return dir, &upload, nil
+}
+
+// PutPart stores one part of a multipart upload and returns its ETag, the hex
+// MD5 of the part like S3 does. When contentMD5 (base64, as in the Content-MD5
+// header) is set, a part that does not match it is rejected.
+func (provider *LocalStorageProvider) PutPart(bucket string, key string, uploadID string, partNumber int, body io.Reader, contentMD5 string) (string, error) {
+	dir, upload, err := provider.readUpload(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if upload.Bucket != bucket || upload.Key != key {
+		return "", ErrUploadNotFound
+	}
+	partPath := filepath.Join(dir, strconv.Itoa(partNumber))
+	part, err := os.Create(partPath)
+	if err != nil {
+		return "", err
+	}
+	defer part.Close()
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(part, hash), body); err != nil {
+		return "", err
+	}
+	checksum := hash.Sum(nil)
+	if contentMD5 != "" && contentMD5 != base64.StdEncoding.EncodeToString(checksum) {
+		os.Remove(partPath)
+		return "", ErrBadDigest
+	}
+	return hex.EncodeToString(checksum), nil
+}
+
+func (provider *LocalStorageProvider) CompleteMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string, parts map[int]string) (*CompletedMultipartUploadResponseType, error) {
+	ext := constants.FileExtMap[contentType]
+	formattedFilePath := filePath + "/" + fileName + "." + ext
+	dir, upload, err := provider.readUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Bucket != bucket || upload.Key != formattedFilePath {
+		return nil, ErrUploadNotFound
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no parts to complete upload %s", uploadID)
+	}
+
+	partNumbers := make([]int, 0, len(parts))
+	for partNumber := range parts {
+		partNumbers = append(partNumbers, partNumber)
+	}
+	sort.Ints(partNumbers)
+
+	objectPath, err := provider.objectPath(bucket, formattedFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o755); err != nil {
+		return nil, err
+	}
+	tmpPath := filepath.Join(dir, "object")
+	object, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	var fileSize int64
+	for _, partNumber := range partNumbers {
+		written, err := appendPart(object, filepath.Join(dir, strconv.Itoa(partNumber)), strings.Trim(parts[partNumber], "\""))
+		if err != nil {
+			object.Close()
+			return nil, fmt.Errorf("part %d: %v", partNumber, err)
+		}
+		fileSize += written
+	}
+	if err := object.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, objectPath); err != nil {
+		return nil, err
+	}
+	os.RemoveAll(dir)
+
+	domain := provider.baseURL + "/" + bucket
+	return &CompletedMultipartUploadResponseType{
+		URL:      fmt.Sprintf("%s/%s", domain, formattedFilePath),
+		Path:     filePath,
+		Domain:   domain,
+		FileSize: fileSize,
+	}, nil
+}
+
+func appendPart(object io.Writer, partPath string, etag string) (int64, error) {
+	part, err := os.Open(partPath)
+	if err != nil {
+		return 0, fmt.Errorf("part was never uploaded")
+	}
+	defer part.Close()
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(object, hash), part)
+	if err != nil {
+		return 0, err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != etag {
+		return 0, fmt.Errorf("etag mismatch")
+	}
+	return written, nil
+}
+
+func (provider *LocalStorageProvider) AbortMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string) error {
+	dir, _, err := provider.readUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (provider *LocalStorageProvider) UploadFile(signedUrls map[int]string, file []byte, partSize int, contentType string) (map[int]string, error) {
+	return uploadParts(signedUrls, file, partSize, contentType)
+}
+
+func (provider *LocalStorageProvider) DeleteFile(bucket string, filePath string, fileName string, contentType string) error {
+	ext := constants.FileExtMap[contentType]
+	objectPath, err := provider.objectPath(bucket, filePath+"/"+fileName+"."+ext)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(objectPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Open returns the object for the storage routes to serve.
+func (provider *LocalStorageProvider) Open(bucket string, key string) (*os.File, error) {
+	objectPath, err := provider.objectPath(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(objectPath)
+	if err != nil {
+		return nil, ErrObjectNotFound
+	}
+	return file, nil
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PartSize is the size of every part of a multipart upload but the last one.
+// S3 rejects parts below 5MB unless they are the last part.
+const PartSize = 5 * 1024 * 1024
+
+const (
+	maxConcurrentPartUploads = 4
+	maxPartUploadAttempts    = 3
+	partUploadRetryBackoff   = 500 * time.Millisecond
+)
+
+var partUploadClient = &http.Client{Timeout: 60 * time.Second}
+
+// PartsCount returns how many parts of PartSize a file of fileSize is split into.
+func PartsCount(fileSize int) int {
+	return (fileSize + PartSize - 1) / PartSize
+}
+
+// errPermanent marks part upload errors that are not worth retrying.
+type errPermanent struct {
+	err error
+}
+
+func (e errPermanent) Error() string {
+	return e.err.Error()
+}
+
+func uploadFile(ctx context.Context, signedURL string, data []byte, contentType string) (string, error) {
+	checksum := md5.Sum(data)
+	req, err := http.NewRequestWithContext(ctx, "PUT", signedURL, bytes.NewReader(data))
+	if err != nil {
+		return "", errPermanent{err}
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	// The store rejects the part if it does not match what was sent
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(checksum[:]))
+
+	resp, err := partUploadClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("upload failed with status code %d", resp.StatusCode)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return "", errPermanent{err}
+		}
+		return "", err
+	}
+
+	// The ETag is only the MD5 of the part without server side encryption,
+	// the Content-MD5 header already has the store check the content
+	etag := strings.Trim(resp.Header.Get("ETag"), "\"")
+	if etag == "" {
+		return "", fmt.Errorf("ETag not found in response headers")
+	}
+
+	return etag, nil
+}
+
+// uploadPart uploads a single part, retrying transient failures with an
+// exponential backoff.
+func uploadPart(ctx context.Context, signedURL string, data []byte, contentType string) (string, error) {
+	var err error
+	for attempt := 0; attempt < maxPartUploadAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(partUploadRetryBackoff << (attempt - 1)):
+			}
+		}
+		var etag string
+		etag, err = uploadFile(ctx, signedURL, data, contentType)
+		if err == nil {
+			return etag, nil
+		}
+		if _, ok := err.(errPermanent); ok || ctx.Err() != nil {
+			break
+		}
+	}
+	return "", err
+}
+
+// uploadParts uploads the file through pre-signed part URLs and returns the
+// ETag of every part keyed by part number. Part n covers bytes
+// [(n-1)*partSize, n*partSize) and a URL is required for every part. The first
+// part that fails for good cancels the others and its error is returned.
+func uploadParts(signedUrls map[int]string, file []byte, partSize int, contentType string) (map[int]string, error) {
+	if partSize <= 0 {
+		return nil, fmt.Errorf("invalid part size %d", partSize)
+	}
+	partsCount := (len(file) + partSize - 1) / partSize
+	if partsCount == 0 {
+		return nil, fmt.Errorf("cannot upload an empty file")
+	}
+	if len(signedUrls) != partsCount {
+		return nil, fmt.Errorf("expected %d signed part URLs, got %d", partsCount, len(signedUrls))
+	}
+	for partNumber := 1; partNumber <= partsCount; partNumber++ {
+		if _, ok := signedUrls[partNumber]; !ok {
+			return nil, fmt.Errorf("missing signed URL for part %d", partNumber)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		result   = make(map[int]string, partsCount)
+		slots    = make(chan struct{}, maxConcurrentPartUploads)
+	)
+	for partNumber := 1; partNumber <= partsCount; partNumber++ {
+		start := (partNumber - 1) * partSize
+		end := min(start+partSize, len(file))
+
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func(partNumber int, filePart []byte) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			etag, err := uploadPart(ctx, signedUrls[partNumber], filePart, contentType)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("part %d of %d: %v", partNumber, partsCount, err)
+					cancel()
+				}
+				return
+			}
+			result[partNumber] = etag
+		}(partNumber, file[start:end])
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// UploadObject stores data through the provider's multipart upload: it
+// initiates the upload, uploads every part and completes it. Whatever fails,
+// the upload is aborted so no orphaned parts are left behind, and a single
+// error describing the failed step is returned.
+func UploadObject(provider StorageProvider, bucket string, filePath string, fileName string, contentType string, data []byte) (*CompletedMultipartUploadResponseType, error) {
+	initUploadRes, err := provider.InitiateMultipartUpload(bucket, filePath, fileName, contentType, len(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiate upload of %s/%s: %v", filePath, fileName, err)
+	}
+
+	fail := func(step string, err error) error {
+		if abortErr := provider.AbortMultipartUpload(bucket, initUploadRes.UploadId, filePath, fileName, contentType); abortErr != nil {
+			return fmt.Errorf("failed to %s %s/%s: %v (abort also failed: %v)", step, filePath, fileName, err, abortErr)
+		}
+		return fmt.Errorf("failed to %s %s/%s: %v", step, filePath, fileName, err)
+	}
+
+	signedURLsRes, err := provider.GenerateSignedURLsForParts(bucket, filePath, fileName, initUploadRes.UploadId, contentType, len(data))
+	if err != nil {
+		return nil, fail("sign parts of", err)
+	}
+	parts, err := provider.UploadFile(signedURLsRes.SignedUrls, data, PartSize, contentType)
+	if err != nil {
+		return nil, fail("upload", err)
+	}
+	uploadCompleteRes, err := provider.CompleteMultipartUpload(bucket, initUploadRes.UploadId, filePath, fileName, contentType, parts)
+	if err != nil {
+		return nil, fail("complete upload of", err)
+	}
+	return uploadCompleteRes, nil
+}
+
+// UploadStream stores size bytes read from r like UploadObject, reading and
+// uploading one part at a time so at most PartSize bytes are held in memory.
+func UploadStream(provider StorageProvider, bucket string, filePath string, fileName string, contentType string, r io.Reader, size int) (*CompletedMultipartUploadResponseType, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("cannot upload an empty file")
+	}
+	initUploadRes, err := provider.InitiateMultipartUpload(bucket, filePath, fileName, contentType, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiate upload of %s/%s: %v", filePath, fileName, err)
+	}
+
+	fail := func(step string, err error) error {
+		if abortErr := provider.AbortMultipartUpload(bucket, initUploadRes.UploadId, filePath, fileName, contentType); abortErr != nil {
+			return fmt.Errorf("failed to %s %s/%s: %v (abort also failed: %v)", step, filePath, fileName, err, abortErr)
+		}
+		return fmt.Errorf("failed to %s %s/%s: %v", step, filePath, fileName, err)
+	}
+
+	signedURLsRes, err := provider.GenerateSignedURLsForParts(bucket, filePath, fileName, initUploadRes.UploadId, contentType, size)
+	if err != nil {
+		return nil, fail("sign parts of", err)
+	}
+	partsCount := PartsCount(size)
+	parts := make(map[int]string, partsCount)
+	buf := make([]byte, PartSize)
+	for partNumber := 1; partNumber <= partsCount; partNumber++ {
+		signedURL, ok := signedURLsRes.SignedUrls[partNumber]
+		if !ok {
+			return nil, fail("upload", fmt.Errorf("missing signed URL for part %d", partNumber))
+		}
+		partLength := min(PartSize, size-(partNumber-1)*PartSize)
+		if _, err := io.ReadFull(r, buf[:partLength]); err != nil {
+			return nil, fail("read", err)
+		}
+		etag, err := uploadPart(context.Background(), signedURL, buf[:partLength], contentType)
+		if err != nil {
+			return nil, fail("upload", fmt.Errorf("part %d of %d: %v", partNumber, partsCount, err))
+		}
+		parts[partNumber] = etag
+	}
+	uploadCompleteRes, err := provider.CompleteMultipartUpload(bucket, initUploadRes.UploadId, filePath, fileName, contentType, parts)
+	if err != nil {
+		return nil, fail("complete upload of", err)
+	}
+	return uploadCompleteRes, nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type partServer struct {
+	mu         sync.Mutex
+	parts      map[string][]byte
+	attempts   map[string]int
+	failOnce   map[string]bool
+	opaqueEtag bool
+}
+
+func newPartServer() (*partServer, *httptest.Server) {
+	ps := &partServer{parts: map[string][]byte{}, attempts: map[string]int{}, failOnce: map[string]bool{}}
+	return ps, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		part := r.URL.Query().Get("partNumber")
+		body, _ := io.ReadAll(r.Body)
+
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		ps.attempts[part]++
+		if ps.failOnce[part] && ps.attempts[part] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		checksum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(checksum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ps.parts[part] = body
+		etag := hex.EncodeToString(checksum[:])
+		if ps.opaqueEtag {
+			// SSE-KMS and SSE-C parts don't have the MD5 as ETag
+			etag = "a1b2c3d4e5f60718293a4b5c6d7e8f90-kms"
+		}
+		w.Header().Set("ETag", fmt.Sprintf("\"%s\"", etag))
+	}))
+}
+
+func signedPartUrls(baseURL string, partsCount int) map[int]string {
+	urls := map[int]string{}
+	for partNumber := 1; partNumber <= partsCount; partNumber++ {
+		urls[partNumber] = fmt.Sprintf("%s/object?partNumber=%d", baseURL, partNumber)
+	}
+	return urls
+}
+
+func TestUploadPartsOrdersAndRetriesParts(t *testing.T) {
+	ps, server := newPartServer()
+	defer server.Close()
+	ps.failOnce["2"] = true
+
+	file := bytes.Repeat([]byte("0123456789"), 25)
+	etags, err := uploadParts(signedPartUrls(server.URL, 3), file, 100, "image/jpeg")
+	if err != nil {
+		t.Fatalf("expected upload to succeed, got %v", err)
+	}
+	for partNumber := 1; partNumber <= 3; partNumber++ {
+		start := (partNumber - 1) * 100
+		end := min(start+100, len(file))
+		checksum := md5.Sum(file[start:end])
+		if etags[partNumber] != hex.EncodeToString(checksum[:]) {
+			t.Errorf("part %d paired with the wrong ETag %s", partNumber, etags[partNumber])
+		}
+		if !bytes.Equal(ps.parts[fmt.Sprint(partNumber)], file[start:end]) {
+			t.Errorf("part %d received the wrong bytes", partNumber)
+		}
+	}
+	if ps.attempts["2"] != 2 {
+		t.Errorf("expected part 2 to be retried once, got %d attempts", ps.attempts["2"])
+	}
+}
+
+func TestUploadPartsAcceptsEtagsOfEncryptedParts(t *testing.T) {
+	ps, server := newPartServer()
+	defer server.Close()
+	ps.opaqueEtag = true
+
+	etags, err := uploadParts(signedPartUrls(server.URL, 1), []byte("hello"), 100, "image/jpeg")
+	if err != nil {
+		t.Fatalf("expected upload to succeed, got %v", err)
+	}
+	if etags[1] != "a1b2c3d4e5f60718293a4b5c6d7e8f90-kms" {
+		t.Errorf("expected the ETag of the store, got %s", etags[1])
+	}
+}
+
+func TestUploadPartsRequiresUrlForEveryPart(t *testing.T) {
+	_, err := uploadParts(signedPartUrls("http://localhost", 1), make([]byte, 150), 100, "image/jpeg")
+	if err == nil {
+		t.Fatal("expected error for missing part URL")
+	}
+}
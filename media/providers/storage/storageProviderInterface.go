@@ -14,5 +14,7 @@ type StorageProvider interface {
 	InitiateMultipartUpload(bucket string, filePath string, fileName string, contentType string, fileSize int) (*InitiateMultipartUpload, error)
 	GenerateSignedURLsForParts(bucket string, filePath string, fileName string, uploadID string, contentType string, fileSize int) (*GenerateSignedURLsForPartsResType, error)
 	CompleteMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string, parts map[int]string) (*CompletedMultipartUploadResponseType, error)
+	AbortMultipartUpload(bucket string, uploadID string, filePath string, fileName string, contentType string) error
 	UploadFile(signedUrls map[int]string, data []byte, partSize int, contentType string) (map[int]string, error)
+	DeleteFile(bucket string, filePath string, fileName string, contentType string) error
 }
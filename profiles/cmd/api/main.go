@@ -44,6 +44,9 @@ func main() {
 
 	server.RegisterFiberRoutes()
 
+	purgerCtx, stopPurger := context.WithCancel(context.Background())
+	purger := server.StartDeletionPurger(purgerCtx)
+
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
@@ -61,5 +64,7 @@ func main() {
 
 	// Wait for the graceful shutdown to complete
 	<-done
+	stopPurger()
+	purger.Wait()
 	log.Println("Graceful shutdown complete.")
 }
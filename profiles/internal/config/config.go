@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -15,14 +18,35 @@ var (
 	env                       = os.Getenv("APP_ENV")
 	googleMapsAPIKey          = os.Getenv("GOOGLE_MAPS_API_KEY")
 	googleServiceJsonFilePath = os.Getenv("GOOGLE_SERVICE_JSON_FILE_PATH")
+	mediaServiceURL           = os.Getenv("MEDIA_SERVICE_URL")
+	faceMatchApproveThreshold = os.Getenv("FACE_MATCH_APPROVE_THRESHOLD")
+	faceMatchRejectThreshold  = os.Getenv("FACE_MATCH_REJECT_THRESHOLD")
+	firebaseAuthFallback      = os.Getenv("FIREBASE_AUTH_FALLBACK")
+	deletionPurgeAfter        = os.Getenv("DELETION_PURGE_AFTER")
 	aws                       = AwsConfig{
 		Region:             os.Getenv("AWS_REGION"),
 		AWSAccessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
 		AWSSecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
 	}
+	faceMatch = FaceMatchConfig{
+		APIURL: os.Getenv("FACE_MATCH_API_URL"),
+		APIKey: os.Getenv("FACE_MATCH_API_KEY"),
+	}
+	auth = AuthConfig{
+		JwksURL: os.Getenv("AUTH_JWKS_URL"),
+		Issuer:  os.Getenv("AUTH_JWT_ISSUER"),
+	}
 	google = GoogleConfig{
 		ProjectID: os.Getenv("GOOGLE_PROJECT_ID"),
 	}
+	rateLimit = RateLimitConfig{
+		Store:  os.Getenv("RATE_LIMIT_STORE"),
+		Limits: os.Getenv("RATE_LIMITS"),
+	}
+	proxy = ProxyConfig{
+		Header: os.Getenv("PROXY_HEADER"),
+	}
+	trustedProxies = os.Getenv("TRUSTED_PROXIES")
 )
 
 type AwsConfig struct {
@@ -31,10 +55,46 @@ type AwsConfig struct {
 	AWSSecretAccessKey string `json:"awsSecretAccessKey"`
 }
 
+// AuthConfig configures how user requests are authenticated. Access tokens
+// of the auth service are verified against the keys published at JwksURL,
+// Firebase ID tokens are only accepted when FirebaseFallback is turned on.
+type AuthConfig struct {
+	JwksURL          string
+	Issuer           string
+	FirebaseFallback bool
+}
+
 type GoogleConfig struct {
 	ProjectID string
 }
 
+// FaceMatchConfig configures the automatic comparator for verification
+// selfies. Without APIURL every submission waits for a reviewer. Submissions
+// scoring at least ApproveThreshold with the right pose are approved, those
+// at or below RejectThreshold are rejected, the rest go to review.
+type FaceMatchConfig struct {
+	APIURL           string
+	APIKey           string
+	ApproveThreshold float64
+	RejectThreshold  float64
+}
+
+// RateLimitConfig picks where rate limit buckets are kept: "memory" (the
+// default) per instance, "mongo" shared by all instances. Limits overrides
+// the limits of routes by rule name, like "locations=30/1m".
+type RateLimitConfig struct {
+	Store  string
+	Limits string
+}
+
+// ProxyConfig names the load balancers in front of the service. The client
+// address is read from Header only on requests coming from TrustedProxies
+// (addresses or CIDR ranges), other requests are keyed on their own address.
+type ProxyConfig struct {
+	Header         string
+	TrustedProxies []string
+}
+
 type configType struct {
 	Port                      string
 	MongoConnUrl              string
@@ -44,8 +104,14 @@ type configType struct {
 	Env                       string
 	GoogleMapsAPIKey          string
 	GoogleServiceJsonFilePath string
+	MediaServiceURL           string
+	DeletionPurgeAfter        time.Duration
 	AWS                       AwsConfig
 	Google                    GoogleConfig
+	Auth                      AuthConfig
+	FaceMatch                 FaceMatchConfig
+	RateLimit                 RateLimitConfig
+	Proxy                     ProxyConfig
 }
 
 func GetConfig() configType {
@@ -58,8 +124,13 @@ func GetConfig() configType {
 		Env:                       env,
 		GoogleMapsAPIKey:          googleMapsAPIKey,
 		GoogleServiceJsonFilePath: googleServiceJsonFilePath,
+		MediaServiceURL:           mediaServiceURL,
 		AWS:                       aws,
 		Google:                    google,
+		Auth:                      auth,
+		FaceMatch:                 faceMatch,
+		RateLimit:                 rateLimit,
+		Proxy:                     proxy,
 	}
 	if port == "" {
 		obj.Port = "8080"
@@ -67,8 +138,35 @@ func GetConfig() configType {
 	if googleServiceJsonFilePath == "" {
 		obj.GoogleServiceJsonFilePath = "media/googleService.json"
 	}
+	obj.FaceMatch.ApproveThreshold, _ = strconv.ParseFloat(faceMatchApproveThreshold, 64)
+	if obj.FaceMatch.ApproveThreshold <= 0 {
+		obj.FaceMatch.ApproveThreshold = 0.85
+	}
+	obj.FaceMatch.RejectThreshold, _ = strconv.ParseFloat(faceMatchRejectThreshold, 64)
+	if obj.FaceMatch.RejectThreshold <= 0 {
+		obj.FaceMatch.RejectThreshold = 0.3
+	}
 	if aws.Region == "" {
 		obj.AWS.Region = "ap-south-1"
 	}
+	if auth.Issuer == "" {
+		obj.Auth.Issuer = "purely-auth"
+	}
+	obj.Auth.FirebaseFallback = firebaseAuthFallback == "true"
+	if rateLimit.Store == "" {
+		obj.RateLimit.Store = "memory"
+	}
+	if proxy.Header == "" {
+		obj.Proxy.Header = "X-Forwarded-For"
+	}
+	for _, trustedProxy := range strings.Split(trustedProxies, ",") {
+		if trustedProxy = strings.TrimSpace(trustedProxy); trustedProxy != "" {
+			obj.Proxy.TrustedProxies = append(obj.Proxy.TrustedProxies, trustedProxy)
+		}
+	}
+	obj.DeletionPurgeAfter, _ = time.ParseDuration(deletionPurgeAfter)
+	if obj.DeletionPurgeAfter <= 0 {
+		obj.DeletionPurgeAfter = 30 * 24 * time.Hour
+	}
 	return obj
 }
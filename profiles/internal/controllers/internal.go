@@ -8,7 +8,10 @@ import (
 	"net/http"
 	PubSub "profiles/internal/providers/pubSub"
 	"profiles/internal/services"
+	"profiles/internal/types/profileServiceTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
 	"profiles/internal/utils/helpers/httpHelper"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,14 +24,23 @@ type PubSubMessagePayload struct {
 
 type InternalController struct {
 	InternalService services.InternalService
+	ProfileService  services.ProfileService
 }
 
 func (ic *InternalController) HandlePubSubMessage(c *fiber.Ctx) error {
 	return httpHelper.Controller(httpHelper.ControllerHelperType{
 		C: c,
 		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
-			res := ic.InternalService.HandlePubSubMessage(ctx, data.(PubSub.PubSubMessageType))
-			return res, nil
+			parsedData, ok := data.(PubSub.PubSubMessageType)
+			if !ok {
+				fmt.Println("HandlePubSubMessage Type parsing error")
+				return false, nil
+			}
+			// A non-2xx response has Pub/Sub redeliver the message
+			if !ic.InternalService.HandlePubSubMessage(ctx, parsedData) {
+				return nil, httpErrors.HydrateHttpError("purely/requests/errors/message-not-handled", 503, "Message not handled, redeliver it")
+			}
+			return true, nil
 		},
 		DataExtractor: func(c *fiber.Ctx) interface{} {
 			var msg PubSubMessagePayload
@@ -50,3 +62,46 @@ func (ic *InternalController) HandlePubSubMessage(c *fiber.Ctx) error {
 		Code:    nil,
 	})
 }
+
+func (ic *InternalController) GetVerificationQueue(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return ic.ProfileService.GetVerificationQueue(ctx, data.(profileServiceTypes.GetVerificationQueueType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			page, err := strconv.ParseInt(c.Query("page", "0"), 10, 64)
+			if err != nil || page < 0 {
+				page = 0
+			}
+			return profileServiceTypes.GetVerificationQueueType{
+				Page: page,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (ic *InternalController) ReviewVerification(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			reviewData, ok := data.(profileServiceTypes.ReviewVerificationType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return ic.ProfileService.ReviewVerification(ctx, reviewData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var data profileServiceTypes.ReviewVerificationType
+			if err := c.BodyParser(&data); err != nil {
+				return nil
+			}
+			data.VerificationID = c.Params("verificationID")
+			return data
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
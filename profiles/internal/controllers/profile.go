@@ -132,13 +132,20 @@ func (provider *ProfileController) UpsertDatingProfile(c *fiber.Ctx) error {
 			var convertedPrompts []profileServiceTypes.DatingPromptType
 			if datingProfile.Prompts != nil {
 				for _, prompt := range *datingProfile.Prompts {
-					if prompt.PromptId == nil || prompt.Answer == nil {
+					// Voice prompts are answered with a media ID
+					if prompt.PromptId == nil || (prompt.Answer == nil && prompt.MediaID == nil) {
 						continue
 					}
-					convertedPrompts = append(convertedPrompts, profileServiceTypes.DatingPromptType{
+					convertedPrompt := profileServiceTypes.DatingPromptType{
 						PromptId: *prompt.PromptId,
-						Answer:   *prompt.Answer,
-					})
+					}
+					if prompt.Answer != nil {
+						convertedPrompt.Answer = *prompt.Answer
+					}
+					if prompt.MediaID != nil {
+						convertedPrompt.MediaID = *prompt.MediaID
+					}
+					convertedPrompts = append(convertedPrompts, convertedPrompt)
 				}
 			}
 
@@ -278,3 +285,199 @@ func (provider *ProfileController) GetProfiles(c *fiber.Ctx) error {
 		Code:    nil,
 	})
 }
+
+func (provider *ProfileController) RevealProfileMedia(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			revealData, ok := data.(profileServiceTypes.RevealProfileMediaType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return provider.ProfileService.RevealProfileMedia(ctx, revealData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var body profileControllerTypes.RevealProfileMediaType
+			if err := c.BodyParser(&body); err != nil || body.ProfileID == nil {
+				return nil
+			}
+
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.RevealProfileMediaType{
+				AuthId:          auth.Id,
+				Category:        c.Params("profileCategory"),
+				ViewerProfileID: *body.ProfileID,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) ConcealProfileMedia(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			revealData, ok := data.(profileServiceTypes.RevealProfileMediaType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return provider.ProfileService.ConcealProfileMedia(ctx, revealData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.RevealProfileMediaType{
+				AuthId:          auth.Id,
+				Category:        c.Params("profileCategory"),
+				ViewerProfileID: c.Params("profileID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) ReorderProfileMedia(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			reorderData, ok := data.(profileServiceTypes.ReorderMediaType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return provider.ProfileService.ReorderProfileMedia(ctx, reorderData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var body profileControllerTypes.ReorderMediaType
+			if err := c.BodyParser(&body); err != nil || body.MediaIDs == nil {
+				return nil
+			}
+
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.ReorderMediaType{
+				AuthId:   auth.Id,
+				Category: c.Params("profileCategory"),
+				MediaIDs: *body.MediaIDs,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) SetMediaFocalPoint(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			focalPointData, ok := data.(profileServiceTypes.MediaFocalPointType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return provider.ProfileService.SetMediaFocalPoint(ctx, focalPointData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var body profileControllerTypes.FocalPointType
+			if err := c.BodyParser(&body); err != nil || body.X == nil || body.Y == nil {
+				return nil
+			}
+
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.MediaFocalPointType{
+				AuthId:     auth.Id,
+				Category:   c.Params("profileCategory"),
+				MediaID:    c.Params("mediaID"),
+				FocalPoint: &profileServiceTypes.FocalPointType{X: *body.X, Y: *body.Y},
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) ResetMediaFocalPoint(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			focalPointData, ok := data.(profileServiceTypes.MediaFocalPointType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return provider.ProfileService.SetMediaFocalPoint(ctx, focalPointData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.MediaFocalPointType{
+				AuthId:   auth.Id,
+				Category: c.Params("profileCategory"),
+				MediaID:  c.Params("mediaID"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) RequestVerificationChallenge(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return provider.ProfileService.RequestVerificationChallenge(ctx, data.(profileServiceTypes.VerificationType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.VerificationType{
+				AuthId:   auth.Id,
+				Category: c.Params("profileCategory"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) SubmitVerification(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			verificationData, ok := data.(profileServiceTypes.VerificationType)
+			if !ok {
+				return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Invalid data")
+			}
+			return provider.ProfileService.SubmitVerification(ctx, verificationData)
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			var body profileControllerTypes.SubmitVerificationType
+			if err := c.BodyParser(&body); err != nil || body.VerificationID == nil || body.MediaID == nil {
+				return nil
+			}
+
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.VerificationType{
+				AuthId:         auth.Id,
+				Category:       c.Params("profileCategory"),
+				VerificationID: *body.VerificationID,
+				MediaID:        *body.MediaID,
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
+
+func (provider *ProfileController) GetVerification(c *fiber.Ctx) error {
+	return httpHelper.Controller(httpHelper.ControllerHelperType{
+		C: c,
+		Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+			return provider.ProfileService.GetVerification(ctx, data.(profileServiceTypes.VerificationType))
+		},
+		DataExtractor: func(c *fiber.Ctx) interface{} {
+			auth := c.Locals("auth").(appTypes.Auth)
+			return profileServiceTypes.VerificationType{
+				AuthId:   auth.Id,
+				Category: c.Params("profileCategory"),
+			}
+		},
+		Message: nil,
+		Code:    nil,
+	})
+}
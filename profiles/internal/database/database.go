@@ -50,6 +50,7 @@ func createIndexes(client *mongo.Client, model interface{}, collectionName strin
 		field := t.Field(i)
 		bsonTag := field.Tag.Get("bson")
 		uniqueTag := field.Tag.Get("unique")
+		indexTag := field.Tag.Get("index")
 
 		// If the field has a BSON tag and is unique
 		if bsonTag != "" && uniqueTag == "true" {
@@ -61,6 +62,15 @@ func createIndexes(client *mongo.Client, model interface{}, collectionName strin
 			}
 			indexModels = append(indexModels, indexModel)
 		}
+
+		// Plain (non unique) ascending index for fields used in lookups
+		if bsonTag != "" && indexTag == "true" && uniqueTag != "true" {
+			parts := strings.Split(bsonTag, ",")
+			tagName := parts[0]
+			indexModels = append(indexModels, mongo.IndexModel{
+				Keys: bson.D{{Key: tagName, Value: 1}},
+			})
+		}
 	}
 
 	// Create the indexes in MongoDB
@@ -119,3 +129,13 @@ func (s *service) Client() *mongo.Client {
 func (s *service) Db() *mongo.Database {
 	return s.db
 }
+
+// Use makes Mongo return db instead of connecting, for tests running against
+// a mock deployment.
+func Use(db *mongo.Database) {
+	once.Do(func() {})
+	instance = &service{
+		client: db.Client(),
+		db:     db,
+	}
+}
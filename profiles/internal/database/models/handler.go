@@ -40,6 +40,16 @@ var (
 			CollectionName: "media",
 			Timestamps:     true,
 		},
+		reflect.TypeOf(Reveal{}): {
+			Model:          Reveal{},
+			CollectionName: "reveals",
+			Timestamps:     true,
+		},
+		reflect.TypeOf(Verification{}): {
+			Model:          Verification{},
+			CollectionName: "verifications",
+			Timestamps:     true,
+		},
 	}
 )
 
@@ -170,6 +180,26 @@ func UpdateMany(ctx context.Context, db *mongo.Database, model interface{}, filt
 	return result, nil
 }
 
+// DeleteOne deletes the first document that matches the filter.
+func DeleteOne(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M) (*mongo.DeleteResult, error) {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteMany deletes every document that matches the filter.
+func DeleteMany(ctx context.Context, db *mongo.Database, model interface{}, filter bson.M) (*mongo.DeleteResult, error) {
+	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func FindOne(ctx context.Context, db *mongo.Database, model interface{}) *mongo.SingleResult {
 	collection := db.Collection(models[reflect.TypeOf(model)].CollectionName)
 	var filter map[string]interface{}
@@ -4,9 +4,26 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Media is owned by the media service, which also keeps its indexes.
 type Media struct {
 	ID primitive.ObjectID `bson:"_id" json:"id"`
 
-	Url string `bson:"url" json:"url" unique:"true"`
-	EXT string `bson:"ext" json:"ext"`
+	Url string `bson:"url,omitempty" json:"url"`
+	EXT string `bson:"ext,omitempty" json:"ext"`
+
+	// Written by the media service, read here to check uploads handed in
+	AuthId           string        `bson:"authId,omitempty" json:"authId,omitempty"`
+	Purpose          string        `bson:"purpose,omitempty" json:"purpose,omitempty"`
+	ModerationStatus string        `bson:"moderationStatus,omitempty" json:"moderationStatus,omitempty"`
+	Quality          *MediaQuality `bson:"quality,omitempty" json:"quality,omitempty"`
+	Visibility       string        `bson:"visibility,omitempty" json:"visibility,omitempty"`
+
+	// Length and waveform preview of audio
+	DurationMs int   `bson:"durationMs,omitempty" json:"durationMs,omitempty"`
+	Waveform   []int `bson:"waveform,omitempty" json:"waveform,omitempty"`
+}
+
+// MediaQuality carries the quality warnings media raised for a photo.
+type MediaQuality struct {
+	Warnings []string `bson:"warnings,omitempty" json:"warnings,omitempty"`
 }
@@ -11,18 +11,34 @@ type Location struct {
 	Coordinates []float64 `bson:"coordinates,omitempty" json:"coordinates,omitempty"`
 }
 
+// FocalPoint is relative to the photo, 0,0 is the top left corner and 1,1
+// the bottom right one.
+type FocalPoint struct {
+	X float64 `bson:"x" json:"x"`
+	Y float64 `bson:"y" json:"y"`
+}
+
 type MediaType struct {
 	MediaID        primitive.ObjectID `bson:"mediaID,omitempty" json:"id,omitempty"`
 	Order          int                `bson:"order,omitempty" json:"order,omitempty"`
 	BlurredImageID primitive.ObjectID `bson:"blurredImageID,omitempty" json:"blurredImageID,omitempty"`
+	// Picked by the owner, crops of the photo are centered on it
+	FocalPoint *FocalPoint `bson:"focalPoint,omitempty" json:"focalPoint,omitempty"`
 }
 
 type PromptElementType struct {
 	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
 	Prompt primitive.ObjectID `bson:"prompt,omitempty" json:"id"`
 	Answer string             `bson:"answer,omitempty"  json:"answer"`
+	// Voice prompts are answered with an audio media instead of text
+	MediaID primitive.ObjectID `bson:"mediaID,omitempty" json:"mediaID,omitempty"`
 }
 
+const (
+	ProfileStatusActive  = "active"
+	ProfileStatusDeleted = "deleted"
+)
+
 type Profile struct {
 	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	AuthId string             `bson:"authId,omitempty" json:"authId,omitempty"`
@@ -55,4 +71,10 @@ type Profile struct {
 	DeletedAt time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 
 	PreferredMatchDistance int `bson:"preferredMatchDistance,omitempty" json:"preferredMatchDistance,omitempty"`
+
+	// Set once a verification selfie of the profile was approved
+	VerifiedAt *time.Time `bson:"verifiedAt,omitempty" json:"verifiedAt,omitempty"`
+	// Photos the approved selfie was matched against, the profile loses the
+	// badge when one of them leaves it
+	VerifiedMediaIDs []primitive.ObjectID `bson:"verifiedMediaIDs,omitempty" json:"verifiedMediaIDs,omitempty"`
 }
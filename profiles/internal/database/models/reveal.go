@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reveal grants ViewerProfileID access to the original (unblurred) media of
+// ProfileID. The profile owner creates it and can revoke it at any time.
+type Reveal struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProfileID       primitive.ObjectID `bson:"profileID,omitempty" json:"profileID,omitempty" index:"true"`
+	ViewerProfileID primitive.ObjectID `bson:"viewerProfileID,omitempty" json:"viewerProfileID,omitempty" index:"true"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt,omitempty"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
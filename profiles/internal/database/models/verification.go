@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	VerificationStatusChallenged = "challenged"
+	VerificationStatusPending    = "pending"
+	VerificationStatusApproved   = "approved"
+	VerificationStatusRejected   = "rejected"
+)
+
+// Verification is one attempt to verify a profile. It starts as a pose
+// challenge, becomes pending once a selfie showing the pose is submitted and
+// is approved or rejected by a reviewer or the automatic comparator.
+type Verification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ProfileID primitive.ObjectID `bson:"profileID,omitempty" json:"profileID,omitempty" index:"true"`
+	AuthId    string             `bson:"authId,omitempty" json:"authId,omitempty"`
+	Pose      string             `bson:"pose,omitempty" json:"pose,omitempty"`
+	ExpiresAt time.Time          `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	MediaID   primitive.ObjectID `bson:"mediaID,omitempty" json:"mediaID,omitempty"`
+	Status    string             `bson:"status,omitempty" json:"status,omitempty" index:"true"`
+
+	// Profile photos the selfie is compared against, taken at submission
+	PhotoIDs []primitive.ObjectID `bson:"photoIDs,omitempty" json:"photoIDs,omitempty"`
+
+	// Score of the automatic comparator, if one ran
+	MatchScore     *float64 `bson:"matchScore,omitempty" json:"matchScore,omitempty"`
+	ReviewedBy     string   `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	RejectedReason string   `bson:"rejectedReason,omitempty" json:"rejectedReason,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt,omitempty"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
@@ -8,6 +8,7 @@ import (
 	firebaseHelper "profiles/internal/utils/helpers/firebaseHelpers"
 	httpErrors "profiles/internal/utils/helpers/httpError"
 	"profiles/internal/utils/helpers/httpHelper"
+	"profiles/internal/utils/helpers/jwtHelpers"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -43,6 +44,21 @@ func VerifyUserAccess(c *fiber.Ctx) error {
 	if len(bearerToken) < 2 {
 		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
 	}
+	// Access tokens of the auth service are verified locally
+	authConfig := config.GetConfig().Auth
+	if verifier := jwtHelpers.GetVerifier(); verifier != nil && jwtHelpers.IssuerOf(bearerToken[1]) == authConfig.Issuer {
+		claims, err := verifier.Verify(bearerToken[1])
+		if err != nil {
+			return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+		}
+		c.Locals("auth", appTypes.Auth{
+			Id: claims.ID,
+		})
+		return c.Next()
+	}
+	if !authConfig.FirebaseFallback {
+		return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/unauthorized", 401, "Unauthorized"))
+	}
 	firebaseAuth, err := firebaseHelper.App().Auth(context.Background())
 	if err != nil {
 		log.Default().Println(err)
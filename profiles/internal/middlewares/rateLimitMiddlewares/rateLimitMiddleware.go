@@ -0,0 +1,78 @@
+package rateLimitMiddlewares
+
+import (
+	"log"
+	"math"
+	"profiles/internal/types/appTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
+	"profiles/internal/utils/helpers/httpHelper"
+	"profiles/internal/utils/helpers/rateLimitHelpers"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// What the buckets of a rule are keyed by. Routes keyed by auth ID fall back
+// to the IP for callers that aren't signed in.
+const (
+	KeyByAuthId = "authId"
+	KeyByIP     = "ip"
+	KeyByRoute  = "route"
+)
+
+// Rule limits a route. Name identifies it in RATE_LIMITS and prefixes the
+// keys of its buckets.
+type Rule struct {
+	Name  string
+	KeyBy string
+	Limit rateLimitHelpers.Limit
+}
+
+var (
+	store     rateLimitHelpers.Store = rateLimitHelpers.NewMemoryStore()
+	overrides                        = map[string]rateLimitHelpers.Limit{}
+)
+
+// Configure sets where buckets are kept and the limits replacing the ones
+// rules were declared with. It is called once at startup.
+func Configure(limiterStore rateLimitHelpers.Store, limits map[string]rateLimitHelpers.Limit) {
+	store = limiterStore
+	overrides = limits
+}
+
+func keyOf(c *fiber.Ctx, keyBy string) string {
+	switch keyBy {
+	case KeyByRoute:
+		return "route"
+	case KeyByAuthId:
+		if auth, ok := c.Locals("auth").(appTypes.Auth); ok && auth.Id != "" {
+			return "auth:" + auth.Id
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+// RateLimit refuses requests over the limit of rule with a 429 and a
+// Retry-After header.
+func RateLimit(rule Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := rule.Limit
+		if override, ok := overrides[rule.Name]; ok {
+			limit = override
+		}
+		res, err := store.Take(c.Context(), rule.Name+":"+keyOf(c, rule.KeyBy), limit, time.Now())
+		if err != nil {
+			// An unavailable limiter shouldn't take the route down with it
+			log.Printf("Error rate limiting %s: %v", rule.Name, err)
+			return c.Next()
+		}
+		c.Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			c.Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(res.RetryAfter.Seconds())))))
+			return httpHelper.SendErrorResponse(c, httpErrors.HydrateHttpError("purely/requests/errors/too-many-requests", 429, "Too many requests, try again later"))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package faceMatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// APIComparator hands the comparison to an external face matching API. It is
+// posted {"selfie": "<url>", "photos": ["<url>", ...], "pose": "..."} and
+// answers {"score": 0.93, "poseMatched": true}.
+type APIComparator struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+func NewAPIComparator(url string, apiKey string) *APIComparator {
+	return &APIComparator{
+		URL:    url,
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type apiCompareRequest struct {
+	Selfie string   `json:"selfie"`
+	Photos []string `json:"photos"`
+	Pose   string   `json:"pose"`
+}
+
+func (comparator *APIComparator) Compare(ctx context.Context, data CompareType) (*ComparisonResult, error) {
+	body, err := json.Marshal(apiCompareRequest{
+		Selfie: data.SelfieURL,
+		Photos: data.PhotoURLs,
+		Pose:   data.Pose,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, comparator.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if comparator.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+comparator.APIKey)
+	}
+	res, err := comparator.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("face match API responded with status %d", res.StatusCode)
+	}
+	var result ComparisonResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	result.Provider = "api"
+	return &result, nil
+}
@@ -0,0 +1,9 @@
+package faceMatch
+
+import "context"
+
+// Comparator checks a verification selfie against the photos already on the
+// profile and whether it shows the requested pose.
+type Comparator interface {
+	Compare(ctx context.Context, data CompareType) (*ComparisonResult, error)
+}
@@ -0,0 +1,15 @@
+package faceMatch
+
+type CompareType struct {
+	SelfieURL string
+	PhotoURLs []string
+	Pose      string
+}
+
+// ComparisonResult holds how likely (0 to 1) the selfie shows the person in
+// the photos, and whether the requested pose was recognised.
+type ComparisonResult struct {
+	Score       float64 `json:"score"`
+	PoseMatched bool    `json:"poseMatched"`
+	Provider    string  `json:"provider,omitempty"`
+}
@@ -12,4 +12,6 @@ type InternalRoutes struct {
 
 func (ir *InternalRoutes) InitRoutes(router fiber.Router) {
 	router.Post("/pubsub/messages", ir.InternalController.HandlePubSubMessage)
+	router.Get("/verifications", ir.InternalController.GetVerificationQueue)
+	router.Put("/verifications/:verificationID", ir.InternalController.ReviewVerification)
 }
@@ -18,4 +18,12 @@ func (profileRoutes *ProfileRoutes) InitRoutes(router fiber.Router) {
 	router.Get("/:profileCategory/layout", profileRoutes.profileController.GetProfileLayout)
 	router.Patch("/:profileCategory/upsert", profileRoutes.profileController.UpsertDatingProfile)
 	router.Get("/:profileCategory/profiles", profileRoutes.profileController.GetProfiles)
+	router.Post("/:profileCategory/reveals", profileRoutes.profileController.RevealProfileMedia)
+	router.Delete("/:profileCategory/reveals/:profileID", profileRoutes.profileController.ConcealProfileMedia)
+	router.Patch("/:profileCategory/media/order", profileRoutes.profileController.ReorderProfileMedia)
+	router.Put("/:profileCategory/media/:mediaID/focal-point", profileRoutes.profileController.SetMediaFocalPoint)
+	router.Delete("/:profileCategory/media/:mediaID/focal-point", profileRoutes.profileController.ResetMediaFocalPoint)
+	router.Get("/:profileCategory/verification", profileRoutes.profileController.GetVerification)
+	router.Post("/:profileCategory/verification", profileRoutes.profileController.SubmitVerification)
+	router.Post("/:profileCategory/verification/challenge", profileRoutes.profileController.RequestVerificationChallenge)
 }
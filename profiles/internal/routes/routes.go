@@ -3,7 +3,10 @@ package routes
 import (
 	"profiles/internal/controllers"
 	"profiles/internal/middlewares/authMiddlewares"
+	"profiles/internal/middlewares/rateLimitMiddlewares"
 	"profiles/internal/services"
+	"profiles/internal/utils/helpers/rateLimitHelpers"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -32,6 +35,7 @@ func (r *Router) InitRoutes(router fiber.Router) {
 	internalRoutes := InternalRoutes{
 		InternalController: controllers.InternalController{
 			InternalService: services.InternalService{},
+			ProfileService:  services.ProfileService{},
 		},
 	}
 	internalRoutesGroup.Use(authMiddlewares.VerifyInternalAccess)
@@ -39,6 +43,11 @@ func (r *Router) InitRoutes(router fiber.Router) {
 
 	locationRoutesGroup := router.Group("/locations")
 	locationRoutesGroup.Use(authMiddlewares.VerifyUserAccess)
+	// Every lookup is a paid Places call: a limit per user and one for everyone
+	locationRoutesGroup.Use(
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "locations", KeyBy: rateLimitMiddlewares.KeyByAuthId, Limit: rateLimitHelpers.Limit{Burst: 30, Per: time.Minute}}),
+		rateLimitMiddlewares.RateLimit(rateLimitMiddlewares.Rule{Name: "locationsTotal", KeyBy: rateLimitMiddlewares.KeyByRoute, Limit: rateLimitHelpers.Limit{Burst: 300, Per: time.Minute}}),
+	)
 	locationRoutes.InitRoutes(locationRoutesGroup)
 
 	profileRoutesGroup := router.Group("/")
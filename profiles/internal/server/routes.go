@@ -1,12 +1,38 @@
 package server
 
 import (
+	"context"
+	"fmt"
+	"profiles/internal/config"
+	"profiles/internal/database"
+	"profiles/internal/middlewares/rateLimitMiddlewares"
 	"profiles/internal/routes"
+	"profiles/internal/utils/helpers/rateLimitHelpers"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+func newRateLimitStore() (rateLimitHelpers.Store, error) {
+	switch config.GetConfig().RateLimit.Store {
+	case "memory":
+		return rateLimitHelpers.NewMemoryStore(), nil
+	case "mongo":
+		return rateLimitHelpers.NewMongoStore(context.Background(), database.Mongo().Db().Collection("rateLimits"))
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q", config.GetConfig().RateLimit.Store)
+}
+
 func (s *FiberServer) RegisterFiberRoutes() {
+	rateLimitStore, err := newRateLimitStore()
+	if err != nil {
+		panic(err)
+	}
+	rateLimits, err := rateLimitHelpers.ParseLimits(config.GetConfig().RateLimit.Limits)
+	if err != nil {
+		panic(err)
+	}
+	rateLimitMiddlewares.Configure(rateLimitStore, rateLimits)
+
 	locationRoutes := routes.Router{}
 	rootGroup := s.App.Group("/")
 	locationRoutes.InitRoutes(rootGroup)
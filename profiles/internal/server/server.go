@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -9,6 +10,7 @@ import (
 	"profiles/internal/config"
 	"profiles/internal/database"
 	PubSub "profiles/internal/providers/pubSub"
+	"profiles/internal/services"
 )
 
 type FiberServer struct {
@@ -22,6 +24,12 @@ func New() *FiberServer {
 		App: fiber.New(fiber.Config{
 			ServerHeader: "profiles",
 			AppName:      "profiles",
+			// Rate limits key on c.IP(), which only trusts the proxy header
+			// on requests from the configured load balancers
+			ProxyHeader:             config.GetConfig().Proxy.Header,
+			EnableTrustedProxyCheck: true,
+			TrustedProxies:          config.GetConfig().Proxy.TrustedProxies,
+			EnableIPValidation:      true,
 		}),
 
 		db: database.Mongo(),
@@ -48,3 +56,11 @@ func New() *FiberServer {
 
 	return server
 }
+
+// StartDeletionPurger starts purging the profiles of deleted accounts. The
+// returned WaitGroup is done once ctx is cancelled and the purge running has
+// finished.
+func (s *FiberServer) StartDeletionPurger(ctx context.Context) *sync.WaitGroup {
+	profileService := services.ProfileService{}
+	return profileService.StartDeletionPurger(ctx)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"log"
+	"profiles/internal/config"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	PubSub "profiles/internal/providers/pubSub"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// How often deleted profiles are looked for to purge.
+const deletionPurgeInterval = time.Hour
+
+// DeleteAccount soft deletes the profiles of authId for the account deletion
+// saga of auth: they get status deleted and stop showing up right away. They
+// are purged with their reveals and verifications once DELETION_PURGE_AFTER
+// went by. Running it again is harmless, auth asks until it gets the
+// acknowledgement.
+func (profileService *ProfileService) DeleteAccount(ctx context.Context, authId string) error {
+	_, err := models.UpdateMany(ctx, database.Mongo().Db(), models.Profile{}, bson.M{
+		"authId": authId,
+		"status": bson.M{"$ne": models.ProfileStatusDeleted},
+	}, map[string]interface{}{
+		"status":    models.ProfileStatusDeleted,
+		"deletedAt": time.Now(),
+	})
+	return err
+}
+
+// PurgeDeletedAccounts deletes the profiles soft deleted longer ago than
+// DELETION_PURGE_AFTER, with the reveals on either side and the
+// verifications of their owner.
+func (profileService *ProfileService) PurgeDeletedAccounts(ctx context.Context) error {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Profile{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":    models.ProfileStatusDeleted,
+			"deletedAt": bson.M{"$lte": time.Now().Add(-config.GetConfig().DeletionPurgeAfter)},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$authId",
+			"profileIDs": bson.M{"$push": "$_id"},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	var accounts []struct {
+		AuthId     string               `bson:"_id"`
+		ProfileIDs []primitive.ObjectID `bson:"profileIDs"`
+	}
+	err = cursor.All(ctx, &accounts)
+	cursor.Close(ctx)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		if _, err := models.DeleteMany(ctx, database.Mongo().Db(), models.Reveal{}, bson.M{"$or": bson.A{
+			bson.M{"profileID": bson.M{"$in": account.ProfileIDs}},
+			bson.M{"viewerProfileID": bson.M{"$in": account.ProfileIDs}},
+		}}); err != nil {
+			return err
+		}
+		if _, err := models.DeleteMany(ctx, database.Mongo().Db(), models.Verification{}, bson.M{"authId": account.AuthId}); err != nil {
+			return err
+		}
+		if _, err := models.DeleteMany(ctx, database.Mongo().Db(), models.Profile{}, bson.M{"_id": bson.M{"$in": account.ProfileIDs}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartDeletionPurger purges deleted profiles every hour until ctx is
+// cancelled. The returned WaitGroup is done once it stopped.
+func (profileService *ProfileService) StartDeletionPurger(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			if err := profileService.PurgeDeletedAccounts(ctx); err != nil {
+				log.Printf("Error purging deleted profiles: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(deletionPurgeInterval):
+			}
+		}
+	}()
+	return &wg
+}
+
+// acknowledgeAccountDeletion tells auth the profiles step of a deletion is done.
+func acknowledgeAccountDeletion(ctx context.Context, sagaID string, authId string) error {
+	return PubSub.GetClient().PublishToService(ctx, "auth", PubSub.PubSubMessageType{
+		Type: "accountDeletionStepCompleted",
+		Data: map[string]interface{}{
+			"sagaID":  sagaID,
+			"authId":  authId,
+			"service": "profiles",
+		},
+	})
+}
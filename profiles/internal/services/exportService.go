@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	PubSub "profiles/internal/providers/pubSub"
+	"profiles/internal/types/profileServiceTypes"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ExportAccount returns the profiles of authId for a data export, with the
+// questions of their prompts spelled out. Only the user's own documents are
+// read, reveals are left out as they name other users.
+func (profileService *ProfileService) ExportAccount(ctx context.Context, authId string) ([]map[string]interface{}, error) {
+	cursor, err := models.Find(ctx, database.Mongo().Db(), models.Profile{AuthId: authId}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var profiles []models.Profile
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return nil, err
+	}
+
+	promptIDs := []primitive.ObjectID{}
+	for _, profile := range profiles {
+		for _, prompt := range profile.Prompts {
+			promptIDs = append(promptIDs, prompt.Prompt)
+		}
+	}
+	labels := map[primitive.ObjectID]string{}
+	if len(promptIDs) > 0 {
+		promptCursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Prompt{}, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": promptIDs}}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var prompts []models.Prompt
+		err = promptCursor.All(ctx, &prompts)
+		promptCursor.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, prompt := range prompts {
+			labels[prompt.ID] = prompt.Label
+		}
+	}
+
+	exported := []map[string]interface{}{}
+	for _, profile := range profiles {
+		prompts := []profileServiceTypes.ExportedPromptType{}
+		for _, prompt := range profile.Prompts {
+			exportedPrompt := profileServiceTypes.ExportedPromptType{
+				PromptID: prompt.Prompt.Hex(),
+				Prompt:   labels[prompt.Prompt],
+				Answer:   prompt.Answer,
+			}
+			if !prompt.MediaID.IsZero() {
+				exportedPrompt.MediaID = prompt.MediaID.Hex()
+			}
+			prompts = append(prompts, exportedPrompt)
+		}
+		profile.Prompts = nil
+		// Round trip through JSON so the export uses the API field names
+		raw, err := json.Marshal(profile)
+		if err != nil {
+			return nil, err
+		}
+		var document map[string]interface{}
+		if err := json.Unmarshal(raw, &document); err != nil {
+			return nil, err
+		}
+		document["prompts"] = prompts
+		exported = append(exported, document)
+	}
+	return exported, nil
+}
+
+// forwardAccountExport adds the profiles of the user to an export and hands it
+// to media, which packages it with the user's photos.
+func forwardAccountExport(ctx context.Context, data map[string]interface{}, profiles []map[string]interface{}) error {
+	forwarded := map[string]interface{}{}
+	for key, value := range data {
+		forwarded[key] = value
+	}
+	forwarded["profiles"] = profiles
+	return PubSub.GetClient().PublishToService(ctx, "media", PubSub.PubSubMessageType{
+		Type: "exportAccount",
+		Data: forwarded,
+	})
+}
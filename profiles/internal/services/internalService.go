@@ -2,14 +2,15 @@ package services
 
 import (
 	"context"
+	"log"
 	PubSub "profiles/internal/providers/pubSub"
 )
 
 type InternalService struct{}
 
-func (i *InternalService) HandleProfileImageBlurred(ctx context.Context, mediaID string, blurredImageID string, profileID string) {
+func (i *InternalService) HandleProfileImageBlurred(ctx context.Context, mediaID string, canonicalMediaID string, blurredImageID string, profileID string) {
 	ps := ProfileService{}
-	ps.UpsertProfileBlurredImage(ctx, mediaID, blurredImageID, profileID)
+	ps.UpsertProfileBlurredImage(ctx, mediaID, canonicalMediaID, blurredImageID, profileID)
 }
 
 func (i *InternalService) HandlePubSubMessage(ctx context.Context, data PubSub.PubSubMessageType) bool {
@@ -19,7 +20,65 @@ func (i *InternalService) HandlePubSubMessage(ctx context.Context, data PubSub.P
 			mediaID := data.Data["mediaID"].(string)
 			blurredImageID := data.Data["blurredImageID"].(string)
 			profileID := data.Data["profileID"].(string)
-			i.HandleProfileImageBlurred(ctx, mediaID, blurredImageID, profileID)
+			// Set when media merged a duplicate upload into an existing photo
+			canonicalMediaID, ok := data.Data["canonicalMediaID"].(string)
+			if !ok {
+				canonicalMediaID = mediaID
+			}
+			i.HandleProfileImageBlurred(ctx, mediaID, canonicalMediaID, blurredImageID, profileID)
+		}
+	// A photo rejected by media processing leaves the profile like a deleted one
+	case "mediaDeleted", "mediaRejected":
+		{
+			ps := ProfileService{}
+			ps.RemoveProfileMedia(ctx, data.Data["authId"].(string), data.Data["mediaID"].(string))
+			ps.RemoveVoicePrompts(ctx, data.Data["authId"].(string), data.Data["mediaID"].(string))
+		}
+	case "mediaQualityChecked":
+		{
+			ps := ProfileService{}
+			ps.RescoreProfilesWithMedia(ctx, data.Data["authId"].(string), data.Data["mediaID"].(string))
+		}
+	case "mediaReplaced":
+		{
+			ps := ProfileService{}
+			ps.ReplaceProfileMedia(ctx, data.Data["authId"].(string), data.Data["mediaID"].(string), data.Data["newMediaID"].(string))
+		}
+	// Part of the account deletion saga run by auth, redelivered until acknowledged
+	case "deleteAccount":
+		{
+			authId, _ := data.Data["authId"].(string)
+			sagaID, _ := data.Data["sagaID"].(string)
+			if authId == "" {
+				return true
+			}
+			ps := ProfileService{}
+			if err := ps.DeleteAccount(ctx, authId); err != nil {
+				log.Printf("Error deleting profiles of %s: %v", authId, err)
+				return false
+			}
+			if err := acknowledgeAccountDeletion(ctx, sagaID, authId); err != nil {
+				log.Printf("Error acknowledging deletion %s: %v", sagaID, err)
+				return false
+			}
+		}
+	// Data exports start in auth and are packaged by media
+	case "exportAccount":
+		{
+			authId, _ := data.Data["authId"].(string)
+			if authId == "" {
+				return true
+			}
+			ps := ProfileService{}
+			profiles, err := ps.ExportAccount(ctx, authId)
+			if err != nil {
+				log.Printf("Error exporting profiles of %s: %v", authId, err)
+				return false
+			}
+			if err := forwardAccountExport(ctx, data.Data, profiles); err != nil {
+				log.Printf("Error forwarding export of %s: %v", authId, err)
+				return false
+			}
 		}
 	}
 	return true
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"log"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	PubSub "profiles/internal/providers/pubSub"
+	profileLayoutTypes "profiles/internal/types/profileLayout"
+	"profiles/internal/types/profileServiceTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const mediaModerationApproved = "approved"
+
+// displayableMedia matches media that may be shown on profiles. Only media
+// the media service approved are shown, those not screened yet, in review or
+// rejected are held back. Verification selfies are only ever seen by
+// reviewers.
+var displayableMedia = bson.M{
+	"moderationStatus": mediaModerationApproved,
+	"purpose":          bson.M{"$ne": mediaPurposeVerification},
+}
+
+// setMediaPlaceholders copies the BlurHash and dominant color next to a media
+// entry so clients can render a placeholder before the image loads. They are
+// taken from the blurred image when there is one, which every viewer may see.
+func setMediaPlaceholders(mediaItem primitive.M) {
+	for _, key := range []string{"blurredImage", "media"} {
+		media, ok := mediaItem[key].(primitive.M)
+		if !ok || media["blurHash"] == nil {
+			continue
+		}
+		mediaItem["blurHash"] = media["blurHash"]
+		mediaItem["dominantColor"] = media["dominantColor"]
+		return
+	}
+}
+
+// lowQualityPrimaryPhoto reports whether media raised quality warnings for the
+// first photo of a profile.
+func lowQualityPrimaryPhoto(ctx context.Context, media []models.MediaType) bool {
+	if len(media) == 0 {
+		return false
+	}
+	primary := media[0]
+	for _, mediaEle := range media[1:] {
+		if mediaEle.Order < primary.Order {
+			primary = mediaEle
+		}
+	}
+	var primaryMedia models.Media
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Media{ID: primary.MediaID}).Decode(&primaryMedia); err != nil {
+		return false
+	}
+	return primaryMedia.Quality != nil && len(primaryMedia.Quality.Warnings) > 0
+}
+
+// refreshProfileCompletionScore computes the completion score of a profile
+// again after its photos or their quality changed.
+func (profileService *ProfileService) refreshProfileCompletionScore(ctx context.Context, profileID primitive.ObjectID) {
+	var profile models.Profile
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{ID: profileID}).Decode(&profile); err != nil {
+		log.Printf("Error fetching profile %s to score: %v", profileID.Hex(), err)
+		return
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Profile{}, profileID, map[string]interface{}{
+		"profileCompletionScore": profileService.computeProfileCompletionScore(ctx, &profile),
+	}); err != nil {
+		log.Printf("Error updating completion score of profile %s: %v", profileID.Hex(), err)
+	}
+}
+
+// RescoreProfilesWithMedia updates the completion score of the profiles
+// showing a photo whose quality was checked.
+func (profileService *ProfileService) RescoreProfilesWithMedia(ctx context.Context, authId string, mediaID string) {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		log.Printf("Invalid mediaID: %v", err)
+		return
+	}
+	profiles, err := profilesWithMedia(ctx, authId, mediaObjectID)
+	if err != nil {
+		log.Printf("Error fetching profiles with media %s: %v", mediaID, err)
+		return
+	}
+	for _, profile := range profiles {
+		profileService.refreshProfileCompletionScore(ctx, profile.ID)
+	}
+}
+
+// imagesLayout returns the images element of the profile layout, the rules
+// photo changes are checked against.
+func (profileService *ProfileService) imagesLayout(ctx context.Context) *profileLayoutTypes.Images {
+	layout, err := profileService.GetProfileLayout(ctx, profileServiceTypes.GetProfileLayoutType{})
+	if err != nil {
+		return nil
+	}
+	elements, _ := layout.([]profileLayoutTypes.LayoutElement)
+	for _, element := range elements {
+		if images, ok := element.(profileLayoutTypes.Images); ok {
+			return &images
+		}
+	}
+	return nil
+}
+
+// profilesWithMedia returns the profiles of authId that show mediaID.
+func profilesWithMedia(ctx context.Context, authId string, mediaID primitive.ObjectID) ([]models.Profile, error) {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Profile{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"authId": authId, "media.mediaID": mediaID}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var profiles []models.Profile
+	if err := cursor.All(ctx, &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// showsVerifiedPhotos reports whether media still holds every photo the
+// verification selfie of the profile was matched against.
+func showsVerifiedPhotos(profile models.Profile, media []models.MediaType) bool {
+	shown := map[primitive.ObjectID]bool{}
+	for _, mediaEle := range media {
+		shown[mediaEle.MediaID] = true
+	}
+	for _, mediaID := range profile.VerifiedMediaIDs {
+		if !shown[mediaID] {
+			return false
+		}
+	}
+	return true
+}
+
+// updateProfileMedia stores the new photos of a profile. A verified profile
+// loses the badge once a photo it was verified with is no longer shown.
+func updateProfileMedia(ctx context.Context, profile models.Profile, media []models.MediaType) error {
+	update := map[string]interface{}{
+		"media": media,
+	}
+	if profile.VerifiedAt != nil && !showsVerifiedPhotos(profile, media) {
+		update["verifiedAt"] = nil
+		update["verifiedMediaIDs"] = nil
+	}
+	_, err := models.UpdateById(ctx, database.Mongo().Db(), models.Profile{}, profile.ID, update)
+	if err != nil {
+		return err
+	}
+	// The primary photo may have changed
+	profileService := ProfileService{}
+	profileService.refreshProfileCompletionScore(ctx, profile.ID)
+	return nil
+}
+
+// RemoveProfileMedia drops a deleted photo from the profiles showing it and
+// closes the gap it leaves in the order.
+func (profileService *ProfileService) RemoveProfileMedia(ctx context.Context, authId string, mediaID string) {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		log.Printf("Invalid mediaID: %v", err)
+		return
+	}
+	profiles, err := profilesWithMedia(ctx, authId, mediaObjectID)
+	if err != nil {
+		log.Printf("Error fetching profiles with media %s: %v", mediaID, err)
+		return
+	}
+	for _, profile := range profiles {
+		mediaArr := []models.MediaType{}
+		for _, mediaEle := range profile.Media {
+			if mediaEle.MediaID != mediaObjectID {
+				mediaArr = append(mediaArr, mediaEle)
+			}
+		}
+		sort.SliceStable(mediaArr, func(i, j int) bool {
+			return mediaArr[i].Order < mediaArr[j].Order
+		})
+		for idx := range mediaArr {
+			mediaArr[idx].Order = idx + 1
+		}
+		if err := updateProfileMedia(ctx, profile, mediaArr); err != nil {
+			log.Printf("Error removing media %s from profile %s: %v", mediaID, profile.ID.Hex(), err)
+		}
+	}
+}
+
+// ReplaceProfileMedia puts the new photo in the slot of the replaced one and
+// asks media to blur it.
+func (profileService *ProfileService) ReplaceProfileMedia(ctx context.Context, authId string, mediaID string, newMediaID string) {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		log.Printf("Invalid mediaID: %v", err)
+		return
+	}
+	newMediaObjectID, err := primitive.ObjectIDFromHex(newMediaID)
+	if err != nil {
+		log.Printf("Invalid newMediaID: %v", err)
+		return
+	}
+	profiles, err := profilesWithMedia(ctx, authId, mediaObjectID)
+	if err != nil {
+		log.Printf("Error fetching profiles with media %s: %v", mediaID, err)
+		return
+	}
+	for _, profile := range profiles {
+		mediaArr := []models.MediaType{}
+		for _, mediaEle := range profile.Media {
+			if mediaEle.MediaID == mediaObjectID {
+				mediaEle = models.MediaType{
+					MediaID: newMediaObjectID,
+					Order:   mediaEle.Order,
+				}
+			}
+			mediaArr = append(mediaArr, mediaEle)
+		}
+		if err := updateProfileMedia(ctx, profile, mediaArr); err != nil {
+			log.Printf("Error replacing media %s on profile %s: %v", mediaID, profile.ID.Hex(), err)
+			continue
+		}
+		PubSub.GetClient().PublishToService(ctx, "media", PubSub.PubSubMessageType{
+			Type: "blurImage",
+			Data: map[string]interface{}{
+				"mediaID":   newMediaID,
+				"profileID": profile.ID.Hex(),
+			},
+		})
+	}
+}
+
+// ReorderProfileMedia applies a new photo order, the first media ID becomes
+// order 1. The order is checked against the images rules of the profile
+// layout and has to contain exactly the photos already on the profile.
+func (profileService *ProfileService) ReorderProfileMedia(ctx context.Context, data profileServiceTypes.ReorderMediaType) (string, error) {
+	var profile models.Profile
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{
+		AuthId:   data.AuthId,
+		Category: data.Category,
+	}).Decode(&profile); err != nil {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
+	}
+	if images := profileService.imagesLayout(ctx); images != nil && (len(data.MediaIDs) < images.RequiredCount || len(data.MediaIDs) > images.Count) {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-media-count", 400, "Invalid number of photos")
+	}
+	if len(data.MediaIDs) != len(profile.Media) {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/media-mismatch", 400, "Order must contain the photos on the profile")
+	}
+
+	currentMedia := map[string]models.MediaType{}
+	for _, mediaEle := range profile.Media {
+		currentMedia[mediaEle.MediaID.Hex()] = mediaEle
+	}
+	mediaArr := []models.MediaType{}
+	for idx, mediaID := range data.MediaIDs {
+		mediaEle, ok := currentMedia[mediaID]
+		if !ok {
+			return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/media-mismatch", 400, "Order must contain the photos on the profile")
+		}
+		delete(currentMedia, mediaID)
+		mediaEle.Order = idx + 1
+		mediaArr = append(mediaArr, mediaEle)
+	}
+	if err := updateProfileMedia(ctx, profile, mediaArr); err != nil {
+		log.Printf("Error reordering media of profile %s: %v", profile.ID.Hex(), err)
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/could-not-update-profile", 500, "Could not update profile")
+	}
+	return "Media reordered", nil
+}
+
+// SetMediaFocalPoint stores the point the owner wants a photo cropped around
+// and asks media to crop its variants again. Without a focal point the one
+// media detected is used again.
+func (profileService *ProfileService) SetMediaFocalPoint(ctx context.Context, data profileServiceTypes.MediaFocalPointType) (string, error) {
+	var focalPoint *models.FocalPoint
+	if data.FocalPoint != nil {
+		if data.FocalPoint.X < 0 || data.FocalPoint.X > 1 || data.FocalPoint.Y < 0 || data.FocalPoint.Y > 1 {
+			return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-focal-point", 400, "Focal point must be between 0 and 1")
+		}
+		focalPoint = &models.FocalPoint{X: data.FocalPoint.X, Y: data.FocalPoint.Y}
+	}
+	mediaObjectID, err := primitive.ObjectIDFromHex(data.MediaID)
+	if err != nil {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-image-id", 400, "Invalid image ID")
+	}
+	var profile models.Profile
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{
+		AuthId:   data.AuthId,
+		Category: data.Category,
+	}).Decode(&profile); err != nil {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
+	}
+
+	found := false
+	mediaArr := []models.MediaType{}
+	for _, mediaEle := range profile.Media {
+		if mediaEle.MediaID == mediaObjectID {
+			mediaEle.FocalPoint = focalPoint
+			found = true
+		}
+		mediaArr = append(mediaArr, mediaEle)
+	}
+	if !found {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/media-not-found", 404, "Photo is not on the profile")
+	}
+	if err := updateProfileMedia(ctx, profile, mediaArr); err != nil {
+		log.Printf("Error setting focal point of %s on profile %s: %v", data.MediaID, profile.ID.Hex(), err)
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/could-not-update-profile", 500, "Could not update profile")
+	}
+	PubSub.GetClient().PublishToService(ctx, "media", PubSub.PubSubMessageType{
+		Type: "focalPointChanged",
+		Data: map[string]interface{}{
+			"authId":     data.AuthId,
+			"mediaID":    data.MediaID,
+			"focalPoint": focalPoint,
+		},
+	})
+	return "Focal point updated", nil
+}
@@ -39,13 +39,18 @@ func (profileService *ProfileService) CreateProfile(ctx context.Context, data pr
 
 func (profileService *ProfileService) GetProfile(ctx context.Context, data profileServiceTypes.GetProfileType) (interface{}, error) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"category": *data.Category, "authId": *data.AuthId}}},
+		{{Key: "$match", Value: bson.M{"category": *data.Category, "authId": *data.AuthId, "status": bson.M{"$ne": models.ProfileStatusDeleted}}}},
 		{
 			{Key: "$lookup", Value: bson.M{
-				"from":         "media",
-				"localField":   "media.mediaID",
-				"foreignField": "_id",
-				"as":           "mediaDetails",
+				"from": "media",
+				"let":  bson.M{"mediaArray": "$media"},
+				"pipeline": mongo.Pipeline{
+					{{Key: "$match", Value: bson.M{
+						"$expr": bson.M{"$in": bson.A{"$_id", bson.M{"$ifNull": bson.A{"$$mediaArray.mediaID", bson.A{}}}}},
+					}}},
+					{{Key: "$match", Value: displayableMedia}},
+				},
+				"as": "mediaDetails",
 			}},
 		},
 	}
@@ -62,6 +67,8 @@ func (profileService *ProfileService) GetProfile(ctx context.Context, data profi
 		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
 	}
 	profileToReturn := results[0]
+	profileToReturn["verified"] = profileToReturn["verifiedAt"] != nil
+	profileService.hydrateVoicePrompts(ctx, *data.AuthId, "", results[:1])
 	mediaDetails, ok := profileToReturn["mediaDetails"].(primitive.A)
 	if !ok {
 		fmt.Println("mediaDetails is not of type []primitive.A")
@@ -80,14 +87,31 @@ func (profileService *ProfileService) GetProfile(ctx context.Context, data profi
 			rawMediaListMap[mediaMap["mediaID"].(primitive.ObjectID).Hex()] = mediaMap
 		}
 	}
+	privateMediaIDs := []string{}
+	for _, p := range mediaDetails {
+		if mediaMap, ok := p.(primitive.M); ok && mediaMap["visibility"] == mediaVisibilityPrivate {
+			privateMediaIDs = append(privateMediaIDs, mediaMap["_id"].(primitive.ObjectID).Hex())
+		}
+	}
+	// Originals are private, the owner gets short lived signed URLs instead
+	signedURLs := profileService.signedMediaURLs(ctx, *data.AuthId, "", privateMediaIDs)
 	for _, p := range mediaDetails {
 		if mediaMap, ok := p.(primitive.M); ok {
+			mediaID := mediaMap["_id"].(primitive.ObjectID).Hex()
+			mediaURL := mediaMap["url"]
+			if mediaMap["visibility"] == mediaVisibilityPrivate {
+				mediaURL = signedURLs[mediaID]
+			}
 			mediaArr = append(mediaArr, primitive.M{
-				"id":       rawMediaListMap[mediaMap["_id"].(primitive.ObjectID).Hex()]["_id"],
+				"id":       rawMediaListMap[mediaID]["_id"],
 				"ext":      mediaMap["ext"],
-				"order":    rawMediaListMap[mediaMap["_id"].(primitive.ObjectID).Hex()]["order"],
-				"mediaURL": mediaMap["url"],
+				"order":    rawMediaListMap[mediaID]["order"],
+				"mediaURL": mediaURL,
 				"mediaID":  mediaMap["_id"],
+
+				"blurHash":      mediaMap["blurHash"],
+				"dominantColor": mediaMap["dominantColor"],
+				"focalPoint":    rawMediaListMap[mediaID]["focalPoint"],
 			})
 		}
 	}
@@ -217,6 +241,17 @@ func (profileService *ProfileService) GetProfileLayout(ctx context.Context, data
 						Placeholder: "Enter a prompt",
 					},
 				},
+				profileLayoutTypes.VoicePrompt{
+					Element: profileLayoutTypes.Element{
+						Id:    "voicePrompt",
+						Type:  profileLayoutTypes.VoicePromptElementType,
+						Label: "Say it out loud",
+					},
+					Count:              1,
+					MaxDurationSeconds: 30,
+					ContentTypes:       []string{"audio/mp4", "audio/aac", "audio/mpeg", "audio/ogg"},
+					UploadPurpose:      mediaPurposeVoicePrompt,
+				},
 			},
 		},
 		profileLayoutTypes.Images{
@@ -256,7 +291,10 @@ func (profileService *ProfileService) GetProfileLayout(ctx context.Context, data
 	}, nil
 }
 
-func (profileService *ProfileService) computeProfileCompletionScore(profile *models.Profile) int {
+// A primary photo media raised quality warnings for costs this many points.
+const lowQualityPrimaryPhotoPenalty = 1
+
+func (profileService *ProfileService) computeProfileCompletionScore(ctx context.Context, profile *models.Profile) int {
 	score := 0
 	if profile.Name != "" {
 		score++
@@ -276,6 +314,9 @@ func (profileService *ProfileService) computeProfileCompletionScore(profile *mod
 	if profile.LocationLabel != "" {
 		score++
 	}
+	if lowQualityPrimaryPhoto(ctx, profile.Media) {
+		score = max(score-lowQualityPrimaryPhotoPenalty, 0)
+	}
 	return score
 }
 
@@ -340,16 +381,32 @@ func (profileService *ProfileService) UpsertDatingProfile(ctx context.Context, p
 			if err != nil {
 				return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-prompt-id", 400, "Invalid prompt ID")
 			}
-			prompts = append(prompts, models.PromptElementType{
+			promptElement := models.PromptElementType{
 				Prompt: promptId,
 				Answer: prompt.Answer,
-			})
+			}
+			if prompt.MediaID != "" {
+				mediaID, err := primitive.ObjectIDFromHex(prompt.MediaID)
+				if err != nil {
+					return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-media-id", 400, "Invalid voice prompt media ID")
+				}
+				promptElement.MediaID = mediaID
+			}
+			prompts = append(prompts, promptElement)
+		}
+		if err := profileService.checkVoicePrompts(ctx, *profile.AuthId, prompts); err != nil {
+			return "", err
 		}
 		upsertData.Prompts = prompts
 	}
 	var mediaIDsToBlur []string
 	if profile.Media != nil {
 		var mediaElements []models.MediaType
+		// Focal points are set on their own, keep them for photos still shown
+		focalPoints := map[primitive.ObjectID]*models.FocalPoint{}
+		for _, mediaEle := range existingProfile.Media {
+			focalPoints[mediaEle.MediaID] = mediaEle.FocalPoint
+		}
 		for _, media := range *profile.Media {
 			mediaID, err := primitive.ObjectIDFromHex(media.MediaID)
 			if err != nil {
@@ -362,8 +419,9 @@ func (profileService *ProfileService) UpsertDatingProfile(ctx context.Context, p
 				blurredImageID = &blurredImageObjectID
 			}
 			toAppendMediaEle := models.MediaType{
-				MediaID: mediaID,
-				Order:   media.Order,
+				MediaID:    mediaID,
+				Order:      media.Order,
+				FocalPoint: focalPoints[mediaID],
 			}
 			if blurredImageID != nil {
 				toAppendMediaEle.BlurredImageID = *blurredImageID
@@ -379,7 +437,7 @@ func (profileService *ProfileService) UpsertDatingProfile(ctx context.Context, p
 		}
 	}
 
-	upsertData.ProfileCompletionScore = profileService.computeProfileCompletionScore(&upsertData)
+	upsertData.ProfileCompletionScore = profileService.computeProfileCompletionScore(ctx, &upsertData)
 
 	upsertResult, err := models.Upsert(ctx, database.Mongo().Db(), filter, upsertData)
 	if err != nil {
@@ -532,7 +590,7 @@ func (profileService *ProfileService) GetProfiles(ctx context.Context, data prof
 	fmt.Println("Get profiles called")
 	pipeline := mongo.Pipeline{
 		// Match by category
-		{{Key: "$match", Value: bson.M{"category": data.Category}}},
+		{{Key: "$match", Value: bson.M{"category": data.Category, "status": bson.M{"$ne": models.ProfileStatusDeleted}}}},
 
 		// Lookup mediaDetails for each media.mediaID
 		{{Key: "$lookup", Value: bson.M{
@@ -542,6 +600,7 @@ func (profileService *ProfileService) GetProfiles(ctx context.Context, data prof
 				{{Key: "$match", Value: bson.M{
 					"$expr": bson.M{"$in": bson.A{"$_id", "$$mediaArray.mediaID"}},
 				}}},
+				{{Key: "$match", Value: displayableMedia}},
 			},
 			"as": "mediaDetails",
 		}}},
@@ -560,6 +619,7 @@ func (profileService *ProfileService) GetProfiles(ctx context.Context, data prof
 				{{Key: "$match", Value: bson.M{
 					"$expr": bson.M{"$in": bson.A{"$_id", "$$blurredIds"}},
 				}}},
+				{{Key: "$match", Value: displayableMedia}},
 			},
 			"as": "blurredMediaDetails",
 		}}},
@@ -620,6 +680,7 @@ func (profileService *ProfileService) GetProfiles(ctx context.Context, data prof
 					"input": "$media",
 					"as":    "m",
 					"in": bson.M{
+						"focalPoint": "$$m.focalPoint",
 						"media": bson.M{
 							"$arrayElemAt": bson.A{
 								bson.M{
@@ -671,24 +732,80 @@ func (profileService *ProfileService) GetProfiles(ctx context.Context, data prof
 	if len(results) == 0 {
 		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
 	}
+	revealed, err := profileService.revealedProfileIDs(ctx, profileData.ID)
+	if err != nil {
+		log.Printf("Error fetching reveals: %v", err)
+		revealed = map[primitive.ObjectID]bool{}
+	}
+
 	var profiles []primitive.M
+	var privateMedia []primitive.M
 
 	for _, profile := range results {
 		runes := []rune(profile["name"].(string))
 		firstNameChar := unicode.ToUpper(runes[0])
 		profile["name"] = fmt.Sprintf("%s...", string(firstNameChar))
+		profile["verified"] = profile["verifiedAt"] != nil
+
+		// Private originals are only returned to viewers the owner revealed them to,
+		// everyone else gets the blurred image alone
+		profileID, _ := profile["_id"].(primitive.ObjectID)
+		entitled := profile["authId"] == data.AuthId || revealed[profileID]
+		if mediaDetails, ok := profile["mediaDetails"].(primitive.A); ok {
+			visibleMedia := primitive.A{}
+			for _, item := range mediaDetails {
+				mediaItem, ok := item.(primitive.M)
+				if !ok {
+					continue
+				}
+				// Nothing is left of quarantined photos, drop the slot
+				_, hasMedia := mediaItem["media"]
+				_, hasBlurredImage := mediaItem["blurredImage"]
+				if !hasMedia && !hasBlurredImage {
+					continue
+				}
+				visibleMedia = append(visibleMedia, mediaItem)
+				setMediaPlaceholders(mediaItem)
+				media, ok := mediaItem["media"].(primitive.M)
+				if !ok || media["visibility"] != mediaVisibilityPrivate {
+					continue
+				}
+				if !entitled {
+					delete(mediaItem, "media")
+					continue
+				}
+				privateMedia = append(privateMedia, media)
+			}
+			profile["mediaDetails"] = visibleMedia
+		}
 		profiles = append(profiles, profile)
 	}
 
+	privateMediaIDs := []string{}
+	for _, media := range privateMedia {
+		privateMediaIDs = append(privateMediaIDs, media["_id"].(primitive.ObjectID).Hex())
+	}
+	// Revealed photos come back watermarked with the viewer's profile ID
+	signedURLs := profileService.signedMediaURLs(ctx, data.AuthId, profileData.ID.Hex(), privateMediaIDs)
+	for _, media := range privateMedia {
+		media["url"] = signedURLs[media["_id"].(primitive.ObjectID).Hex()]
+	}
+	profileService.hydrateVoicePrompts(ctx, data.AuthId, profileData.ID.Hex(), profiles)
+
 	return profiles, nil
 }
 
-func (profileService *ProfileService) UpsertProfileBlurredImage(ctx context.Context, mediaID string, blurredImageID string, profileID string) {
+func (profileService *ProfileService) UpsertProfileBlurredImage(ctx context.Context, mediaID string, canonicalMediaID string, blurredImageID string, profileID string) {
 	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
 	if err != nil {
 		log.Printf("Invalid mediaObjectID: %v", err)
 		return
 	}
+	canonicalMediaObjectID, err := primitive.ObjectIDFromHex(canonicalMediaID)
+	if err != nil {
+		log.Printf("Invalid canonicalMediaID: %v", err)
+		return
+	}
 	profileObjectID, err := primitive.ObjectIDFromHex(profileID)
 	if err != nil {
 		log.Printf("Invalid blurredMediaID: %v", err)
@@ -712,10 +829,24 @@ func (profileService *ProfileService) UpsertProfileBlurredImage(ctx context.Cont
 		return
 	}
 	media := profileData.Media
+	isDuplicate := canonicalMediaObjectID != mediaObjectID
+	hasCanonical := false
+	for _, mediaEle := range media {
+		if isDuplicate && mediaEle.MediaID == canonicalMediaObjectID {
+			hasCanonical = true
+		}
+	}
 	mediaArr := []models.MediaType{}
 	for _, mediaEle := range media {
 		currMediaEle := mediaEle
 		if mediaEle.MediaID.Hex() == mediaObjectID.Hex() {
+			// The upload was merged into a photo already on the profile
+			if isDuplicate && hasCanonical {
+				continue
+			}
+			currMediaEle.MediaID = canonicalMediaObjectID
+			currMediaEle.BlurredImageID = blurredImageObjectID
+		} else if isDuplicate && mediaEle.MediaID == canonicalMediaObjectID {
 			currMediaEle.BlurredImageID = blurredImageObjectID
 		}
 		mediaArr = append(mediaArr, currMediaEle)
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"log"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	"profiles/internal/types/profileServiceTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
+	mediaHelper "profiles/internal/utils/helpers/mediaHelpers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const mediaVisibilityPrivate = "private"
+
+// revealParties resolves the profile of the caller and the profile the reveal
+// is about, both have to be in the same category.
+func (profileService *ProfileService) revealParties(ctx context.Context, data profileServiceTypes.RevealProfileMediaType) (*models.Profile, primitive.ObjectID, error) {
+	viewerProfileID, err := primitive.ObjectIDFromHex(data.ViewerProfileID)
+	if err != nil {
+		return nil, primitive.NilObjectID, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-profile-id", 400, "Invalid profile ID")
+	}
+	var selfProfile models.Profile
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{
+		AuthId:   data.AuthId,
+		Category: data.Category,
+	}).Decode(&selfProfile); err != nil {
+		return nil, primitive.NilObjectID, httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
+	}
+	if selfProfile.ID == viewerProfileID {
+		return nil, primitive.NilObjectID, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-profile-id", 400, "Cannot reveal media to yourself")
+	}
+	var viewerProfile models.Profile
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{
+		ID:       viewerProfileID,
+		Category: data.Category,
+	}).Decode(&viewerProfile); err != nil {
+		return nil, primitive.NilObjectID, httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
+	}
+	return &selfProfile, viewerProfile.ID, nil
+}
+
+// RevealProfileMedia lets another profile see the caller's original photos.
+func (profileService *ProfileService) RevealProfileMedia(ctx context.Context, data profileServiceTypes.RevealProfileMediaType) (string, error) {
+	selfProfile, viewerProfileID, err := profileService.revealParties(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	_, err = models.Upsert(ctx, database.Mongo().Db(), bson.M{
+		"profileID":       selfProfile.ID,
+		"viewerProfileID": viewerProfileID,
+	}, models.Reveal{
+		ProfileID:       selfProfile.ID,
+		ViewerProfileID: viewerProfileID,
+	})
+	if err != nil {
+		log.Printf("Error revealing media of %s: %v", selfProfile.ID.Hex(), err)
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/could-not-reveal-media", 500, "Could not reveal media")
+	}
+	return "Media revealed", nil
+}
+
+// ConcealProfileMedia revokes a reveal, signed URLs already handed out stay
+// valid until they expire.
+func (profileService *ProfileService) ConcealProfileMedia(ctx context.Context, data profileServiceTypes.RevealProfileMediaType) (string, error) {
+	selfProfile, viewerProfileID, err := profileService.revealParties(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	_, err = models.DeleteOne(ctx, database.Mongo().Db(), models.Reveal{}, bson.M{
+		"profileID":       selfProfile.ID,
+		"viewerProfileID": viewerProfileID,
+	})
+	if err != nil {
+		log.Printf("Error concealing media of %s: %v", selfProfile.ID.Hex(), err)
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/could-not-conceal-media", 500, "Could not conceal media")
+	}
+	return "Media concealed", nil
+}
+
+// revealedProfileIDs returns the profiles that revealed their media to viewerProfileID.
+func (profileService *ProfileService) revealedProfileIDs(ctx context.Context, viewerProfileID primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Reveal{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"viewerProfileID": viewerProfileID}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var reveals []models.Reveal
+	if err := cursor.All(ctx, &reveals); err != nil {
+		return nil, err
+	}
+	revealed := map[primitive.ObjectID]bool{}
+	for _, reveal := range reveals {
+		revealed[reveal.ProfileID] = true
+	}
+	return revealed, nil
+}
+
+// signedMediaURLs fetches download URLs for private originals the viewer is
+// entitled to. Failures are logged and leave the media without a URL rather
+// than failing the whole profile.
+func (profileService *ProfileService) signedMediaURLs(ctx context.Context, viewerAuthId string, viewerProfileID string, mediaIDs []string) map[string]string {
+	urls := map[string]string{}
+	signedUrls, err := mediaHelper.GetSignedDownloadUrls(ctx, viewerAuthId, viewerProfileID, mediaIDs)
+	if err != nil {
+		log.Printf("Error fetching signed media URLs: %v", err)
+		return urls
+	}
+	for mediaID, signedUrl := range signedUrls {
+		urls[mediaID] = signedUrl.URL
+	}
+	return urls
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"profiles/internal/config"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	"profiles/internal/providers/faceMatch"
+	"profiles/internal/types/profileServiceTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
+	mediaHelper "profiles/internal/utils/helpers/mediaHelpers"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	verificationChallengeTTL      = 10 * time.Minute
+	verificationQueuePageSize     = 20
+	verificationStatusUnverified  = "unverified"
+	verificationReviewerFaceMatch = "faceMatch"
+	mediaPurposeVerification      = "verification"
+)
+
+// Poses a verification selfie has to show, picked at random so an old photo
+// can't be handed in.
+var verificationPoses = []string{
+	"thumbsUp",
+	"peaceSign",
+	"touchNose",
+	"handOnChin",
+	"waveHand",
+	"pointUp",
+}
+
+var (
+	faceComparator     faceMatch.Comparator
+	faceComparatorOnce sync.Once
+)
+
+// getFaceComparator returns the automatic comparator, nil when none is
+// configured and every submission waits for a reviewer.
+func getFaceComparator() faceMatch.Comparator {
+	faceComparatorOnce.Do(func() {
+		faceMatchConfig := config.GetConfig().FaceMatch
+		if faceMatchConfig.APIURL != "" {
+			faceComparator = faceMatch.NewAPIComparator(faceMatchConfig.APIURL, faceMatchConfig.APIKey)
+		}
+	})
+	return faceComparator
+}
+
+func verificationProfile(ctx context.Context, authId string, category string) (*models.Profile, error) {
+	var profile models.Profile
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{
+		AuthId:   authId,
+		Category: category,
+	}).Decode(&profile); err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/profile-not-found", 404, "Profile not found")
+	}
+	return &profile, nil
+}
+
+// latestVerification returns the most recent verification of a profile, nil
+// if it never started one.
+func latestVerification(ctx context.Context, profileID primitive.ObjectID) (*models.Verification, error) {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Verification{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"profileID": profileID}}},
+		{{Key: "$sort", Value: bson.M{"createdAt": -1}}},
+		{{Key: "$limit", Value: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var verifications []models.Verification
+	if err := cursor.All(ctx, &verifications); err != nil {
+		return nil, err
+	}
+	if len(verifications) == 0 {
+		return nil, nil
+	}
+	return &verifications[0], nil
+}
+
+func verificationRes(verification *models.Verification, profile *models.Profile) *profileServiceTypes.VerificationResType {
+	res := &profileServiceTypes.VerificationResType{
+		Status:     verificationStatusUnverified,
+		VerifiedAt: profile.VerifiedAt,
+	}
+	if verification == nil {
+		return res
+	}
+	res.VerificationID = verification.ID.Hex()
+	res.Status = verification.Status
+	res.RejectedReason = verification.RejectedReason
+	if verification.Status == models.VerificationStatusChallenged {
+		res.Pose = verification.Pose
+		res.ExpiresAt = &verification.ExpiresAt
+	}
+	return res
+}
+
+// RequestVerificationChallenge starts a verification with a random pose the
+// selfie has to show.
+func (profileService *ProfileService) RequestVerificationChallenge(ctx context.Context, data profileServiceTypes.VerificationType) (*profileServiceTypes.VerificationResType, error) {
+	profile, err := verificationProfile(ctx, data.AuthId, data.Category)
+	if err != nil {
+		return nil, err
+	}
+	if profile.VerifiedAt != nil {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/already-verified", 400, "Profile is already verified")
+	}
+	current, err := latestVerification(ctx, profile.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Status == models.VerificationStatusPending {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-pending", 409, "A verification is already under review")
+	}
+
+	verification := models.Verification{
+		ProfileID: profile.ID,
+		AuthId:    profile.AuthId,
+		Pose:      verificationPoses[rand.IntN(len(verificationPoses))],
+		ExpiresAt: time.Now().Add(verificationChallengeTTL),
+		Status:    models.VerificationStatusChallenged,
+	}
+	created, err := models.Create(ctx, database.Mongo().Db(), verification)
+	if err != nil {
+		log.Printf("Error creating verification for %s: %v", profile.ID.Hex(), err)
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/could-not-start-verification", 500, "Could not start verification")
+	}
+	verification.ID = created.InsertedID.(primitive.ObjectID)
+	return verificationRes(&verification, profile), nil
+}
+
+// SubmitVerification hands in the selfie for a challenge. The selfie has to
+// be an upload of the caller with the verification purpose.
+func (profileService *ProfileService) SubmitVerification(ctx context.Context, data profileServiceTypes.VerificationType) (*profileServiceTypes.VerificationResType, error) {
+	profile, err := verificationProfile(ctx, data.AuthId, data.Category)
+	if err != nil {
+		return nil, err
+	}
+	verificationID, err := primitive.ObjectIDFromHex(data.VerificationID)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-verification-id", 400, "Invalid verification ID")
+	}
+	mediaID, err := primitive.ObjectIDFromHex(data.MediaID)
+	if err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-media-id", 400, "Invalid media ID")
+	}
+	var verification models.Verification
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Verification{
+		ID:        verificationID,
+		ProfileID: profile.ID,
+	}).Decode(&verification); err != nil {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-not-found", 404, "Verification not found")
+	}
+	if verification.Status != models.VerificationStatusChallenged {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-submitted", 400, "A selfie was already submitted for this verification")
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-expired", 400, "The challenge expired, request a new one")
+	}
+
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id":     mediaID,
+			"authId":  data.AuthId,
+			"purpose": mediaPurposeVerification,
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-verification-media", 400, "Selfie must be uploaded for verification")
+	}
+
+	photoIDs := []primitive.ObjectID{}
+	for _, media := range profile.Media {
+		photoIDs = append(photoIDs, media.MediaID)
+	}
+	// Only the submission that moves the challenge on is judged, a concurrent
+	// one or one racing the expiry matches nothing
+	result, err := models.UpdateOne(ctx, database.Mongo().Db(), models.Verification{}, bson.M{
+		"_id":       verification.ID,
+		"status":    models.VerificationStatusChallenged,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}, map[string]interface{}{
+		"mediaID":  mediaID,
+		"photoIDs": photoIDs,
+		"status":   models.VerificationStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-submitted", 400, "A selfie was already submitted for this verification")
+	}
+	verification.MediaID = mediaID
+	verification.PhotoIDs = photoIDs
+	verification.Status = models.VerificationStatusPending
+	profileService.compareVerification(ctx, &verification, profile)
+	return verificationRes(&verification, profile), nil
+}
+
+// verificationPhotoURLs returns signed URLs of the selfie and the profile
+// photos it is compared against, issued for the owner of the profile.
+func verificationPhotoURLs(ctx context.Context, verification *models.Verification, profile *models.Profile) (string, []string, error) {
+	mediaIDs := []string{verification.MediaID.Hex()}
+	for _, photoID := range verification.PhotoIDs {
+		mediaIDs = append(mediaIDs, photoID.Hex())
+	}
+	urls, err := mediaHelper.GetSignedDownloadUrls(ctx, profile.AuthId, "", mediaIDs)
+	if err != nil {
+		return "", nil, err
+	}
+	photoURLs := []string{}
+	for _, mediaID := range mediaIDs[1:] {
+		if url, ok := urls[mediaID]; ok {
+			photoURLs = append(photoURLs, url.URL)
+		}
+	}
+	return urls[verification.MediaID.Hex()].URL, photoURLs, nil
+}
+
+// compareVerification runs the automatic comparator on a submission. Clear
+// matches are approved and clear mismatches rejected, anything else and any
+// failure leaves the submission to a reviewer.
+func (profileService *ProfileService) compareVerification(ctx context.Context, verification *models.Verification, profile *models.Profile) {
+	comparator := getFaceComparator()
+	if comparator == nil || len(verification.PhotoIDs) == 0 {
+		return
+	}
+	selfieURL, photoURLs, err := verificationPhotoURLs(ctx, verification, profile)
+	if err != nil || selfieURL == "" || len(photoURLs) == 0 {
+		log.Printf("Skipping face match of verification %s: %v", verification.ID.Hex(), err)
+		return
+	}
+	result, err := comparator.Compare(ctx, faceMatch.CompareType{
+		SelfieURL: selfieURL,
+		PhotoURLs: photoURLs,
+		Pose:      verification.Pose,
+	})
+	if err != nil {
+		log.Printf("Error matching faces of verification %s: %v", verification.ID.Hex(), err)
+		return
+	}
+	verification.MatchScore = &result.Score
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Verification{}, verification.ID, map[string]interface{}{
+		"matchScore": result.Score,
+	}); err != nil {
+		log.Printf("Error storing match score of verification %s: %v", verification.ID.Hex(), err)
+	}
+
+	faceMatchConfig := config.GetConfig().FaceMatch
+	status, reason := "", ""
+	if result.Score >= faceMatchConfig.ApproveThreshold && result.PoseMatched {
+		status = models.VerificationStatusApproved
+	} else if result.Score <= faceMatchConfig.RejectThreshold {
+		status, reason = models.VerificationStatusRejected, "faceMismatch"
+	}
+	if status == "" {
+		return
+	}
+	if err := setVerificationStatus(ctx, verification, profile.ID, status, verificationReviewerFaceMatch, reason); err != nil {
+		log.Printf("Error updating verification %s: %v", verification.ID.Hex(), err)
+		return
+	}
+	if status == models.VerificationStatusApproved {
+		now := time.Now()
+		profile.VerifiedAt = &now
+		profile.VerifiedMediaIDs = verification.PhotoIDs
+	}
+}
+
+// setVerificationStatus records the outcome of a review, approving marks the
+// profile as verified for the photos the selfie was compared against.
+func setVerificationStatus(ctx context.Context, verification *models.Verification, profileID primitive.ObjectID, status string, reviewer string, reason string) error {
+	update := map[string]interface{}{
+		"status":     status,
+		"reviewedBy": reviewer,
+	}
+	if reason != "" {
+		update["rejectedReason"] = reason
+	}
+	if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Verification{}, verification.ID, update); err != nil {
+		return err
+	}
+	verification.Status = status
+	verification.ReviewedBy = reviewer
+	verification.RejectedReason = reason
+	if status != models.VerificationStatusApproved {
+		return nil
+	}
+	_, err := models.UpdateById(ctx, database.Mongo().Db(), models.Profile{}, profileID, map[string]interface{}{
+		"verifiedAt":       time.Now(),
+		"verifiedMediaIDs": verification.PhotoIDs,
+	})
+	return err
+}
+
+func (profileService *ProfileService) GetVerification(ctx context.Context, data profileServiceTypes.VerificationType) (*profileServiceTypes.VerificationResType, error) {
+	profile, err := verificationProfile(ctx, data.AuthId, data.Category)
+	if err != nil {
+		return nil, err
+	}
+	verification, err := latestVerification(ctx, profile.ID)
+	if err != nil {
+		return nil, err
+	}
+	return verificationRes(verification, profile), nil
+}
+
+// GetVerificationQueue lists the submissions waiting for a reviewer, oldest
+// first, with the selfie next to the photos on the profile.
+func (profileService *ProfileService) GetVerificationQueue(ctx context.Context, data profileServiceTypes.GetVerificationQueueType) (*profileServiceTypes.VerificationQueueResType, error) {
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Verification{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": models.VerificationStatusPending}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"verifications": bson.A{
+				bson.M{"$sort": bson.M{"_id": 1}},
+				bson.M{"$skip": data.Page * verificationQueuePageSize},
+				bson.M{"$limit": verificationQueuePageSize},
+			},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var pages []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Verifications []models.Verification `bson:"verifications"`
+	}
+	if err := cursor.All(ctx, &pages); err != nil {
+		return nil, err
+	}
+
+	queue := &profileServiceTypes.VerificationQueueResType{
+		Verifications: []profileServiceTypes.VerificationQueueItemType{},
+	}
+	if len(pages) == 0 {
+		return queue, nil
+	}
+	if len(pages[0].Total) > 0 {
+		queue.Total = pages[0].Total[0].Count
+	}
+	for _, verification := range pages[0].Verifications {
+		var profile models.Profile
+		if err := models.FindOne(ctx, database.Mongo().Db(), models.Profile{
+			ID: verification.ProfileID,
+		}).Decode(&profile); err != nil {
+			log.Printf("Error fetching profile of verification %s: %v", verification.ID.Hex(), err)
+			continue
+		}
+		selfieURL, photoURLs, err := verificationPhotoURLs(ctx, &verification, &profile)
+		if err != nil {
+			log.Printf("Error signing photos of verification %s: %v", verification.ID.Hex(), err)
+		}
+		queue.Verifications = append(queue.Verifications, profileServiceTypes.VerificationQueueItemType{
+			VerificationID: verification.ID.Hex(),
+			ProfileID:      verification.ProfileID.Hex(),
+			Pose:           verification.Pose,
+			SelfieURL:      selfieURL,
+			PhotoURLs:      photoURLs,
+			MatchScore:     verification.MatchScore,
+		})
+	}
+	return queue, nil
+}
+
+// ReviewVerification approves or rejects a pending submission.
+func (profileService *ProfileService) ReviewVerification(ctx context.Context, data profileServiceTypes.ReviewVerificationType) (string, error) {
+	if data.Status != models.VerificationStatusApproved && data.Status != models.VerificationStatusRejected {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-verification-status", 400, "Status must be approved or rejected")
+	}
+	if data.ReviewerID == "" {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-data", 400, "Reviewer is required")
+	}
+	verificationID, err := primitive.ObjectIDFromHex(data.VerificationID)
+	if err != nil {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-verification-id", 400, "Invalid verification ID")
+	}
+	var verification models.Verification
+	if err := models.FindOne(ctx, database.Mongo().Db(), models.Verification{
+		ID: verificationID,
+	}).Decode(&verification); err != nil {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-not-found", 404, "Verification not found")
+	}
+	if verification.Status != models.VerificationStatusPending {
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/verification-not-pending", 400, "Verification is not waiting for review")
+	}
+	if err := setVerificationStatus(ctx, &verification, verification.ProfileID, data.Status, data.ReviewerID, data.Reason); err != nil {
+		log.Printf("Error reviewing verification %s: %v", verification.ID.Hex(), err)
+		return "", httpErrors.HydrateHttpError("purely/profiles/requests/errors/could-not-review-verification", 500, "Could not review verification")
+	}
+	return "Verification reviewed", nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	"profiles/internal/types/profileServiceTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// commandCount counts the commands of the given name sent to the deployment.
+func commandCount(mt *mtest.T, name string) int {
+	count := 0
+	for _, event := range mt.GetAllStartedEvents() {
+		if event.CommandName == name {
+			count++
+		}
+	}
+	return count
+}
+
+func errorCode(err error) string {
+	var httpError *httpErrors.HttpError
+	if errors.As(err, &httpError) {
+		return httpError.Code
+	}
+	return ""
+}
+
+func TestVerification(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	profileService := &ProfileService{}
+	profile := bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "authId", Value: "user"},
+		{Key: "category", Value: "dating"},
+		{Key: "media", Value: bson.A{bson.D{{Key: "mediaID", Value: primitive.NewObjectID()}, {Key: "order", Value: 1}}}},
+	}
+	challenge := func(expiresAt time.Time) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "profileID", Value: profile[0].Value},
+			{Key: "pose", Value: "thumbsUp"},
+			{Key: "expiresAt", Value: expiresAt},
+			{Key: "status", Value: models.VerificationStatusChallenged},
+		}
+	}
+	submission := func(verification bson.D) profileServiceTypes.VerificationType {
+		return profileServiceTypes.VerificationType{
+			AuthId:         "user",
+			Category:       "dating",
+			VerificationID: verification[0].Value.(primitive.ObjectID).Hex(),
+			MediaID:        primitive.NewObjectID().Hex(),
+		}
+	}
+
+	mt.Run("challenge", func(mt *mtest.T) {
+		database.Use(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".profiles", mtest.FirstBatch, profile),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".verifications", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+		res, err := profileService.RequestVerificationChallenge(context.Background(), profileServiceTypes.VerificationType{AuthId: "user", Category: "dating"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if res.Status != models.VerificationStatusChallenged || !slices.Contains(verificationPoses, res.Pose) {
+			mt.Errorf("unexpected challenge %+v", res)
+		}
+		if res.ExpiresAt == nil || time.Until(*res.ExpiresAt) > verificationChallengeTTL || time.Until(*res.ExpiresAt) < verificationChallengeTTL-time.Minute {
+			mt.Errorf("expected the challenge to expire in %v, got %v", verificationChallengeTTL, res.ExpiresAt)
+		}
+	})
+
+	mt.Run("submit", func(mt *mtest.T) {
+		database.Use(mt.DB)
+		verification := challenge(time.Now().Add(verificationChallengeTTL))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".profiles", mtest.FirstBatch, profile),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".verifications", mtest.FirstBatch, verification),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".media", mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		res, err := profileService.SubmitVerification(context.Background(), submission(verification))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if res.Status != models.VerificationStatusPending {
+			mt.Errorf("expected the verification to be pending, got %q", res.Status)
+		}
+	})
+
+	mt.Run("concurrent submit", func(mt *mtest.T) {
+		database.Use(mt.DB)
+		verification := challenge(time.Now().Add(verificationChallengeTTL))
+		// The challenge was still open when read, another submission moved
+		// it on before the update
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".profiles", mtest.FirstBatch, profile),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".verifications", mtest.FirstBatch, verification),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".media", mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+		_, err := profileService.SubmitVerification(context.Background(), submission(verification))
+		if code := errorCode(err); code != "purely/profiles/requests/errors/verification-submitted" {
+			mt.Errorf("expected the submission to be refused, got %v", err)
+		}
+	})
+
+	mt.Run("expired", func(mt *mtest.T) {
+		database.Use(mt.DB)
+		verification := challenge(time.Now().Add(-time.Minute))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".profiles", mtest.FirstBatch, profile),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".verifications", mtest.FirstBatch, verification),
+		)
+		_, err := profileService.SubmitVerification(context.Background(), submission(verification))
+		if code := errorCode(err); code != "purely/profiles/requests/errors/verification-expired" {
+			mt.Errorf("expected the challenge to be expired, got %v", err)
+		}
+		if updates := commandCount(mt, "update"); updates != 0 {
+			mt.Errorf("expected no update of an expired challenge, got %d", updates)
+		}
+	})
+}
+
+func TestShowsVerifiedPhotos(t *testing.T) {
+	kept, replaced := primitive.NewObjectID(), primitive.NewObjectID()
+	now := time.Now()
+	profile := models.Profile{VerifiedAt: &now, VerifiedMediaIDs: []primitive.ObjectID{kept, replaced}}
+
+	reordered := []models.MediaType{{MediaID: replaced, Order: 1}, {MediaID: kept, Order: 2}}
+	if !showsVerifiedPhotos(profile, reordered) {
+		t.Error("expected a reorder to keep the badge")
+	}
+	swapped := []models.MediaType{{MediaID: kept, Order: 1}, {MediaID: primitive.NewObjectID(), Order: 2}}
+	if showsVerifiedPhotos(profile, swapped) {
+		t.Error("expected replacing a verified photo to drop the badge")
+	}
+	if showsVerifiedPhotos(profile, reordered[1:]) {
+		t.Error("expected removing a verified photo to drop the badge")
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"log"
+	"profiles/internal/database"
+	"profiles/internal/database/models"
+	profileLayoutTypes "profiles/internal/types/profileLayout"
+	"profiles/internal/types/profileServiceTypes"
+	httpErrors "profiles/internal/utils/helpers/httpError"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const mediaPurposeVoicePrompt = "voicePrompt"
+
+// voicePromptLayout returns the voice prompt element of the profile layout.
+func (profileService *ProfileService) voicePromptLayout(ctx context.Context) *profileLayoutTypes.VoicePrompt {
+	layout, err := profileService.GetProfileLayout(ctx, profileServiceTypes.GetProfileLayoutType{})
+	if err != nil {
+		return nil
+	}
+	elements, _ := layout.([]profileLayoutTypes.LayoutElement)
+	for _, element := range elements {
+		group, ok := element.(profileLayoutTypes.ElementGroup)
+		if !ok {
+			continue
+		}
+		for _, groupElement := range group.Elements {
+			if voicePrompt, ok := groupElement.(profileLayoutTypes.VoicePrompt); ok {
+				return &voicePrompt
+			}
+		}
+	}
+	return nil
+}
+
+// checkVoicePrompts makes sure prompts answered with media point at voice
+// recordings of authId, no more than the layout allows.
+func (profileService *ProfileService) checkVoicePrompts(ctx context.Context, authId string, prompts []models.PromptElementType) error {
+	mediaIDs := []primitive.ObjectID{}
+	for _, prompt := range prompts {
+		if prompt.MediaID != primitive.NilObjectID {
+			mediaIDs = append(mediaIDs, prompt.MediaID)
+		}
+	}
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+	layout := profileService.voicePromptLayout(ctx)
+	if layout == nil || len(mediaIDs) > layout.Count {
+		return httpErrors.HydrateHttpError("purely/profiles/requests/errors/too-many-voice-prompts", 400, "Too many voice prompts")
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"_id":     bson.M{"$in": mediaIDs},
+			"authId":  authId,
+			"purpose": mediaPurposeVoicePrompt,
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	var media []models.Media
+	if err := cursor.All(ctx, &media); err != nil {
+		return err
+	}
+	if len(media) != len(mediaIDs) {
+		return httpErrors.HydrateHttpError("purely/profiles/requests/errors/invalid-voice-prompt", 400, "Voice prompts must be recordings uploaded as voicePrompt")
+	}
+	return nil
+}
+
+// hydrateVoicePrompts adds the recording of every voice prompt of profiles:
+// a download URL for the viewer, its duration and waveform. Prompts whose
+// recording is gone are dropped.
+func (profileService *ProfileService) hydrateVoicePrompts(ctx context.Context, viewerAuthId string, viewerProfileID string, profiles []primitive.M) {
+	mediaIDs := []primitive.ObjectID{}
+	for _, profile := range profiles {
+		prompts, _ := profile["prompts"].(primitive.A)
+		for _, item := range prompts {
+			if prompt, ok := item.(primitive.M); ok {
+				if mediaID, ok := prompt["mediaID"].(primitive.ObjectID); ok {
+					mediaIDs = append(mediaIDs, mediaID)
+				}
+			}
+		}
+	}
+	if len(mediaIDs) == 0 {
+		return
+	}
+
+	recordings := map[primitive.ObjectID]models.Media{}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Media{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"_id": bson.M{"$in": mediaIDs}}}},
+	})
+	if err != nil {
+		log.Printf("Error fetching voice prompts: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+	var media []models.Media
+	if err := cursor.All(ctx, &media); err != nil {
+		log.Printf("Error fetching voice prompts: %v", err)
+		return
+	}
+	privateMediaIDs := []string{}
+	for _, recording := range media {
+		recordings[recording.ID] = recording
+		if recording.Visibility == mediaVisibilityPrivate {
+			privateMediaIDs = append(privateMediaIDs, recording.ID.Hex())
+		}
+	}
+	signedURLs := profileService.signedMediaURLs(ctx, viewerAuthId, viewerProfileID, privateMediaIDs)
+
+	for _, profile := range profiles {
+		prompts, ok := profile["prompts"].(primitive.A)
+		if !ok {
+			continue
+		}
+		hydratedPrompts := primitive.A{}
+		for _, item := range prompts {
+			prompt, ok := item.(primitive.M)
+			if !ok {
+				continue
+			}
+			if mediaID, ok := prompt["mediaID"].(primitive.ObjectID); ok {
+				recording, found := recordings[mediaID]
+				if !found {
+					continue
+				}
+				url := recording.Url
+				if recording.Visibility == mediaVisibilityPrivate {
+					url = signedURLs[mediaID.Hex()]
+				}
+				prompt["voice"] = primitive.M{
+					"url":        url,
+					"durationMs": recording.DurationMs,
+					"waveform":   recording.Waveform,
+				}
+			}
+			hydratedPrompts = append(hydratedPrompts, prompt)
+		}
+		profile["prompts"] = hydratedPrompts
+	}
+}
+
+// RemoveVoicePrompts drops the prompts answered with a deleted recording from
+// the profiles of authId.
+func (profileService *ProfileService) RemoveVoicePrompts(ctx context.Context, authId string, mediaID string) {
+	mediaObjectID, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		log.Printf("Invalid mediaID: %v", err)
+		return
+	}
+	cursor, err := models.Aggregate(ctx, database.Mongo().Db(), models.Profile{}, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"authId": authId, "prompts.mediaID": mediaObjectID}}},
+	})
+	if err != nil {
+		log.Printf("Error fetching profiles with voice prompt %s: %v", mediaID, err)
+		return
+	}
+	defer cursor.Close(ctx)
+	var profiles []models.Profile
+	if err := cursor.All(ctx, &profiles); err != nil {
+		log.Printf("Error fetching profiles with voice prompt %s: %v", mediaID, err)
+		return
+	}
+	for _, profile := range profiles {
+		prompts := []models.PromptElementType{}
+		for _, prompt := range profile.Prompts {
+			if prompt.MediaID != mediaObjectID {
+				prompts = append(prompts, prompt)
+			}
+		}
+		if _, err := models.UpdateById(ctx, database.Mongo().Db(), models.Profile{}, profile.ID, map[string]interface{}{
+			"prompts": prompts,
+		}); err != nil {
+			log.Printf("Error removing voice prompt %s from profile %s: %v", mediaID, profile.ID.Hex(), err)
+			continue
+		}
+		// Prompts count towards the completion score
+		profileService.refreshProfileCompletionScore(ctx, profile.ID)
+	}
+}
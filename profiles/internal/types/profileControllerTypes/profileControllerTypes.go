@@ -13,6 +13,7 @@ type MediaElementType struct {
 type DatingPromptType struct {
 	PromptId *string `json:"id"`
 	Answer   *string `json:"answer"`
+	MediaID  *string `json:"mediaID"`
 }
 
 type Location struct {
@@ -56,3 +57,21 @@ type CompleteMultipartUpload struct {
 	FilePath string         `json:"filePath"`
 	Parts    map[int]string `json:"parts"`
 }
+
+type FocalPointType struct {
+	X *float64 `json:"x"`
+	Y *float64 `json:"y"`
+}
+
+type ReorderMediaType struct {
+	MediaIDs *[]string `json:"mediaIDs"`
+}
+
+type RevealProfileMediaType struct {
+	ProfileID *string `json:"profileID"`
+}
+
+type SubmitVerificationType struct {
+	VerificationID *string `json:"verificationID"`
+	MediaID        *string `json:"mediaID"`
+}
@@ -7,6 +7,7 @@ const (
 	InputElementType            elementTypeEnum = "input"
 	SelectElementType           elementTypeEnum = "select"
 	PromptElementType           elementTypeEnum = "prompt"
+	VoicePromptElementType      elementTypeEnum = "voicePrompt"
 	ImageElementType            elementTypeEnum = "image"
 	SearchableSelectElementType elementTypeEnum = "searchableSelect"
 	LocationElementType         elementTypeEnum = "location"
@@ -64,6 +65,16 @@ type Prompt struct {
 	PromptOptions       []string    `json:"staticPrompts"`
 }
 
+// VoicePrompt picks from the same prompts as Prompt but is answered with a
+// recording uploaded to media under UploadPurpose instead of text.
+type VoicePrompt struct {
+	Element
+	Count              int      `json:"count"`
+	MaxDurationSeconds int      `json:"maxDurationSeconds"`
+	ContentTypes       []string `json:"contentTypes"`
+	UploadPurpose      string   `json:"uploadPurpose"`
+}
+
 type Images struct {
 	Element
 	Count         int `json:"count"`
@@ -30,6 +30,7 @@ type MediaElementType struct {
 type DatingPromptType struct {
 	PromptId string `json:"id"`
 	Answer   string `json:"answer"`
+	MediaID  string `json:"mediaID,omitempty"`
 }
 
 type Location struct {
@@ -135,9 +136,10 @@ type MediaType struct {
 }
 
 type PromptElementType struct {
-	ID     string `json:"_id,omitempty"`
-	Prompt string `json:"id"`
-	Answer string `json:"answer"`
+	ID      string `json:"_id,omitempty"`
+	Prompt  string `json:"id"`
+	Answer  string `json:"answer"`
+	MediaID string `json:"mediaID,omitempty"`
 }
 
 type HydratedProfileType struct {
@@ -171,3 +173,77 @@ type HydratedProfileType struct {
 
 	PreferredMatchDistance int `bson:"preferredMatchDistance,omitempty" json:"preferredMatchDistance,omitempty"`
 }
+
+type FocalPointType struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type MediaFocalPointType struct {
+	AuthId     string          `json:"authId"`
+	Category   string          `json:"category"`
+	MediaID    string          `json:"mediaID"`
+	FocalPoint *FocalPointType `json:"focalPoint"`
+}
+
+type ReorderMediaType struct {
+	AuthId   string   `json:"authId"`
+	Category string   `json:"category"`
+	MediaIDs []string `json:"mediaIDs"`
+}
+
+type RevealProfileMediaType struct {
+	AuthId          string `json:"authId"`
+	Category        string `json:"category"`
+	ViewerProfileID string `json:"viewerProfileID"`
+}
+
+type VerificationType struct {
+	AuthId         string `json:"authId"`
+	Category       string `json:"category"`
+	VerificationID string `json:"verificationID"`
+	MediaID        string `json:"mediaID"`
+}
+
+type VerificationResType struct {
+	VerificationID string     `json:"verificationID"`
+	Pose           string     `json:"pose,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	Status         string     `json:"status"`
+	RejectedReason string     `json:"rejectedReason,omitempty"`
+	VerifiedAt     *time.Time `json:"verifiedAt,omitempty"`
+}
+
+type GetVerificationQueueType struct {
+	Page int64 `json:"page"`
+}
+
+type VerificationQueueItemType struct {
+	VerificationID string   `json:"verificationID"`
+	ProfileID      string   `json:"profileID"`
+	Pose           string   `json:"pose"`
+	SelfieURL      string   `json:"selfieURL"`
+	PhotoURLs      []string `json:"photoURLs"`
+	MatchScore     *float64 `json:"matchScore,omitempty"`
+}
+
+type VerificationQueueResType struct {
+	Verifications []VerificationQueueItemType `json:"verifications"`
+	Total         int64                       `json:"total"`
+}
+
+type ReviewVerificationType struct {
+	VerificationID string `json:"verificationID"`
+	Status         string `json:"status"`
+	ReviewerID     string `json:"reviewerID"`
+	Reason         string `json:"reason"`
+}
+
+// ExportedPromptType is a prompt answer as it appears in a data export, with
+// the question spelled out.
+type ExportedPromptType struct {
+	PromptID string `json:"promptID"`
+	Prompt   string `json:"prompt"`
+	Answer   string `json:"answer,omitempty"`
+	MediaID  string `json:"mediaID,omitempty"`
+}
@@ -0,0 +1,75 @@
+package mediaHelper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"profiles/internal/config"
+	"strings"
+	"time"
+)
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+type SignedDownloadUrl struct {
+	URL    string `json:"url"`
+	Expiry int64  `json:"expiry,omitempty"`
+}
+
+type signedDownloadUrlsRequest struct {
+	ViewerAuthId    string   `json:"viewerAuthId"`
+	ViewerProfileID string   `json:"viewerProfileID,omitempty"`
+	MediaIDs        []string `json:"mediaIDs"`
+}
+
+type signedDownloadUrlsResponse struct {
+	Data struct {
+		URLs map[string]SignedDownloadUrl `json:"urls"`
+	} `json:"data"`
+}
+
+// GetSignedDownloadUrls asks the media service for short lived download URLs
+// of the given media. Callers must have checked that the viewer is entitled to
+// see them, the media service trusts this service's decision. With a viewer
+// profile ID, originals of other users point at renditions watermarked with it.
+func GetSignedDownloadUrls(ctx context.Context, viewerAuthId string, viewerProfileID string, mediaIDs []string) (map[string]SignedDownloadUrl, error) {
+	if len(mediaIDs) == 0 {
+		return map[string]SignedDownloadUrl{}, nil
+	}
+	mediaServiceURL := config.GetConfig().MediaServiceURL
+	if mediaServiceURL == "" {
+		return nil, fmt.Errorf("MEDIA_SERVICE_URL is not configured")
+	}
+
+	body, err := json.Marshal(signedDownloadUrlsRequest{
+		ViewerAuthId:    viewerAuthId,
+		ViewerProfileID: viewerProfileID,
+		MediaIDs:        mediaIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(mediaServiceURL, "/")+"/internal/media/signed-urls", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Access-Token", config.GetConfig().InternalAccessToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("media service responded with status %d", resp.StatusCode)
+	}
+
+	var res signedDownloadUrlsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res.Data.URLs, nil
+}
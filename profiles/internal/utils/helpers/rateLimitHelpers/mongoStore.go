@@ -0,0 +1,60 @@
+package rateLimitHelpers
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// MongoStore keeps buckets in a collection so every instance of a service
+// shares them. Each request is a single atomic update, buckets expire once
+// they would be full again.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(ctx context.Context, collection *mongo.Collection) (*MongoStore, error) {
+	if _, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}); err != nil {
+		return nil, err
+	}
+	return &MongoStore{collection: collection}, nil
+}
+
+func (store *MongoStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	burst := float64(limit.Burst)
+	// Same refill as the memory store, computed by the update itself
+	refilled := bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{
+			bson.M{"$divide": bson.A{
+				bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updatedAt", now}}}}}},
+				1000,
+			}},
+			limit.rate(),
+		}},
+	}}}}
+	var bucket mongoBucket
+	err := store.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "updatedAt": now}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed":   bson.M{"$gte": bson.A{"$tokens", 1}},
+			"tokens":    bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$tokens", 1}}, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"expiresAt": now.Add(limit.Per),
+		}}},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&bucket)
+	if err != nil {
+		return Result{}, err
+	}
+	return resultOf(bucket.Allowed, bucket.Tokens, limit), nil
+}
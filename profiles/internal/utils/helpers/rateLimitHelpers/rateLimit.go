@@ -0,0 +1,91 @@
+package rateLimitHelpers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests go through at once and the bucket
+// refills at Burst tokens per Per.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// rate is the refill rate in tokens per second.
+func (limit Limit) rate() float64 {
+	return float64(limit.Burst) / limit.Per.Seconds()
+}
+
+// Result is the outcome of taking a token. RetryAfter is set when the request
+// was refused, it is how long until a token is available.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets of the limiter, keyed by route and caller.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// refill returns the tokens of a bucket holding tokens at last, as of now.
+func refill(tokens float64, last time.Time, limit Limit, now time.Time) float64 {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Burst), tokens+elapsed*limit.rate())
+}
+
+// resultOf describes a bucket left with tokens after a request that was
+// allowed or not.
+func resultOf(allowed bool, tokens float64, limit Limit) Result {
+	result := Result{Allowed: allowed, Remaining: int(math.Floor(tokens))}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	}
+	return result
+}
+
+// ParseLimit reads a limit written as "<burst>/<duration>", like "10/1m".
+func ParseLimit(value string) (Limit, error) {
+	burst, per, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <burst>/<duration>", value)
+	}
+	limit := Limit{}
+	var err error
+	if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid burst in limit %q", value)
+	}
+	if limit.Per, err = time.ParseDuration(per); err != nil || limit.Per <= 0 {
+		return Limit{}, fmt.Errorf("invalid duration in limit %q", value)
+	}
+	return limit, nil
+}
+
+// ParseLimits reads limits per rule written as "token=10/1m,locations=30/1m".
+func ParseLimits(value string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, rawLimit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q, expected <rule>=<burst>/<duration>", entry)
+		}
+		limit, err := ParseLimit(rawLimit)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}